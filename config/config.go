@@ -18,6 +18,34 @@ var SupplierCollection *mongo.Collection
 var TransactionCollection *mongo.Collection
 var ReportCollection *mongo.Collection
 var ItemTransactionCollection *mongo.Collection
+var CounterCollection *mongo.Collection
+var PromotionCollection *mongo.Collection
+var PromotionUsageCollection *mongo.Collection
+var LoyaltyLedgerCollection *mongo.Collection
+var PackageCollection *mongo.Collection
+var CustomerPackageCollection *mongo.Collection
+var WalletLedgerCollection *mongo.Collection
+var DeliveryZoneCollection *mongo.Collection
+var DeliveryJobCollection *mongo.Collection
+var ServiceCollection *mongo.Collection
+var HolidayCollection *mongo.Collection
+var NotificationCollection *mongo.Collection
+var NotificationTemplateCollection *mongo.Collection
+var PaymentCollection *mongo.Collection
+var PaymentChargeCollection *mongo.Collection
+var ShiftCollection *mongo.Collection
+var CashMovementCollection *mongo.Collection
+var ClaimCollection *mongo.Collection
+var DisposalCollection *mongo.Collection
+var AttachmentCollection *mongo.Collection
+var MachineCollection *mongo.Collection
+var MachineCycleCollection *mongo.Collection
+var MaintenanceLogCollection *mongo.Collection
+var LoadCollection *mongo.Collection
+var RevisionCollection *mongo.Collection
+var ScheduleCollection *mongo.Collection
+var AccountCollection *mongo.Collection
+var CorporateInvoiceCollection *mongo.Collection
 
 // InitMongoDB untuk menginisialisasi koneksi ke MongoDB
 func InitMongoDB() error {
@@ -48,6 +76,40 @@ func InitMongoDB() error {
 	TransactionCollection = client.Database("apkclaundry").Collection("transaksi")
 	ReportCollection = client.Database("apkclaundry").Collection("laporan")
 	ItemTransactionCollection = client.Database("apkclaundry").Collection("stok")
+	CounterCollection = client.Database("apkclaundry").Collection("counter")
+	PromotionCollection = client.Database("apkclaundry").Collection("promo")
+	PromotionUsageCollection = client.Database("apkclaundry").Collection("pemakaian_promo")
+	LoyaltyLedgerCollection = client.Database("apkclaundry").Collection("poin")
+	PackageCollection = client.Database("apkclaundry").Collection("paket")
+	CustomerPackageCollection = client.Database("apkclaundry").Collection("paket_pelanggan")
+	WalletLedgerCollection = client.Database("apkclaundry").Collection("mutasi_saldo")
+	DeliveryZoneCollection = client.Database("apkclaundry").Collection("zona_antar")
+	DeliveryJobCollection = client.Database("apkclaundry").Collection("antar_jemput")
+	ServiceCollection = client.Database("apkclaundry").Collection("layanan")
+	HolidayCollection = client.Database("apkclaundry").Collection("hari_libur")
+	NotificationCollection = client.Database("apkclaundry").Collection("outbox_notifikasi")
+	NotificationTemplateCollection = client.Database("apkclaundry").Collection("template_notifikasi")
+	PaymentCollection = client.Database("apkclaundry").Collection("pembayaran")
+	PaymentChargeCollection = client.Database("apkclaundry").Collection("tagihan_bayar")
+	ShiftCollection = client.Database("apkclaundry").Collection("shift_kasir")
+	CashMovementCollection = client.Database("apkclaundry").Collection("kas")
+	ClaimCollection = client.Database("apkclaundry").Collection("klaim")
+	DisposalCollection = client.Database("apkclaundry").Collection("pesanan_terbengkalai")
+	AttachmentCollection = client.Database("apkclaundry").Collection("lampiran")
+	MachineCollection = client.Database("apkclaundry").Collection("mesin")
+	MachineCycleCollection = client.Database("apkclaundry").Collection("siklus_mesin")
+	MaintenanceLogCollection = client.Database("apkclaundry").Collection("perawatan_mesin")
+	LoadCollection = client.Database("apkclaundry").Collection("muatan")
+	// Nilai lama/baru di riwayat dibaca sebagai map agar JSON-nya berupa objek biasa
+	RevisionCollection = client.Database("apkclaundry").Collection("riwayat_perubahan",
+		options.Collection().SetBSONOptions(&options.BSONOptions{DefaultDocumentM: true}))
+	ScheduleCollection = client.Database("apkclaundry").Collection("jadwal_berulang")
+	AccountCollection = client.Database("apkclaundry").Collection("akun_korporat")
+	CorporateInvoiceCollection = client.Database("apkclaundry").Collection("tagihan_korporat")
+
+	if err := ensureIndexes(ctx); err != nil {
+		log.Printf("Failed to create MongoDB indexes: %v", err)
+	}
 
     return nil
 }
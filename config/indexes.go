@@ -0,0 +1,163 @@
+package config
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ensureIndexes membuat index yang dibutuhkan aplikasi; CreateMany aman dipanggil berulang kali
+func ensureIndexes(ctx context.Context) error {
+	// Nomor nota harus unik; sparse agar transaksi lama tanpa nomor nota tetap valid
+	_, err := TransactionCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "invoice_number", Value: 1}},
+			Options: options.Index().SetUnique(true).SetSparse(true),
+		},
+		// Pesanan anak dicari dari induknya
+		{Keys: bson.D{{Key: "parent_id", Value: 1}}, Options: options.Index().SetSparse(true)},
+		// Satu pesanan per jadwal berulang per tanggal, agar scheduler aman dijalankan ulang
+		{
+			Keys:    bson.D{{Key: "schedule_id", Value: 1}, {Key: "scheduled_for", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"schedule_id": bson.M{"$exists": true}}),
+		},
+		// Pesanan akun korporat yang belum masuk tagihan bulanan
+		{Keys: bson.D{{Key: "account_id", Value: 1}, {Key: "corporate_invoice_id", Value: 1}}, Options: options.Index().SetSparse(true)},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = PromotionCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "code", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Outbox dibaca oleh dispatcher berdasarkan status dan jadwal kirim berikutnya
+	_, err = NotificationCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}}},
+		{Keys: bson.D{{Key: "transaction_id", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = NotificationTemplateCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "event", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Referensi pembayaran unik agar webhook yang dikirim ulang tidak tercatat dua kali
+	_, err = PaymentCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "reference", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = PaymentChargeCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "order_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Satu kasir hanya boleh punya satu shift yang masih terbuka
+	_, err = ShiftCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "cashier_id", Value: 1}},
+		Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"status": "open"}),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = CashMovementCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "shift_id", Value: 1}, {Key: "created_at", Value: 1}},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Satu catatan donasi/pembuangan per pesanan, meskipun job berjalan bersamaan
+	_, err = DisposalCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "transaction_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = AttachmentCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "owner_type", Value: 1}, {Key: "owner_id", Value: 1}, {Key: "created_at", Value: 1}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = MachineCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "code", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = MachineCycleCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "machine_id", Value: 1}, {Key: "started_at", Value: 1}}},
+		{Keys: bson.D{{Key: "transaction_ids", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = MaintenanceLogCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "machine_id", Value: 1}, {Key: "started_at", Value: -1}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = LoadCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "status", Value: 1}, {Key: "stage", Value: 1}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = AccountCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "code", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Satu tagihan konsolidasi per akun per bulan
+	_, err = CorporateInvoiceCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "account_id", Value: 1}, {Key: "period", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "status", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Satu nomor revisi per dokumen
+	_, err = RevisionCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "collection", Value: 1}, {Key: "document_id", Value: 1}, {Key: "number", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
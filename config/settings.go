@@ -0,0 +1,165 @@
+package config
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BusinessProfile holds the identity printed on receipts and other customer-facing documents
+type BusinessProfile struct {
+	Name    string
+	Address string
+	Phone   string
+	Footer  string
+}
+
+// GetEnv returns the value of an environment variable, or def when it is not set
+func GetEnv(key, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return def
+}
+
+// GetEnvInt returns an environment variable parsed as int, or def when it is missing or invalid
+func GetEnvInt(key string, def int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+// GetEnvFloat returns an environment variable parsed as float64, or def when it is missing or invalid
+func GetEnvFloat(key string, def float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+// Business returns the business profile configured through environment variables
+func Business() BusinessProfile {
+	return BusinessProfile{
+		Name:    GetEnv("BUSINESS_NAME", "C Laundry"),
+		Address: GetEnv("BUSINESS_ADDRESS", ""),
+		Phone:   GetEnv("BUSINESS_PHONE", ""),
+		Footer:  GetEnv("RECEIPT_FOOTER", "Terima kasih atas kepercayaan Anda"),
+	}
+}
+
+// Location returns the business time zone used for day boundaries, defaulting to WIB (UTC+7)
+func Location() *time.Location {
+	location, err := time.LoadLocation(GetEnv("TIMEZONE", "Asia/Jakarta"))
+	if err != nil {
+		return time.FixedZone("WIB", 7*60*60)
+	}
+	return location
+}
+
+// LoyaltySettings holds the rules of the customer loyalty program
+type LoyaltySettings struct {
+	RupiahPerPoint float64 // Belanja (rupiah) untuk mendapatkan 1 poin
+	PointValue     float64 // Nilai 1 poin dalam rupiah saat ditukar
+	SilverSpend    float64 // Total belanja minimal untuk tier silver
+	GoldSpend      float64 // Total belanja minimal untuk tier gold
+	SilverDiscount float64 // Diskon tier silver dalam persen
+	GoldDiscount   float64 // Diskon tier gold dalam persen
+}
+
+// Loyalty returns the loyalty program rules configured through environment variables
+func Loyalty() LoyaltySettings {
+	return LoyaltySettings{
+		RupiahPerPoint: GetEnvFloat("LOYALTY_RUPIAH_PER_POINT", 1000),
+		PointValue:     GetEnvFloat("LOYALTY_POINT_VALUE", 100),
+		SilverSpend:    GetEnvFloat("LOYALTY_SILVER_SPEND", 1000000),
+		GoldSpend:      GetEnvFloat("LOYALTY_GOLD_SPEND", 5000000),
+		SilverDiscount: GetEnvFloat("LOYALTY_SILVER_DISCOUNT", 5),
+		GoldDiscount:   GetEnvFloat("LOYALTY_GOLD_DISCOUNT", 10),
+	}
+}
+
+// OperatingHours describes when the outlet processes laundry
+type OperatingHours struct {
+	OpenMinute      int          // Menit sejak tengah malam, mis. 08:00 = 480
+	CloseMinute     int          // Menit sejak tengah malam, mis. 20:00 = 1200
+	ClosedDays      map[int]bool // Hari tutup mingguan (0 = Minggu)
+	DailyCapacityKg float64      // Kapasitas cuci per hari untuk menghitung antrean
+}
+
+// parseClock converts "HH:MM" to minutes since midnight, or returns def when invalid
+func parseClock(value string, def int) int {
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return def
+	}
+	return clock.Hour()*60 + clock.Minute()
+}
+
+// Operating returns the outlet operating hours configured through environment variables
+// (OPEN_TIME, CLOSE_TIME, CLOSED_DAYS as comma-separated weekday numbers, DAILY_CAPACITY_KG)
+func Operating() OperatingHours {
+	hours := OperatingHours{
+		OpenMinute:      parseClock(GetEnv("OPEN_TIME", "08:00"), 8*60),
+		CloseMinute:     parseClock(GetEnv("CLOSE_TIME", "20:00"), 20*60),
+		ClosedDays:      map[int]bool{},
+		DailyCapacityKg: GetEnvFloat("DAILY_CAPACITY_KG", 100),
+	}
+	for _, day := range strings.Split(os.Getenv("CLOSED_DAYS"), ",") {
+		if weekday, err := strconv.Atoi(strings.TrimSpace(day)); err == nil && weekday >= 0 && weekday < 7 {
+			hours.ClosedDays[weekday] = true
+		}
+	}
+	if hours.CloseMinute <= hours.OpenMinute {
+		hours.OpenMinute, hours.CloseMinute = 8*60, 20*60
+	}
+	return hours
+}
+
+// UnclaimedPolicy describes how long finished orders may wait on the rack before they are
+// reminded about, charged for storage and finally given away or disposed of
+type UnclaimedPolicy struct {
+	ReminderDays     []int   // Hari setelah siap saat pengingat dikirim, mis. 3, 7, 14
+	StorageFeeAfter  int     // Hari gratis penyimpanan sebelum biaya dikenakan
+	StorageFeePerDay float64 // 0 = tanpa biaya penyimpanan
+	DisposeAfterDays int     // Hari setelah siap saat pesanan ditandai untuk donasi/dibuang
+}
+
+// Unclaimed returns the unclaimed-laundry policy configured through environment variables
+// (UNCLAIMED_REMINDER_DAYS as comma-separated days, STORAGE_FEE_AFTER_DAYS, STORAGE_FEE_PER_DAY,
+// UNCLAIMED_DISPOSE_DAYS)
+func Unclaimed() UnclaimedPolicy {
+	policy := UnclaimedPolicy{
+		StorageFeeAfter:  GetEnvInt("STORAGE_FEE_AFTER_DAYS", 14),
+		StorageFeePerDay: GetEnvFloat("STORAGE_FEE_PER_DAY", 0),
+		DisposeAfterDays: GetEnvInt("UNCLAIMED_DISPOSE_DAYS", 60),
+	}
+	for _, day := range strings.Split(GetEnv("UNCLAIMED_REMINDER_DAYS", "3,7,14,30"), ",") {
+		if days, err := strconv.Atoi(strings.TrimSpace(day)); err == nil && days > 0 {
+			policy.ReminderDays = append(policy.ReminderDays, days)
+		}
+	}
+	sort.Ints(policy.ReminderDays)
+	return policy
+}
+
+// TaxSettings describes the value-added tax (PPN) charged on orders
+type TaxSettings struct {
+	Name      string  // Nama pajak di nota, mis. "PPN"
+	Rate      float64 // Tarif default dalam persen; 0 = usaha tidak memungut pajak
+	Inclusive bool    // true jika harga layanan sudah termasuk pajak
+}
+
+// Tax returns the tax settings configured through environment variables
+// (TAX_NAME, TAX_RATE, TAX_MODE inclusive or exclusive)
+func Tax() TaxSettings {
+	return TaxSettings{
+		Name:      GetEnv("TAX_NAME", "PPN"),
+		Rate:      GetEnvFloat("TAX_RATE", 0),
+		Inclusive: GetEnv("TAX_MODE", "exclusive") == "inclusive",
+	}
+}
@@ -0,0 +1,845 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"apkclaundry/config"
+	"apkclaundry/models"
+	"apkclaundry/receipt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Corporate account and consolidated invoice statuses
+const (
+	AccountActive    = "active"
+	AccountSuspended = "suspended"
+
+	InvoiceOpen = "open"
+	InvoicePaid = "paid"
+
+	// PaymentAccount is the payment method of orders charged to a corporate account
+	PaymentAccount = "account"
+)
+
+// Fields of a corporate account that may be changed through UpdateAccount, by JSON name
+var accountPatchFields = []string{"name", "contact_name", "phone", "email", "billing_address", "tax_id",
+	"credit_limit", "payment_term_days", "status"}
+
+var (
+	errNoAccount          = errors.New("Customer is not linked to a corporate account")
+	errAccountSuspended   = errors.New("Corporate account is suspended")
+	errCreditLimit        = errors.New("Order exceeds the credit limit of the corporate account")
+	errInvoiceExists      = errors.New("An invoice for this period already exists")
+	errNothingToInvoice   = errors.New("No uninvoiced orders in this period")
+	errInvalidPeriod      = errors.New("Invalid period, use yyyy-mm")
+	errInvoiceOverpayment = errors.New("Amount exceeds the balance of the invoice")
+)
+
+// findAccount loads a corporate account by its hex ID
+func findAccount(ctx context.Context, accountID string) (models.CorporateAccount, error) {
+	var account models.CorporateAccount
+	id, err := primitive.ObjectIDFromHex(accountID)
+	if err != nil {
+		return account, err
+	}
+	err = config.AccountCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&account)
+	return account, err
+}
+
+// accountOutstanding sums the unpaid balance of all orders charged to an account, invoiced or not
+func accountOutstanding(ctx context.Context, accountID string) (float64, error) {
+	cursor, err := config.TransactionCollection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"account_id": accountID, "status": bson.M{"$ne": StatusCancelled}}}},
+		{{Key: "$group", Value: bson.M{"_id": nil, "due": bson.M{"$sum": bson.M{
+			"$max": bson.A{0, bson.M{"$subtract": bson.A{"$total_price", "$amount_paid"}}},
+		}}}}},
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var result []struct {
+		Due float64 `bson:"due"`
+	}
+	if err := cursor.All(ctx, &result); err != nil || len(result) == 0 {
+		return 0, err
+	}
+	return result[0].Due, nil
+}
+
+// reserveAccountCredit adds an amount to an account's outstanding balance in one conditional
+// update, so concurrent orders cannot together go over the credit limit
+func reserveAccountCredit(ctx context.Context, accountID string, amount float64) error {
+	id, err := primitive.ObjectIDFromHex(accountID)
+	if err != nil {
+		return errNoAccount
+	}
+	result, err := config.AccountCollection.UpdateOne(ctx, bson.M{
+		"_id":    id,
+		"status": AccountActive,
+		"$or": bson.A{
+			bson.M{"credit_limit": bson.M{"$lte": 0}},
+			bson.M{"$expr": bson.M{"$lte": bson.A{
+				bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$outstanding", 0}}, amount}},
+				"$credit_limit",
+			}}},
+		},
+	}, bson.M{"$inc": bson.M{"outstanding": amount}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errCreditLimit
+	}
+	return nil
+}
+
+// adjustAccountOutstanding moves an account's outstanding balance without checking the limit,
+// for payments, cancellations and fees on orders already charged to the account
+func adjustAccountOutstanding(ctx context.Context, accountID string, delta float64) {
+	id, err := primitive.ObjectIDFromHex(accountID)
+	if err != nil || delta == 0 {
+		return
+	}
+	_, err = config.AccountCollection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{"outstanding": delta}})
+	if err != nil {
+		log.Printf("Failed to adjust outstanding balance of account %s by %.2f: %v", accountID, delta, err)
+	}
+}
+
+// accountDue is what an order adds to the outstanding balance of its account
+func accountDue(transaction models.Transaction) float64 {
+	if transaction.AccountID == "" || transaction.Status == StatusCancelled {
+		return 0
+	}
+	return balanceDue(transaction)
+}
+
+// syncAccountOutstanding applies the change in an order's balance due to its account
+func syncAccountOutstanding(ctx context.Context, before, after models.Transaction) {
+	if after.AccountID != "" {
+		adjustAccountOutstanding(ctx, after.AccountID, accountDue(after)-accountDue(before))
+	}
+}
+
+// billInvoicedFee bills a fee added to an order that is already on a corporate invoice. An open
+// invoice grows by the fee; once the invoice is paid, the order is released from it so the fee is
+// billed on the next invoice.
+func billInvoicedFee(ctx context.Context, transaction models.Transaction, fee, charged float64) {
+	if transaction.CorporateInvoiceID == "" || charged == 0 {
+		return
+	}
+	var base, tax float64
+	if transaction.TaxRate > 0 {
+		base, tax = splitTax(fee, transaction.TaxRate, transaction.TaxInclusive)
+	}
+
+	invoiceID, _ := primitive.ObjectIDFromHex(transaction.CorporateInvoiceID)
+	result, err := config.CorporateInvoiceCollection.UpdateOne(ctx,
+		bson.M{"_id": invoiceID, "status": InvoiceOpen, "lines.transaction_id": transaction.ID},
+		bson.M{"$inc": bson.M{"total": charged, "tax_base": base, "tax_amount": tax, "lines.$.amount": charged}},
+	)
+	if err != nil {
+		log.Printf("Failed to add fee of transaction %s to invoice %s: %v", transaction.ID, transaction.CorporateInvoiceID, err)
+		return
+	}
+	if result.MatchedCount > 0 {
+		return
+	}
+	if charged < 0 {
+		log.Printf("Fee of transaction %s was reduced by %.2f after invoice %s was paid, credit it manually", transaction.ID, -charged, transaction.CorporateInvoiceID)
+		return
+	}
+	// Tagihan sudah lunas: pesanan dilepas agar sisa tagihannya masuk tagihan bulan berikutnya
+	id, _ := primitive.ObjectIDFromHex(transaction.ID)
+	_, err = config.TransactionCollection.UpdateOne(ctx,
+		bson.M{"_id": id, "corporate_invoice_id": transaction.CorporateInvoiceID},
+		bson.M{"$unset": bson.M{"corporate_invoice_id": ""}},
+	)
+	if err != nil {
+		log.Printf("Failed to release transaction %s from invoice %s: %v", transaction.ID, transaction.CorporateInvoiceID, err)
+	}
+}
+
+// chargeToAccount books a new order on the customer's corporate account instead of taking
+// payment, reserving its total within the credit limit. The returned release gives the credit
+// back if the order is not saved.
+func chargeToAccount(ctx context.Context, transaction *models.Transaction) (func(), error) {
+	noop := func() {}
+	customer, err := findCustomer(ctx, transaction.CustomerID)
+	if err != nil || customer.AccountID == "" {
+		return noop, errNoAccount
+	}
+	account, err := findAccount(ctx, customer.AccountID)
+	if err != nil {
+		return noop, errNoAccount
+	}
+	if account.Status != AccountActive {
+		return noop, errAccountSuspended
+	}
+	amount := transaction.TotalPrice
+	if err := reserveAccountCredit(ctx, account.ID, amount); err != nil {
+		return noop, err
+	}
+
+	transaction.AccountID = account.ID
+	transaction.AmountPaid = 0
+	return func() { adjustAccountOutstanding(ctx, account.ID, -amount) }, nil
+}
+
+// previousPeriod returns the month before now as yyyy-mm in the business time zone
+func previousPeriod(now time.Time) string {
+	now = now.In(config.Location())
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).AddDate(0, -1, 0).Format("2006-01")
+}
+
+// issueCorporateInvoice consolidates every uninvoiced order of an account up to the end of the
+// period into one invoice. Orders are claimed with the invoice ID first so an order never lands
+// on two invoices; late orders from earlier months roll into the next invoice.
+func issueCorporateInvoice(ctx context.Context, account models.CorporateAccount, period string, now time.Time) (models.CorporateInvoice, error) {
+	var invoice models.CorporateInvoice
+	start, err := time.ParseInLocation("2006-01", period, config.Location())
+	if err != nil {
+		return invoice, errInvalidPeriod
+	}
+	end := start.AddDate(0, 1, 0)
+
+	if count, err := config.CorporateInvoiceCollection.CountDocuments(ctx, bson.M{"account_id": account.ID, "period": period}); err != nil {
+		return invoice, err
+	} else if count > 0 {
+		return invoice, errInvoiceExists
+	}
+
+	invoiceID := primitive.NewObjectID()
+	_, err = config.TransactionCollection.UpdateMany(ctx, bson.M{
+		"account_id":           account.ID,
+		"corporate_invoice_id": bson.M{"$exists": false},
+		"status":               bson.M{"$nin": bson.A{StatusCancelled, StatusDraft}},
+		"transaction_date":     bson.M{"$lt": end},
+	}, bson.M{"$set": bson.M{"corporate_invoice_id": invoiceID.Hex()}})
+	if err != nil {
+		return invoice, err
+	}
+	release := func() {
+		config.TransactionCollection.UpdateMany(ctx,
+			bson.M{"corporate_invoice_id": invoiceID.Hex()},
+			bson.M{"$unset": bson.M{"corporate_invoice_id": ""}},
+		)
+	}
+
+	cursor, err := config.TransactionCollection.Find(ctx,
+		bson.M{"corporate_invoice_id": invoiceID.Hex()},
+		options.Find().SetSort(bson.D{{Key: "transaction_date", Value: 1}}),
+	)
+	if err != nil {
+		release()
+		return invoice, err
+	}
+	var transactions []models.Transaction
+	if err := cursor.All(ctx, &transactions); err != nil {
+		release()
+		return invoice, err
+	}
+	if len(transactions) == 0 {
+		return invoice, errNothingToInvoice
+	}
+
+	terms := account.PaymentTermDays
+	if terms <= 0 {
+		terms = 30
+	}
+	invoice = models.CorporateInvoice{
+		Number:      "INV-" + strings.ToUpper(account.Code) + "-" + strings.ReplaceAll(period, "-", ""),
+		AccountID:   account.ID,
+		AccountName: account.Name,
+		Period:      period,
+		Status:      InvoiceOpen,
+		IssuedAt:    now,
+		DueDate:     now.AddDate(0, 0, terms),
+	}
+	for _, transaction := range transactions {
+		amount := balanceDue(transaction)
+		invoice.Lines = append(invoice.Lines, models.CorporateInvoiceLine{
+			TransactionID:   transaction.ID,
+			InvoiceNumber:   transaction.InvoiceNumber,
+			TransactionDate: transaction.TransactionDate,
+			CustomerName:    transaction.CustomerName,
+			ServiceType:     transaction.ServiceType,
+			WeightPerKg:     transaction.WeightPerKg,
+			Amount:          amount,
+		})
+		invoice.Total += amount
+		// Pajak ikut proporsional dengan sisa tagihan pesanan
+		if transaction.TotalPrice > 0 {
+			share := amount / transaction.TotalPrice
+			invoice.TaxAmount += math.Round(transaction.TaxAmount * share)
+			invoice.TaxBase += math.Round(transaction.TaxBase * share)
+		}
+	}
+
+	invoice.ID = invoiceID.Hex()
+	if _, err := config.CorporateInvoiceCollection.InsertOne(ctx, bson.M{
+		"_id":          invoiceID,
+		"number":       invoice.Number,
+		"account_id":   invoice.AccountID,
+		"account_name": invoice.AccountName,
+		"period":       invoice.Period,
+		"lines":        invoice.Lines,
+		"tax_base":     invoice.TaxBase,
+		"tax_amount":   invoice.TaxAmount,
+		"total":        invoice.Total,
+		"amount_paid":  invoice.AmountPaid,
+		"status":       invoice.Status,
+		"issued_at":    invoice.IssuedAt,
+		"due_date":     invoice.DueDate,
+	}); err != nil {
+		release()
+		if mongo.IsDuplicateKeyError(err) {
+			return invoice, errInvoiceExists
+		}
+		return invoice, err
+	}
+	return invoice, nil
+}
+
+// CreateAccount handles the creation of a corporate account:
+// {"code": "HTLMAWAR", "name": "Hotel Mawar", "credit_limit": 10000000, "payment_term_days": 30}
+func CreateAccount(w http.ResponseWriter, r *http.Request) {
+	var account models.CorporateAccount
+	if err := json.NewDecoder(r.Body).Decode(&account); err != nil {
+		http.Error(w, `{"error": "Invalid input"}`, http.StatusBadRequest)
+		return
+	}
+	account.Code = strings.ToUpper(strings.TrimSpace(account.Code))
+	if account.Code == "" || account.Name == "" {
+		http.Error(w, `{"error": "Code and name are required"}`, http.StatusBadRequest)
+		return
+	}
+	if account.CreditLimit < 0 || account.PaymentTermDays < 0 {
+		http.Error(w, `{"error": "Credit limit and payment terms cannot be negative"}`, http.StatusBadRequest)
+		return
+	}
+	if account.PaymentTermDays == 0 {
+		account.PaymentTermDays = 30
+	}
+	account.Status = AccountActive
+	account.Outstanding = 0
+	account.CreatedAt = time.Now()
+
+	result, err := config.AccountCollection.InsertOne(context.TODO(), account)
+	if mongo.IsDuplicateKeyError(err) {
+		http.Error(w, `{"error": "Account code already exists"}`, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error": "Failed to create account"}`, http.StatusInternalServerError)
+		return
+	}
+	account.ID = result.InsertedID.(primitive.ObjectID).Hex()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(account)
+}
+
+// GetAllAccounts retrieves all corporate accounts
+func GetAllAccounts(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := config.AccountCollection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		http.Error(w, "Failed to fetch accounts", http.StatusInternalServerError)
+		return
+	}
+	accounts := []models.CorporateAccount{}
+	if err := cursor.All(ctx, &accounts); err != nil {
+		http.Error(w, "Failed to decode accounts", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(accounts)
+}
+
+// GetAccountByID retrieves a corporate account with its outstanding balance and remaining credit
+func GetAccountByID(w http.ResponseWriter, r *http.Request) {
+	accountID := r.URL.Query().Get("id")
+	if accountID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	account, err := findAccount(ctx, accountID)
+	if err != nil {
+		http.Error(w, "Account not found", http.StatusNotFound)
+		return
+	}
+
+	response := map[string]interface{}{
+		"account":     account,
+		"outstanding": account.Outstanding,
+	}
+	if account.CreditLimit > 0 {
+		response["available_credit"] = math.Max(0, account.CreditLimit-account.Outstanding)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateAccount changes a corporate account with a JSON merge patch, e.g. {"status": "suspended"}
+func UpdateAccount(w http.ResponseWriter, r *http.Request) {
+	var account models.CorporateAccount
+	validate := func(ctx context.Context, changes []models.FieldChange) error {
+		if account.CreditLimit < 0 || account.PaymentTermDays < 0 {
+			return errors.New("Credit limit and payment terms cannot be negative")
+		}
+		if account.Status != AccountActive && account.Status != AccountSuspended {
+			return errors.New("Status must be active or suspended")
+		}
+		return nil
+	}
+	if !patchDocument(w, r, config.AccountCollection, "account", &account, accountPatchFields, validate) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Account updated successfully"})
+}
+
+// DeleteAccount deletes a corporate account that has nothing left to pay and unlinks its customers
+func DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	accountID := r.URL.Query().Get("id")
+	id, err := primitive.ObjectIDFromHex(accountID)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	outstanding, err := accountOutstanding(ctx, accountID)
+	if err != nil {
+		http.Error(w, "Failed to calculate outstanding balance", http.StatusInternalServerError)
+		return
+	}
+	if outstanding > 0 {
+		http.Error(w, "Account still has an outstanding balance", http.StatusConflict)
+		return
+	}
+
+	result, err := config.AccountCollection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		http.Error(w, "Failed to delete account", http.StatusInternalServerError)
+		return
+	}
+	if result.DeletedCount == 0 {
+		http.Error(w, "Account not found", http.StatusNotFound)
+		return
+	}
+	config.CustomerCollection.UpdateMany(ctx, bson.M{"account_id": accountID}, bson.M{"$unset": bson.M{"account_id": ""}})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Account deleted successfully"})
+}
+
+// IssueCorporateInvoice creates the consolidated invoice of ?account_id= for ?period=yyyy-mm
+// (default the previous month)
+func IssueCorporateInvoice(w http.ResponseWriter, r *http.Request) {
+	period := r.URL.Query().Get("period")
+	if period == "" {
+		period = previousPeriod(time.Now())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	account, err := findAccount(ctx, r.URL.Query().Get("account_id"))
+	if err != nil {
+		http.Error(w, `{"error": "Account not found"}`, http.StatusNotFound)
+		return
+	}
+
+	invoice, err := issueCorporateInvoice(ctx, account, period, time.Now())
+	switch err {
+	case nil:
+	case errInvalidPeriod:
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	case errInvoiceExists, errNothingToInvoice:
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusConflict)
+		return
+	default:
+		http.Error(w, `{"error": "Failed to create invoice"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(invoice)
+}
+
+// RunCorporateInvoices issues last month's invoice for every active corporate account. It is
+// called by a monthly cron job and is safe to re-run.
+func RunCorporateInvoices(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	period := previousPeriod(now)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Second)
+	defer cancel()
+
+	cursor, err := config.AccountCollection.Find(ctx, bson.M{"status": AccountActive})
+	if err != nil {
+		http.Error(w, "Failed to fetch accounts", http.StatusInternalServerError)
+		return
+	}
+	var accounts []models.CorporateAccount
+	if err := cursor.All(ctx, &accounts); err != nil {
+		http.Error(w, "Failed to read account data", http.StatusInternalServerError)
+		return
+	}
+
+	summary := map[string]int{"accounts": len(accounts), "issued": 0, "skipped": 0, "failed": 0}
+	for _, account := range accounts {
+		if ctx.Err() != nil {
+			break
+		}
+		_, err := issueCorporateInvoice(ctx, account, period, now)
+		switch err {
+		case nil:
+			summary["issued"]++
+		case errInvoiceExists, errNothingToInvoice:
+			summary["skipped"]++
+		default:
+			log.Printf("Failed to invoice account %s for %s: %v", account.ID, period, err)
+			summary["failed"]++
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
+
+// GetCorporateInvoices lists consolidated invoices, filtered by ?account_id= and ?status=
+func GetCorporateInvoices(w http.ResponseWriter, r *http.Request) {
+	filter := bson.M{}
+	if accountID := r.URL.Query().Get("account_id"); accountID != "" {
+		filter["account_id"] = accountID
+	}
+	if status := r.URL.Query().Get("status"); status != "" {
+		filter["status"] = status
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := config.CorporateInvoiceCollection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "issued_at", Value: -1}}))
+	if err != nil {
+		http.Error(w, "Failed to fetch invoices", http.StatusInternalServerError)
+		return
+	}
+	invoices := []models.CorporateInvoice{}
+	if err := cursor.All(ctx, &invoices); err != nil {
+		http.Error(w, "Failed to decode invoices", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invoices)
+}
+
+// GetCorporateInvoiceByID returns a consolidated invoice as JSON, or as an A4 PDF with ?format=pdf
+func GetCorporateInvoiceByID(w http.ResponseWriter, r *http.Request) {
+	id, err := primitive.ObjectIDFromHex(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var invoice models.CorporateInvoice
+	if err := config.CorporateInvoiceCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&invoice); err != nil {
+		http.Error(w, "Invoice not found", http.StatusNotFound)
+		return
+	}
+
+	if r.URL.Query().Get("format") != "pdf" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(invoice)
+		return
+	}
+
+	account, _ := findAccount(ctx, invoice.AccountID)
+	if account.ID == "" {
+		account.Name = invoice.AccountName
+	}
+	document := receipt.CorporateInvoice{Business: config.Business(), Account: account, Invoice: invoice}
+	var pdf bytes.Buffer
+	if err := document.WritePDF(&pdf); err != nil {
+		http.Error(w, "Failed to render invoice", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `inline; filename="`+invoice.Number+`.pdf"`)
+	w.Write(pdf.Bytes())
+}
+
+// RecordInvoicePayment records a payment against a consolidated invoice, e.g.
+// {"amount": 4500000, "method": "transfer", "reference": "BCA-123"}. The amount is spread over the
+// orders on the invoice, oldest first, so each order's own balance stays correct.
+func RecordInvoicePayment(w http.ResponseWriter, r *http.Request) {
+	id, err := primitive.ObjectIDFromHex(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		Amount    float64 `json:"amount"`
+		Method    string  `json:"method"`
+		Reference string  `json:"reference"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, `{"error": "Invalid input"}`, http.StatusBadRequest)
+		return
+	}
+	if request.Amount <= 0 || request.Method == "" {
+		http.Error(w, `{"error": "Amount and method are required"}`, http.StatusBadRequest)
+		return
+	}
+	reference := "invoice:" + id.Hex() + ":" + primitive.NewObjectID().Hex()
+	if request.Reference != "" {
+		reference = "invoice:" + id.Hex() + ":" + request.Reference
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	var invoice models.CorporateInvoice
+	if err := config.CorporateInvoiceCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&invoice); err != nil {
+		http.Error(w, `{"error": "Invoice not found"}`, http.StatusNotFound)
+		return
+	}
+	if request.Amount > invoice.Total-invoice.AmountPaid {
+		http.Error(w, `{"error": "`+errInvoiceOverpayment.Error()+`"}`, http.StatusUnprocessableEntity)
+		return
+	}
+
+	// Referensi dan sisa tagihan menjadi syarat update agar pembayaran tidak tercatat dua kali
+	err = config.CorporateInvoiceCollection.FindOneAndUpdate(ctx,
+		bson.M{
+			"_id":          id,
+			"status":       InvoiceOpen,
+			"payment_refs": bson.M{"$ne": reference},
+			"amount_paid":  bson.M{"$lte": invoice.Total - request.Amount},
+		},
+		bson.M{"$inc": bson.M{"amount_paid": request.Amount}, "$push": bson.M{"payment_refs": reference}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&invoice)
+	if err == mongo.ErrNoDocuments {
+		http.Error(w, `{"error": "Payment was already recorded or the invoice changed, please check the invoice"}`, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error": "Failed to record payment"}`, http.StatusInternalServerError)
+		return
+	}
+
+	remaining := request.Amount
+	for _, line := range invoice.Lines {
+		if remaining <= 0 {
+			break
+		}
+		transaction, err := findTransaction(ctx, line.TransactionID)
+		if err != nil {
+			log.Printf("Invoice %s: transaction %s not found", invoice.Number, line.TransactionID)
+			continue
+		}
+		amount := math.Min(remaining, balanceDue(transaction))
+		if amount <= 0 {
+			continue
+		}
+		recorded, err := recordPayment(ctx, models.Payment{
+			TransactionID: transaction.ID,
+			Amount:        amount,
+			PaymentType:   request.Method,
+			Reference:     reference + ":" + transaction.ID,
+		})
+		if err != nil {
+			log.Printf("Invoice %s: failed to record payment on transaction %s: %v", invoice.Number, transaction.ID, err)
+			continue
+		}
+		if recorded && isCashMethod(request.Method) {
+			recordCashSale(ctx, r.Header.Get("User-ID"), transaction, amount)
+		}
+		remaining -= amount
+	}
+
+	if invoice.AmountPaid >= invoice.Total {
+		now := time.Now()
+		config.CorporateInvoiceCollection.UpdateOne(ctx,
+			bson.M{"_id": id, "status": InvoiceOpen},
+			bson.M{"$set": bson.M{"status": InvoicePaid, "paid_at": now}},
+		)
+		invoice.Status = InvoicePaid
+		invoice.PaidAt = &now
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invoice)
+}
+
+// GetARAging reports what corporate accounts owe, with open invoice balances grouped by age
+// since the invoice date (0-30, 31-60, 61-90 and 90+ days) as of ?as_of=yyyy-mm-dd (default
+// today). Orders charged to an account but not invoiced yet are shown as unbilled.
+func GetARAging(w http.ResponseWriter, r *http.Request) {
+	asOf := time.Now()
+	if value := r.URL.Query().Get("as_of"); value != "" {
+		day, err := parseDay(value)
+		if err != nil {
+			http.Error(w, "Invalid as_of date, use yyyy-mm-dd", http.StatusBadRequest)
+			return
+		}
+		asOf = day.AddDate(0, 0, 1).Add(-time.Second)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	cursor, err := config.CorporateInvoiceCollection.Find(ctx, bson.M{
+		"status":    InvoiceOpen,
+		"issued_at": bson.M{"$lte": asOf},
+	})
+	if err != nil {
+		http.Error(w, "Failed to fetch invoices", http.StatusInternalServerError)
+		return
+	}
+	var invoices []models.CorporateInvoice
+	if err := cursor.All(ctx, &invoices); err != nil {
+		http.Error(w, "Failed to decode invoices", http.StatusInternalServerError)
+		return
+	}
+
+	type agingRow struct {
+		AccountID   string  `json:"account_id"`
+		AccountName string  `json:"account_name"`
+		Current     float64 `json:"0_30"`
+		Days31To60  float64 `json:"31_60"`
+		Days61To90  float64 `json:"61_90"`
+		Over90      float64 `json:"over_90"`
+		Overdue     float64 `json:"overdue"` // Bagian yang sudah lewat jatuh tempo
+		Unbilled    float64 `json:"unbilled"`
+		Total       float64 `json:"total"`
+	}
+	rows := map[string]*agingRow{}
+	row := func(accountID, accountName string) *agingRow {
+		if rows[accountID] == nil {
+			rows[accountID] = &agingRow{AccountID: accountID, AccountName: accountName}
+		}
+		return rows[accountID]
+	}
+
+	for _, invoice := range invoices {
+		balance := invoice.Total - invoice.AmountPaid
+		if balance <= 0 {
+			continue
+		}
+		entry := row(invoice.AccountID, invoice.AccountName)
+		switch age := daysSince(invoice.IssuedAt, asOf); {
+		case age <= 30:
+			entry.Current += balance
+		case age <= 60:
+			entry.Days31To60 += balance
+		case age <= 90:
+			entry.Days61To90 += balance
+		default:
+			entry.Over90 += balance
+		}
+		if asOf.After(invoice.DueDate) {
+			entry.Overdue += balance
+		}
+		entry.Total += balance
+	}
+
+	// Pesanan akun yang belum masuk tagihan
+	cursor, err = config.TransactionCollection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"account_id":           bson.M{"$exists": true},
+			"corporate_invoice_id": bson.M{"$exists": false},
+			"status":               bson.M{"$nin": bson.A{StatusCancelled, StatusDraft}},
+			"transaction_date":     bson.M{"$lte": asOf},
+		}}},
+		{{Key: "$group", Value: bson.M{"_id": "$account_id", "due": bson.M{"$sum": bson.M{
+			"$max": bson.A{0, bson.M{"$subtract": bson.A{"$total_price", "$amount_paid"}}},
+		}}}}},
+	})
+	if err != nil {
+		http.Error(w, "Failed to calculate unbilled orders", http.StatusInternalServerError)
+		return
+	}
+	var unbilled []struct {
+		AccountID string  `bson:"_id"`
+		Due       float64 `bson:"due"`
+	}
+	if err := cursor.All(ctx, &unbilled); err != nil {
+		http.Error(w, "Failed to decode unbilled orders", http.StatusInternalServerError)
+		return
+	}
+	for _, item := range unbilled {
+		if item.Due <= 0 {
+			continue
+		}
+		name := ""
+		if rows[item.AccountID] == nil {
+			if account, err := findAccount(ctx, item.AccountID); err == nil {
+				name = account.Name
+			}
+		}
+		entry := row(item.AccountID, name)
+		entry.Unbilled += item.Due
+		entry.Total += item.Due
+	}
+
+	result := []agingRow{}
+	totals := agingRow{AccountName: "Total"}
+	for _, entry := range rows {
+		result = append(result, *entry)
+		totals.Current += entry.Current
+		totals.Days31To60 += entry.Days31To60
+		totals.Days61To90 += entry.Days61To90
+		totals.Over90 += entry.Over90
+		totals.Overdue += entry.Overdue
+		totals.Unbilled += entry.Unbilled
+		totals.Total += entry.Total
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Total > result[j].Total })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"as_of":    asOf.In(config.Location()).Format("2006-01-02"),
+		"accounts": result,
+		"totals":   totals,
+	})
+}
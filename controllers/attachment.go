@@ -0,0 +1,308 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"apkclaundry/config"
+	"apkclaundry/models"
+	"apkclaundry/storage"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	AttachmentTransaction = "transaction"
+	AttachmentClaim       = "claim"
+)
+
+// attachmentTypes are the accepted upload types, detected from the file content, with their extension
+var attachmentTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+}
+
+// attachmentOwner reads ?transaction_id= or ?claim_id= and checks that the owner exists
+func attachmentOwner(ctx context.Context, r *http.Request) (string, string, error) {
+	ownerType, ownerID := AttachmentTransaction, r.URL.Query().Get("transaction_id")
+	collection := config.TransactionCollection
+	if ownerID == "" {
+		ownerType, ownerID = AttachmentClaim, r.URL.Query().Get("claim_id")
+		collection = config.ClaimCollection
+	}
+	if ownerID == "" {
+		return "", "", errors.New("transaction_id or claim_id not provided")
+	}
+
+	id, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return "", "", errors.New("Invalid ID")
+	}
+	if err := collection.FindOne(ctx, bson.M{"_id": id}, options.FindOne().SetProjection(bson.M{"_id": 1})).Err(); err != nil {
+		return "", "", errors.New(ownerType + " not found")
+	}
+	return ownerType, ownerID, nil
+}
+
+// signAttachment fills the viewing URLs of an attachment, valid for ATTACHMENT_URL_MINUTES (default 15)
+func signAttachment(store storage.Store, attachment *models.Attachment) {
+	expiresAt := time.Now().Add(time.Duration(config.GetEnvInt("ATTACHMENT_URL_MINUTES", 15)) * time.Minute)
+	var err error
+	if attachment.URL, err = store.SignedURL(attachment.Key, expiresAt); err != nil {
+		log.Printf("No viewing URL for attachment %s: %v", attachment.ID, err)
+	}
+	attachment.ThumbnailURL, _ = store.SignedURL(attachment.ThumbnailKey, expiresAt)
+}
+
+// UploadAttachment stores a photo for a transaction or claim (POST /attachment?transaction_id= or
+// ?claim_id=, multipart field "file", optional field "note"). Only JPEG and PNG up to
+// ATTACHMENT_MAX_MB (default 4) are accepted; a thumbnail is generated for listing.
+func UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	store, err := storage.FromSettings()
+	if err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	ownerType, ownerID, err := attachmentOwner(ctx, r)
+	if err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	maxSize := int64(config.GetEnvInt("ATTACHMENT_MAX_MB", 4)) << 20
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+64<<10)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, `{"error": "File is larger than `+strconv.FormatInt(maxSize>>20, 10)+` MB"}`, http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, `{"error": "File not provided"}`, http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil {
+		http.Error(w, `{"error": "Failed to read file"}`, http.StatusBadRequest)
+		return
+	}
+	if int64(len(data)) > maxSize {
+		http.Error(w, `{"error": "File is larger than `+strconv.FormatInt(maxSize>>20, 10)+` MB"}`, http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// Jenis file ditentukan dari isinya, bukan dari nama atau header yang dikirim klien
+	contentType := http.DetectContentType(data)
+	extension, allowed := attachmentTypes[contentType]
+	if !allowed {
+		http.Error(w, `{"error": "Only JPEG and PNG images are allowed"}`, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	thumbnail, width, height, err := storage.Thumbnail(data, config.GetEnvInt("ATTACHMENT_THUMBNAIL_SIZE", 320))
+	if err == storage.ErrImageTooLarge {
+		http.Error(w, `{"error": "Image resolution is too large"}`, http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error": "Image could not be read"}`, http.StatusBadRequest)
+		return
+	}
+
+	prefix := ownerType + "s/" + ownerID + "/" + primitive.NewObjectID().Hex()
+	attachment := models.Attachment{
+		OwnerType:    ownerType,
+		OwnerID:      ownerID,
+		Key:          prefix + extension,
+		ThumbnailKey: prefix + "_thumb.jpg",
+		FileName:     header.Filename,
+		ContentType:  contentType,
+		Size:         len(data),
+		Width:        width,
+		Height:       height,
+		Note:         r.FormValue("note"),
+		UploadedBy:   r.Header.Get("Username"),
+		CreatedAt:    time.Now(),
+	}
+
+	if err := store.Put(ctx, attachment.Key, contentType, data); err != nil {
+		log.Printf("Failed to store attachment %s: %v", attachment.Key, err)
+		http.Error(w, `{"error": "Failed to store file"}`, http.StatusBadGateway)
+		return
+	}
+	if err := store.Put(ctx, attachment.ThumbnailKey, "image/jpeg", thumbnail); err != nil {
+		log.Printf("Failed to store thumbnail %s: %v", attachment.ThumbnailKey, err)
+		store.Delete(ctx, attachment.Key)
+		http.Error(w, `{"error": "Failed to store file"}`, http.StatusBadGateway)
+		return
+	}
+
+	result, err := config.AttachmentCollection.InsertOne(ctx, attachment)
+	if err != nil {
+		store.Delete(ctx, attachment.Key)
+		store.Delete(ctx, attachment.ThumbnailKey)
+		http.Error(w, `{"error": "Failed to save attachment"}`, http.StatusInternalServerError)
+		return
+	}
+
+	attachment.ID = result.InsertedID.(primitive.ObjectID).Hex()
+	signAttachment(store, &attachment)
+
+	response := map[string]interface{}{
+		"message":    "Attachment uploaded successfully",
+		"attachment": attachment,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetAttachments lists the photos of a transaction or claim with signed viewing URLs
+func GetAttachments(w http.ResponseWriter, r *http.Request) {
+	store, err := storage.FromSettings()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ownerType, ownerID, err := attachmentOwner(ctx, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cursor, err := config.AttachmentCollection.Find(ctx,
+		bson.M{"owner_type": ownerType, "owner_id": ownerID},
+		options.Find().SetSort(bson.M{"created_at": 1}))
+	if err != nil {
+		http.Error(w, "Failed to fetch attachments", http.StatusInternalServerError)
+		return
+	}
+	attachments := []models.Attachment{}
+	if err := cursor.All(ctx, &attachments); err != nil {
+		http.Error(w, "Failed to read attachment data", http.StatusInternalServerError)
+		return
+	}
+	for i := range attachments {
+		signAttachment(store, &attachments[i])
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(attachments)
+}
+
+// DeleteAttachment deletes an attachment and its files by its ID
+func DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	attachmentID := r.URL.Query().Get("id")
+	if attachmentID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(attachmentID)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	store, err := storage.FromSettings()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	var attachment models.Attachment
+	err = config.AttachmentCollection.FindOneAndDelete(ctx, bson.M{"_id": id}).Decode(&attachment)
+	if err == mongo.ErrNoDocuments {
+		http.Error(w, "Attachment not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to delete attachment", http.StatusInternalServerError)
+		return
+	}
+
+	// File yang gagal dihapus hanya memakan ruang; datanya sudah tidak bisa diakses lagi
+	for _, key := range []string{attachment.Key, attachment.ThumbnailKey} {
+		if err := store.Delete(ctx, key); err != nil {
+			log.Printf("Failed to delete file %s: %v", key, err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Attachment deleted successfully"})
+}
+
+// ServeAttachmentFile serves a file of the local store through a signed URL
+// (GET /attachment-file?key=&expires=&signature=). It requires no login; the signature is the authorization.
+func ServeAttachmentFile(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	key := query.Get("key")
+	err := storage.Verify(key, query.Get("expires"), query.Get("signature"))
+	if err == storage.ErrNoSigningSecret {
+		log.Printf("Attachment file request rejected: %v", err)
+		http.Error(w, "File links are not available", http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Link is invalid or has expired", http.StatusForbidden)
+		return
+	}
+
+	store, err := storage.FromSettings()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	var attachment models.Attachment
+	err = config.AttachmentCollection.FindOne(ctx, bson.M{"$or": bson.A{bson.M{"key": key}, bson.M{"thumbnail_key": key}}}).Decode(&attachment)
+	if err != nil {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+	contentType := attachment.ContentType
+	if key == attachment.ThumbnailKey {
+		contentType = "image/jpeg"
+	}
+
+	file, err := store.Open(ctx, key)
+	if err == storage.ErrNotFound {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to read file", http.StatusBadGateway)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	io.Copy(w, file)
+}
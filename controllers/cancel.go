@@ -0,0 +1,490 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"apkclaundry/config"
+	"apkclaundry/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	CancellationPending  = "pending_approval"
+	CancellationApproved = "approved"
+	CancellationRejected = "rejected"
+)
+
+// cancelReasons are the accepted reason codes; "other" requires a note
+var cancelReasons = map[string]bool{
+	"customer_request": true,
+	"wrong_entry":      true,
+	"duplicate":        true,
+	"cannot_process":   true,
+	"other":            true,
+}
+
+// isManager reports whether a role may approve cancellations
+func isManager(role string) bool {
+	return role == "admin" || role == "manager"
+}
+
+// refundableAmount is the money paid on an order, excluding what was paid from prepaid packages
+// (package balance is restored separately)
+func refundableAmount(transaction models.Transaction) float64 {
+	amount := transaction.AmountPaid
+	for _, debit := range transaction.PackageDebits {
+		amount -= debit.Amount
+	}
+	return math.Max(0, amount)
+}
+
+// releasePromotionUsage gives back the promo use of a cancelled order and drops it from the report
+func releasePromotionUsage(ctx context.Context, transaction models.Transaction) error {
+	var usage models.PromotionUsage
+	err := config.PromotionUsageCollection.FindOneAndDelete(ctx, bson.M{"transaction_id": transaction.ID}).Decode(&usage)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	promoID, err := primitive.ObjectIDFromHex(usage.PromotionID)
+	if err != nil {
+		return err
+	}
+	var promo models.Promotion
+	if err := config.PromotionCollection.FindOne(ctx, bson.M{"_id": promoID}).Decode(&promo); err != nil {
+		return err
+	}
+	releasePromotion(ctx, promo, usage.CustomerKey)
+	return nil
+}
+
+// reverseStockMovements puts back the stock used for an order, writing a "Pembatalan" movement per item
+func reverseStockMovements(ctx context.Context, transaction models.Transaction) error {
+	cursor, err := config.ItemTransactionCollection.Find(ctx, bson.M{"transaction_id": transaction.ID, "transaction_type": StockUsage})
+	if err != nil {
+		return err
+	}
+	var used []models.ItemTransaction
+	if err := cursor.All(ctx, &used); err != nil {
+		return err
+	}
+
+	for _, movement := range used {
+		itemID, err := primitive.ObjectIDFromHex(movement.ItemID)
+		if err != nil {
+			return err
+		}
+		var item models.Item
+		err = config.ItemCollection.FindOneAndUpdate(ctx,
+			bson.M{"_id": itemID},
+			bson.M{"$inc": bson.M{"quantity": movement.Quantity}},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&item)
+		if err != nil {
+			return err
+		}
+		reversal := models.ItemTransaction{
+			ItemID:          movement.ItemID,
+			ItemName:        movement.ItemName,
+			Date:            time.Now(),
+			TransactionType: StockReversal,
+			Quantity:        movement.Quantity,
+			StockAfter:      item.Quantity,
+			TransactionID:   transaction.ID,
+		}
+		if _, err := config.ItemTransactionCollection.InsertOne(ctx, reversal); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cancellationBlocked returns why an order cannot be cancelled right now, or "" when it can
+func cancellationBlocked(ctx context.Context, transaction models.Transaction) (string, error) {
+	// Pesanan yang sudah masuk tagihan bulanan dikoreksi lewat tagihannya
+	if transaction.CorporateInvoiceID != "" {
+		return "Order is already on a corporate invoice", nil
+	}
+	active, err := config.DeliveryJobCollection.CountDocuments(ctx, bson.M{"transaction_id": transaction.ID, "status": JobEnRoute})
+	if err != nil {
+		return "", err
+	}
+	if active > 0 {
+		return "A courier is on the way for this order, finish or fail the delivery job first", nil
+	}
+	return "", nil
+}
+
+// checkRefundShift makes sure a cash refund can be paid from the drawer of cashierID
+func checkRefundShift(ctx context.Context, cancellation *models.TransactionCancellation, cashierID string) error {
+	if cancellation == nil || cancellation.RefundAmount <= 0 || !isCashMethod(cancellation.RefundMethod) {
+		return nil
+	}
+	_, err := findOpenShift(ctx, cashierID)
+	return err
+}
+
+// completeCancellation marks an order cancelled and reverses everything it caused: loyalty points,
+// package balance, promo usage, stock, open delivery jobs, and pays out the refund from the shift of
+// cashierID, the person completing the cancellation. The status filter makes sure this runs only
+// once per order, and never for a rejected request or an invoiced order.
+func completeCancellation(ctx context.Context, transaction models.Transaction, reviewer, reviewNote, cashierID string) (models.Transaction, bool, error) {
+	id, err := primitive.ObjectIDFromHex(transaction.ID)
+	if err != nil {
+		return transaction, false, err
+	}
+
+	now := time.Now()
+	set := bson.M{
+		"status":                    StatusCancelled,
+		"cancellation.status":       CancellationApproved,
+		"cancellation.completed_at": now,
+	}
+	if reviewer != "" {
+		set["cancellation.reviewed_by"] = reviewer
+		set["cancellation.reviewed_at"] = now
+		set["cancellation.review_note"] = reviewNote
+	}
+	err = config.TransactionCollection.FindOneAndUpdate(ctx,
+		bson.M{
+			"_id":                  id,
+			"status":               bson.M{"$ne": StatusCancelled},
+			"cancellation.status":  CancellationPending,
+			"corporate_invoice_id": bson.M{"$exists": false},
+		},
+		bson.M{"$set": set},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&transaction)
+	if err == mongo.ErrNoDocuments {
+		return transaction, false, nil
+	}
+	if err != nil {
+		return transaction, false, err
+	}
+	// Sisa tagihan pesanan korporat tidak lagi membebani limit akun
+	if transaction.AccountID != "" {
+		adjustAccountOutstanding(ctx, transaction.AccountID, -balanceDue(transaction))
+	}
+
+	// Tagihan QRIS/VA yang masih terbuka tidak boleh dibayar lagi
+	expirePendingCharges(ctx, transaction.ID, "")
+
+	if err := reverseLoyaltyPoints(ctx, transaction); err != nil {
+		log.Printf("Failed to reverse loyalty points for transaction %s: %v", transaction.ID, err)
+	}
+	if err := refundPackageDebits(ctx, transaction); err != nil {
+		log.Printf("Failed to refund package balance for transaction %s: %v", transaction.ID, err)
+	}
+	if err := releasePromotionUsage(ctx, transaction); err != nil {
+		log.Printf("Failed to release promotion for transaction %s: %v", transaction.ID, err)
+	}
+	if err := reverseStockMovements(ctx, transaction); err != nil {
+		log.Printf("Failed to reverse stock for transaction %s: %v", transaction.ID, err)
+	}
+
+	_, err = config.DeliveryJobCollection.UpdateMany(ctx,
+		bson.M{"transaction_id": transaction.ID, "status": JobScheduled},
+		bson.M{"$set": bson.M{"status": JobFailed, "failure_reason": "Pesanan dibatalkan", "updated_at": now}},
+	)
+	if err != nil {
+		log.Printf("Failed to cancel delivery jobs for transaction %s: %v", transaction.ID, err)
+	}
+
+	cancellation := transaction.Cancellation
+	if cancellation.RefundAmount > 0 {
+		refund := models.Payment{
+			TransactionID: transaction.ID,
+			CustomerID:    transaction.CustomerID,
+			Amount:        -cancellation.RefundAmount,
+			PaymentType:   cancellation.RefundMethod,
+			Reference:     "refund:" + transaction.ID,
+			Date:          now,
+		}
+		if _, err := config.PaymentCollection.InsertOne(ctx, refund); err != nil && !mongo.IsDuplicateKeyError(err) {
+			log.Printf("Failed to write refund for transaction %s: %v", transaction.ID, err)
+		}
+		if isCashMethod(cancellation.RefundMethod) {
+			// Uang keluar dari laci orang yang menyelesaikan pembatalan
+			err := addCashMovement(ctx, cashierID, models.CashMovement{
+				Type:          CashOut,
+				Amount:        cancellation.RefundAmount,
+				Description:   "Refund nota " + transaction.InvoiceNumber,
+				TransactionID: transaction.ID,
+			})
+			if err != nil {
+				log.Printf("Failed to record cash refund for transaction %s: %v", transaction.ID, err)
+				return transaction, true, err
+			}
+		}
+	}
+
+	return transaction, true, nil
+}
+
+// CancelTransaction cancels an order while keeping its record, e.g.
+// {"reason_code": "customer_request", "note": "...", "refund_amount": 20000, "refund_method": "cash"}.
+// Orders above CANCEL_APPROVAL_LIMIT (default 50000) requested by non-managers wait for approval.
+func CancelTransaction(w http.ResponseWriter, r *http.Request) {
+	transactionID := r.URL.Query().Get("id")
+	if transactionID == "" {
+		http.Error(w, `{"error": "ID not provided"}`, http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		ReasonCode   string  `json:"reason_code"`
+		Note         string  `json:"note"`
+		RefundAmount float64 `json:"refund_amount"`
+		RefundMethod string  `json:"refund_method"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, `{"error": "Invalid input"}`, http.StatusBadRequest)
+		return
+	}
+	request.Note = strings.TrimSpace(request.Note)
+	if !cancelReasons[request.ReasonCode] {
+		http.Error(w, `{"error": "Invalid reason code"}`, http.StatusBadRequest)
+		return
+	}
+	if request.ReasonCode == "other" && request.Note == "" {
+		http.Error(w, `{"error": "A note is required for reason other"}`, http.StatusBadRequest)
+		return
+	}
+	if request.RefundAmount < 0 || (request.RefundAmount > 0 && request.RefundMethod == "") {
+		http.Error(w, `{"error": "Refund amount must not be negative and needs a refund method"}`, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	transaction, err := findTransaction(ctx, transactionID)
+	if err != nil {
+		http.Error(w, `{"error": "Transaction not found"}`, http.StatusNotFound)
+		return
+	}
+	if transaction.Status == StatusCancelled || transaction.Status == StatusPickedUp {
+		http.Error(w, `{"error": "Cannot cancel an order that is `+transaction.Status+`"}`, http.StatusConflict)
+		return
+	}
+	if request.RefundAmount > refundableAmount(transaction) {
+		http.Error(w, `{"error": "Refund exceeds the amount paid"}`, http.StatusUnprocessableEntity)
+		return
+	}
+	if reason, err := cancellationBlocked(ctx, transaction); err != nil || reason != "" {
+		if err != nil {
+			http.Error(w, `{"error": "Failed to cancel transaction"}`, http.StatusInternalServerError)
+			return
+		}
+		http.Error(w, `{"error": "`+reason+`"}`, http.StatusConflict)
+		return
+	}
+
+	manager := isManager(r.Header.Get("Role"))
+	needsApproval := !manager && transaction.TotalPrice > config.GetEnvFloat("CANCEL_APPROVAL_LIMIT", 50000)
+
+	cancellation := models.TransactionCancellation{
+		ReasonCode:    request.ReasonCode,
+		Note:          request.Note,
+		RefundAmount:  request.RefundAmount,
+		RefundMethod:  request.RefundMethod,
+		Status:        CancellationPending,
+		RequestedByID: r.Header.Get("User-ID"),
+		RequestedBy:   r.Header.Get("Username"),
+		RequestedAt:   time.Now(),
+	}
+	// Refund tunai langsung dibayar dari laci peminta, jadi shift-nya harus terbuka
+	if !needsApproval {
+		if err := checkRefundShift(ctx, &cancellation, cancellation.RequestedByID); err != nil {
+			http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusConflict)
+			return
+		}
+	}
+
+	// Hanya satu permintaan pembatalan yang boleh menunggu per pesanan
+	id, _ := primitive.ObjectIDFromHex(transaction.ID)
+	result, err := config.TransactionCollection.UpdateOne(ctx,
+		bson.M{
+			"_id":                 id,
+			"status":              bson.M{"$nin": bson.A{StatusCancelled, StatusPickedUp}},
+			"cancellation.status": bson.M{"$ne": CancellationPending},
+		},
+		bson.M{"$set": bson.M{"cancellation": cancellation}},
+	)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to cancel transaction"}`, http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, `{"error": "A cancellation is already pending or the order has changed"}`, http.StatusConflict)
+		return
+	}
+
+	if needsApproval {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Cancellation is waiting for manager approval"})
+		return
+	}
+
+	reviewer := ""
+	if manager {
+		reviewer = cancellation.RequestedBy
+	}
+	transaction, done, err := completeCancellation(ctx, transaction, reviewer, "", cancellation.RequestedByID)
+	if err != nil && done {
+		http.Error(w, `{"error": "Transaction cancelled but the cash refund could not be recorded: `+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error": "Failed to cancel transaction"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"message":     "Transaction cancelled successfully",
+		"transaction": transaction,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// ReviewCancellation approves or rejects a pending cancellation, e.g. {"approve": true, "note": "..."}.
+// The manager reviewing must not be the person who requested it.
+func ReviewCancellation(w http.ResponseWriter, r *http.Request) {
+	transactionID := r.URL.Query().Get("id")
+	if transactionID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		Approve bool   `json:"approve"`
+		Note    string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	transaction, err := findTransaction(ctx, transactionID)
+	if err != nil {
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+		return
+	}
+	if transaction.Cancellation == nil || transaction.Cancellation.Status != CancellationPending {
+		http.Error(w, "No pending cancellation for this transaction", http.StatusConflict)
+		return
+	}
+	if transaction.Cancellation.RequestedByID == r.Header.Get("User-ID") {
+		http.Error(w, "You cannot approve your own cancellation request", http.StatusForbidden)
+		return
+	}
+
+	reviewer := r.Header.Get("Username")
+	if !request.Approve {
+		id, _ := primitive.ObjectIDFromHex(transaction.ID)
+		result, err := config.TransactionCollection.UpdateOne(ctx,
+			bson.M{"_id": id, "cancellation.status": CancellationPending},
+			bson.M{"$set": bson.M{
+				"cancellation.status":      CancellationRejected,
+				"cancellation.reviewed_by": reviewer,
+				"cancellation.reviewed_at": time.Now(),
+				"cancellation.review_note": request.Note,
+			}},
+		)
+		if err != nil {
+			http.Error(w, "Failed to reject cancellation", http.StatusInternalServerError)
+			return
+		}
+		if result.MatchedCount == 0 {
+			http.Error(w, "Cancellation was changed by someone else", http.StatusConflict)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"message": "Cancellation rejected"})
+		return
+	}
+
+	if reason, err := cancellationBlocked(ctx, transaction); err != nil || reason != "" {
+		if err != nil {
+			http.Error(w, "Failed to cancel transaction", http.StatusInternalServerError)
+			return
+		}
+		http.Error(w, reason, http.StatusConflict)
+		return
+	}
+	// Refund tunai dibayar dari laci manajer yang menyetujui
+	if err := checkRefundShift(ctx, transaction.Cancellation, r.Header.Get("User-ID")); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	transaction, done, err := completeCancellation(ctx, transaction, reviewer, request.Note, r.Header.Get("User-ID"))
+	if err != nil && done {
+		http.Error(w, "Transaction cancelled but the cash refund could not be recorded: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to cancel transaction", http.StatusInternalServerError)
+		return
+	}
+	if !done {
+		http.Error(w, "Cancellation was changed by someone else", http.StatusConflict)
+		return
+	}
+
+	response := map[string]interface{}{
+		"message":     "Cancellation approved",
+		"transaction": transaction,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetPendingCancellations lists orders waiting for cancellation approval, oldest request first
+func GetPendingCancellations(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := config.TransactionCollection.Find(ctx,
+		bson.M{"cancellation.status": CancellationPending},
+		options.Find().SetSort(bson.M{"cancellation.requested_at": 1}))
+	if err != nil {
+		http.Error(w, "Failed to fetch transactions", http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	transactions := []models.Transaction{}
+	for cursor.Next(ctx) {
+		var transaction models.Transaction
+		if err := cursor.Decode(&transaction); err != nil {
+			http.Error(w, "Failed to read transaction data", http.StatusInternalServerError)
+			return
+		}
+		transaction.TransactionDateFormatted = formatDate(transaction.TransactionDate)
+		transactions = append(transactions, transaction)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transactions)
+}
@@ -0,0 +1,412 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"apkclaundry/config"
+	"apkclaundry/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	ClaimDamage = "damage"
+	ClaimLoss   = "loss"
+
+	ClaimOpen          = "open"
+	ClaimInvestigating = "investigating"
+	ClaimApproved      = "approved"
+	ClaimRejected      = "rejected"
+	ClaimPaid          = "paid"
+)
+
+// claimTransitions lists the statuses a claim may move to from each status
+var claimTransitions = map[string][]string{
+	ClaimOpen:          {ClaimInvestigating, ClaimApproved, ClaimRejected},
+	ClaimInvestigating: {ClaimApproved, ClaimRejected},
+	ClaimApproved:      {ClaimPaid},
+	ClaimRejected:      {},
+	ClaimPaid:          {},
+}
+
+func canMoveClaim(from, to string) bool {
+	for _, status := range claimTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// validateClaim checks the fields of a claim sent by the client
+func validateClaim(claim models.Claim) string {
+	if claim.Type != ClaimDamage && claim.Type != ClaimLoss {
+		return "Type must be damage or loss"
+	}
+	if strings.TrimSpace(claim.Description) == "" {
+		return "Description is required"
+	}
+	if claim.ClaimedAmount < 0 {
+		return "Claimed amount cannot be negative"
+	}
+	return ""
+}
+
+// CreateClaim records a damage or loss complaint against a transaction
+func CreateClaim(w http.ResponseWriter, r *http.Request) {
+	var claim models.Claim
+	if err := json.NewDecoder(r.Body).Decode(&claim); err != nil {
+		http.Error(w, `{"error": "Invalid input"}`, http.StatusBadRequest)
+		return
+	}
+	if msg := validateClaim(claim); msg != "" {
+		http.Error(w, `{"error": "`+msg+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	transaction, err := findTransaction(ctx, claim.TransactionID)
+	if err != nil {
+		http.Error(w, `{"error": "Transaction not found"}`, http.StatusNotFound)
+		return
+	}
+
+	// Data pesanan disalin agar laporan tidak perlu join ke transaksi
+	now := time.Now()
+	claim.InvoiceNumber = transaction.InvoiceNumber
+	claim.CustomerID = transaction.CustomerID
+	claim.CustomerName = transaction.CustomerName
+	claim.HandledByID = transaction.HandledByID
+	claim.HandledBy = transaction.HandledBy
+	claim.ReportedBy = r.Header.Get("Username")
+	claim.Status = ClaimOpen
+	claim.ApprovedAmount = 0
+	claim.PaymentMethod = ""
+	claim.PaidAt = nil
+	if claim.Photos == nil {
+		claim.Photos = []string{}
+	}
+	claim.History = []models.ClaimEvent{{Status: ClaimOpen, By: claim.ReportedBy, At: now}}
+	claim.CreatedAt = now
+	claim.UpdatedAt = now
+
+	result, err := config.ClaimCollection.InsertOne(ctx, claim)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to create claim"}`, http.StatusInternalServerError)
+		return
+	}
+
+	claim.ID = result.InsertedID.(primitive.ObjectID).Hex()
+
+	response := map[string]interface{}{
+		"message": "Claim created successfully",
+		"claim":   claim,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetAllClaims lists claims, newest first, filtered by ?status=, ?transaction_id= and ?from=&to=
+func GetAllClaims(w http.ResponseWriter, r *http.Request) {
+	filter := bson.M{}
+	dateFilter, err := dateRangeFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if dateFilter != nil {
+		filter["created_at"] = dateFilter
+	}
+	if status := r.URL.Query().Get("status"); status != "" {
+		filter["status"] = status
+	}
+	if transactionID := r.URL.Query().Get("transaction_id"); transactionID != "" {
+		filter["transaction_id"] = transactionID
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := config.ClaimCollection.Find(ctx, filter, options.Find().SetSort(bson.M{"created_at": -1}))
+	if err != nil {
+		http.Error(w, "Failed to fetch claims", http.StatusInternalServerError)
+		return
+	}
+	claims := []models.Claim{}
+	if err := cursor.All(ctx, &claims); err != nil {
+		http.Error(w, "Failed to read claim data", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(claims)
+}
+
+// GetClaimByID retrieves a claim by its ID
+func GetClaimByID(w http.ResponseWriter, r *http.Request) {
+	claimID := r.URL.Query().Get("id")
+	if claimID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(claimID)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var claim models.Claim
+	if err := config.ClaimCollection.FindOne(context.TODO(), bson.M{"_id": id}).Decode(&claim); err != nil {
+		http.Error(w, "Claim not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(claim)
+}
+
+// UpdateClaim edits the details of a claim that has not been decided yet
+func UpdateClaim(w http.ResponseWriter, r *http.Request) {
+	claimID := r.URL.Query().Get("id")
+	if claimID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(claimID)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var claim models.Claim
+	if err := json.NewDecoder(r.Body).Decode(&claim); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if msg := validateClaim(claim); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if claim.Photos == nil {
+		claim.Photos = []string{}
+	}
+
+	update := bson.M{"$set": bson.M{
+		"type":                claim.Type,
+		"garment_tag":         claim.GarmentTag,
+		"garment_description": claim.GarmentDescription,
+		"description":         claim.Description,
+		"photos":              claim.Photos,
+		"claimed_amount":      claim.ClaimedAmount,
+		"updated_at":          time.Now(),
+	}}
+
+	result, err := config.ClaimCollection.UpdateOne(context.TODO(),
+		bson.M{"_id": id, "status": bson.M{"$in": bson.A{ClaimOpen, ClaimInvestigating}}}, update)
+	if err != nil {
+		http.Error(w, "Failed to update claim", http.StatusInternalServerError)
+		return
+	}
+
+	if result.MatchedCount == 0 {
+		http.Error(w, "Claim not found or already decided", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Claim updated successfully"})
+}
+
+// UpdateClaimStatus moves a claim through its workflow, e.g. {"status": "approved", "approved_amount": 75000}
+// or {"status": "paid", "payment_method": "cash"}. Cash compensation is taken from the cashier's open shift.
+func UpdateClaimStatus(w http.ResponseWriter, r *http.Request) {
+	claimID := r.URL.Query().Get("id")
+	if claimID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(claimID)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		Status         string  `json:"status"`
+		ApprovedAmount float64 `json:"approved_amount"`
+		PaymentMethod  string  `json:"payment_method"`
+		Note           string  `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if _, ok := claimTransitions[request.Status]; !ok {
+		http.Error(w, "Invalid status", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var claim models.Claim
+	if err := config.ClaimCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&claim); err != nil {
+		http.Error(w, "Claim not found", http.StatusNotFound)
+		return
+	}
+	if !canMoveClaim(claim.Status, request.Status) {
+		http.Error(w, "Cannot move claim from "+claim.Status+" to "+request.Status, http.StatusConflict)
+		return
+	}
+
+	now := time.Now()
+	set := bson.M{"status": request.Status, "updated_at": now}
+	switch request.Status {
+	case ClaimApproved:
+		if request.ApprovedAmount <= 0 || request.ApprovedAmount > claim.ClaimedAmount {
+			http.Error(w, "Approved amount must be between 0 and the claimed amount", http.StatusBadRequest)
+			return
+		}
+		set["approved_amount"] = request.ApprovedAmount
+	case ClaimRejected:
+		if strings.TrimSpace(request.Note) == "" {
+			http.Error(w, "A note is required when rejecting a claim", http.StatusBadRequest)
+			return
+		}
+	case ClaimPaid:
+		if request.PaymentMethod == "" {
+			http.Error(w, "Payment method is required", http.StatusBadRequest)
+			return
+		}
+		set["payment_method"] = request.PaymentMethod
+		set["paid_at"] = now
+		// Ganti rugi tunai keluar dari laci kasir, jadi shift-nya harus terbuka sebelum klaim ditandai lunas
+		if isCashMethod(request.PaymentMethod) {
+			if _, err := findOpenShift(ctx, r.Header.Get("User-ID")); err == errNoOpenShift {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			} else if err != nil {
+				http.Error(w, "Failed to read shift", http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
+	event := models.ClaimEvent{Status: request.Status, By: r.Header.Get("Username"), Note: request.Note, At: now}
+	result, err := config.ClaimCollection.UpdateOne(ctx,
+		bson.M{"_id": id, "status": claim.Status},
+		bson.M{"$set": set, "$push": bson.M{"history": event}},
+	)
+	if err != nil {
+		http.Error(w, "Failed to update claim status", http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, "Claim was changed by someone else, please retry", http.StatusConflict)
+		return
+	}
+
+	if request.Status == ClaimPaid && isCashMethod(request.PaymentMethod) {
+		err := addCashMovement(ctx, r.Header.Get("User-ID"), models.CashMovement{
+			Type:          CashOut,
+			Amount:        claim.ApprovedAmount,
+			Description:   "Ganti rugi klaim nota " + claim.InvoiceNumber,
+			TransactionID: claim.TransactionID,
+		})
+		if err != nil {
+			log.Printf("Failed to record claim payout %s: %v", claim.ID, err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Claim status updated successfully"})
+}
+
+// claimReportRow is one group of the claims report
+type claimReportRow struct {
+	Key            string  `json:"key" bson:"_id"`
+	Name           string  `json:"name,omitempty" bson:"name,omitempty"`
+	Count          int     `json:"count" bson:"count"`
+	Open           int     `json:"open" bson:"open"`
+	Rejected       int     `json:"rejected" bson:"rejected"`
+	ClaimedAmount  float64 `json:"claimed_amount" bson:"claimed_amount"`
+	ApprovedAmount float64 `json:"approved_amount" bson:"approved_amount"`
+	PaidAmount     float64 `json:"paid_amount" bson:"paid_amount"`
+}
+
+// claimGroup builds the $group stage that sums claims by the given key
+func claimGroup(key interface{}) bson.D {
+	countIf := func(statuses ...string) bson.M {
+		return bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$in": bson.A{"$status", statuses}}, 1, 0}}}
+	}
+	return bson.D{{Key: "$group", Value: bson.M{
+		"_id":             key,
+		"name":            bson.M{"$first": "$handled_by"},
+		"count":           bson.M{"$sum": 1},
+		"open":            countIf(ClaimOpen, ClaimInvestigating),
+		"rejected":        countIf(ClaimRejected),
+		"claimed_amount":  bson.M{"$sum": "$claimed_amount"},
+		"approved_amount": bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$in": bson.A{"$status", bson.A{ClaimApproved, ClaimPaid}}}, "$approved_amount", 0}}},
+		"paid_amount":     bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$status", ClaimPaid}}, "$approved_amount", 0}}},
+	}}}
+}
+
+// GetClaimReport summarizes claims per month and per employee who handled the order,
+// optionally limited with ?from=&to=
+func GetClaimReport(w http.ResponseWriter, r *http.Request) {
+	match := bson.M{}
+	dateFilter, err := dateRangeFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if dateFilter != nil {
+		match["created_at"] = dateFilter
+	}
+
+	month := bson.M{"$dateToString": bson.M{"format": "%Y-%m", "date": "$created_at", "timezone": time.Now().In(config.Location()).Format("-07:00")}}
+	employee := bson.M{"$ifNull": bson.A{"$handled_by_id", ""}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$facet", Value: bson.M{
+			"by_month":    bson.A{claimGroup(month), bson.M{"$project": bson.M{"name": 0}}, bson.M{"$sort": bson.M{"_id": 1}}},
+			"by_employee": bson.A{claimGroup(employee), bson.M{"$sort": bson.M{"count": -1}}},
+		}}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := config.ClaimCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		http.Error(w, "Failed to build claim report", http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var report []struct {
+		ByMonth    []claimReportRow `json:"by_month" bson:"by_month"`
+		ByEmployee []claimReportRow `json:"by_employee" bson:"by_employee"`
+	}
+	if err := cursor.All(ctx, &report); err != nil || len(report) == 0 {
+		http.Error(w, "Failed to read claim report", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report[0])
+}
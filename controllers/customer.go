@@ -3,6 +3,7 @@ package controllers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
@@ -21,6 +22,11 @@ func CreateCustomer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Poin dan tier hanya diatur oleh program loyalti
+	customer.LoyaltyPoints = 0
+	customer.Tier = ""
+	customer.LifetimeSpend = 0
+
 	// Insert the customer into the database
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -171,43 +177,27 @@ func GetCustomerNameByID(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(result)
 }
 
-// UpdateCustomer updates a customer's data by their ID
+// UpdateCustomer updates a customer's data by their ID. The body is a JSON merge patch:
+// only the fields it contains are changed, and each change is kept in the revision history
 func UpdateCustomer(w http.ResponseWriter, r *http.Request) {
-	customerID := r.URL.Query().Get("id")
-	if customerID == "" {
-		http.Error(w, "ID not provided", http.StatusBadRequest)
-		return
-	}
-
-	id, err := primitive.ObjectIDFromHex(customerID)
-	if err != nil {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
-		return
-	}
-
-	var updatedCustomer models.Customer
-	if err := json.NewDecoder(r.Body).Decode(&updatedCustomer); err != nil {
-		http.Error(w, "Invalid input", http.StatusBadRequest)
-		return
-	}
-
-	update := bson.M{
-		"$set": bson.M{
-			"name":    updatedCustomer.Name,
-			"phone":   updatedCustomer.Phone,
-			"address": updatedCustomer.Address,
-			"email":   updatedCustomer.Email,
-		},
-	}
-
-	result, err := config.CustomerCollection.UpdateOne(context.TODO(), bson.M{"_id": id}, update)
-	if err != nil {
-		http.Error(w, "Failed to update customer", http.StatusInternalServerError)
-		return
+	var customer models.Customer
+	// Pelanggan hanya bisa ditautkan ke akun korporat yang ada dan aktif
+	validate := func(ctx context.Context, changes []models.FieldChange) error {
+		for _, change := range changes {
+			if change.Field != "account_id" || customer.AccountID == "" {
+				continue
+			}
+			account, err := findAccount(ctx, customer.AccountID)
+			if err != nil {
+				return errors.New("Corporate account not found")
+			}
+			if account.Status != AccountActive {
+				return errAccountSuspended
+			}
+		}
+		return nil
 	}
-
-	if result.MatchedCount == 0 {
-		http.Error(w, "Customer not found", http.StatusNotFound)
+	if !patchDocument(w, r, config.CustomerCollection, "customer", &customer, customerPatchFields, validate) {
 		return
 	}
 
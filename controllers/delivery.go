@@ -0,0 +1,606 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"apkclaundry/config"
+	"apkclaundry/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	JobPickup   = "pickup"
+	JobDelivery = "delivery"
+
+	JobScheduled = "scheduled"
+	JobEnRoute   = "en_route"
+	JobDone      = "done"
+	JobFailed    = "failed"
+)
+
+// jobTransitions lists the statuses a delivery job may move to from each status.
+// A failed job can be rescheduled for another attempt.
+var jobTransitions = map[string][]string{
+	JobScheduled: {JobEnRoute, JobFailed},
+	JobEnRoute:   {JobDone, JobFailed},
+	JobFailed:    {JobScheduled},
+	JobDone:      {},
+}
+
+func canMoveJob(from, to string) bool {
+	for _, status := range jobTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// findCourier looks up an employee that can be assigned as courier
+func findCourier(ctx context.Context, courierID string) (models.User, error) {
+	var courier models.User
+	// Karyawan disimpan dengan _id berupa string hex, sama seperti saat registrasi
+	err := config.EmployeeCollection.FindOne(ctx, bson.M{"_id": courierID}).Decode(&courier)
+	if err != nil {
+		return courier, errors.New("courier not found")
+	}
+	return courier, nil
+}
+
+// todayRange returns the start and end of the current day in the business time zone
+func todayRange(now time.Time) (time.Time, time.Time) {
+	local := now.In(config.Location())
+	start := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+	return start, start.AddDate(0, 0, 1)
+}
+
+// adjustDeliveryFee adds (or with a negative fee removes) a job's fee on its transaction
+func adjustDeliveryFee(ctx context.Context, transactionID string, fee float64) error {
+	description := "Ongkos antar-jemput"
+	if fee < 0 {
+		description = "Batal ongkos antar-jemput"
+	}
+	return addTaxedFee(ctx, transactionID, "delivery_fee", description, fee)
+}
+
+// decodeJobs reads all delivery jobs from a cursor
+func decodeJobs(ctx context.Context, cursor *mongo.Cursor) ([]models.DeliveryJob, error) {
+	defer cursor.Close(ctx)
+	jobs := []models.DeliveryJob{}
+	err := cursor.All(ctx, &jobs)
+	return jobs, err
+}
+
+// CreateDeliveryZone handles the creation of a new delivery zone
+func CreateDeliveryZone(w http.ResponseWriter, r *http.Request) {
+	var zone models.DeliveryZone
+	if err := json.NewDecoder(r.Body).Decode(&zone); err != nil {
+		http.Error(w, `{"error": "Invalid input"}`, http.StatusBadRequest)
+		return
+	}
+
+	if zone.Name == "" || zone.Fee < 0 {
+		http.Error(w, `{"error": "Zone name and a non-negative fee are required"}`, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := config.DeliveryZoneCollection.InsertOne(ctx, zone)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to create zone"}`, http.StatusInternalServerError)
+		return
+	}
+
+	zone.ID = result.InsertedID.(primitive.ObjectID).Hex()
+
+	response := map[string]interface{}{
+		"message": "Zone created successfully",
+		"zone":    zone,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetAllDeliveryZones retrieves all delivery zones from the database
+func GetAllDeliveryZones(w http.ResponseWriter, r *http.Request) {
+	cursor, err := config.DeliveryZoneCollection.Find(context.TODO(), bson.M{})
+	if err != nil {
+		http.Error(w, "Failed to fetch zones", http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(context.TODO())
+
+	var zones []models.DeliveryZone
+	for cursor.Next(context.TODO()) {
+		var zone models.DeliveryZone
+		if err := cursor.Decode(&zone); err != nil {
+			http.Error(w, "Failed to read zone data", http.StatusInternalServerError)
+			return
+		}
+		zones = append(zones, zone)
+	}
+
+	if len(zones) == 0 {
+		http.Error(w, "No zones found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(zones)
+}
+
+// UpdateDeliveryZone updates a delivery zone by its ID. Existing jobs keep the fee they were created with.
+func UpdateDeliveryZone(w http.ResponseWriter, r *http.Request) {
+	zoneID := r.URL.Query().Get("id")
+	if zoneID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(zoneID)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var updatedZone models.DeliveryZone
+	if err := json.NewDecoder(r.Body).Decode(&updatedZone); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	if updatedZone.Name == "" || updatedZone.Fee < 0 {
+		http.Error(w, "Zone name and a non-negative fee are required", http.StatusBadRequest)
+		return
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"name": updatedZone.Name,
+			"fee":  updatedZone.Fee,
+		},
+	}
+
+	result, err := config.DeliveryZoneCollection.UpdateOne(context.TODO(), bson.M{"_id": id}, update)
+	if err != nil {
+		http.Error(w, "Failed to update zone", http.StatusInternalServerError)
+		return
+	}
+
+	if result.MatchedCount == 0 {
+		http.Error(w, "Zone not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Zone updated successfully"})
+}
+
+// DeleteDeliveryZone deletes a delivery zone by its ID
+func DeleteDeliveryZone(w http.ResponseWriter, r *http.Request) {
+	zoneID := r.URL.Query().Get("id")
+	if zoneID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(zoneID)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	result, err := config.DeliveryZoneCollection.DeleteOne(context.TODO(), bson.M{"_id": id})
+	if err != nil {
+		http.Error(w, "Failed to delete zone", http.StatusInternalServerError)
+		return
+	}
+
+	if result.DeletedCount == 0 {
+		http.Error(w, "Zone not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Zone deleted successfully"})
+}
+
+// CreateDeliveryJob schedules a pickup or delivery for a transaction. The zone fee is added to
+// the transaction total; the address defaults to the linked customer's address.
+func CreateDeliveryJob(w http.ResponseWriter, r *http.Request) {
+	var job models.DeliveryJob
+	if err := json.NewDecoder(r.Body).Decode(&job); err != nil {
+		http.Error(w, `{"error": "Invalid input"}`, http.StatusBadRequest)
+		return
+	}
+
+	if job.Type != JobPickup && job.Type != JobDelivery {
+		http.Error(w, `{"error": "Job type must be pickup or delivery"}`, http.StatusBadRequest)
+		return
+	}
+	if job.WindowStart.IsZero() || job.WindowEnd.Before(job.WindowStart) {
+		http.Error(w, `{"error": "A valid time window is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	transactionID, err := primitive.ObjectIDFromHex(job.TransactionID)
+	if err != nil {
+		http.Error(w, `{"error": "Invalid transaction ID"}`, http.StatusBadRequest)
+		return
+	}
+	zoneID, err := primitive.ObjectIDFromHex(job.ZoneID)
+	if err != nil {
+		http.Error(w, `{"error": "Invalid zone ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var transaction models.Transaction
+	if err := config.TransactionCollection.FindOne(ctx, bson.M{"_id": transactionID}).Decode(&transaction); err != nil {
+		http.Error(w, `{"error": "Transaction not found"}`, http.StatusNotFound)
+		return
+	}
+
+	var zone models.DeliveryZone
+	if err := config.DeliveryZoneCollection.FindOne(ctx, bson.M{"_id": zoneID}).Decode(&zone); err != nil {
+		http.Error(w, `{"error": "Zone not found"}`, http.StatusNotFound)
+		return
+	}
+
+	if job.CourierID != "" {
+		courier, err := findCourier(ctx, job.CourierID)
+		if err != nil {
+			http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+			return
+		}
+		job.CourierName = courier.Username
+	}
+
+	if job.Address == "" && transaction.CustomerID != "" {
+		if customer, err := findCustomer(ctx, transaction.CustomerID); err == nil {
+			job.Address = customer.Address
+		}
+	}
+	if job.Address == "" {
+		http.Error(w, `{"error": "Address is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	job.InvoiceNumber = transaction.InvoiceNumber
+	job.CustomerName = transaction.CustomerName
+	job.PhoneNumber = transaction.PhoneNumber
+	job.ZoneName = zone.Name
+	job.Fee = zone.Fee
+	job.Status = JobScheduled
+	job.FailureReason = ""
+	job.CreatedAt = now
+	job.UpdatedAt = now
+	job.CompletedAt = nil
+
+	result, err := config.DeliveryJobCollection.InsertOne(ctx, job)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to create job"}`, http.StatusInternalServerError)
+		return
+	}
+
+	job.ID = result.InsertedID.(primitive.ObjectID).Hex()
+
+	if err := adjustDeliveryFee(ctx, job.TransactionID, job.Fee); err != nil {
+		config.DeliveryJobCollection.DeleteOne(ctx, bson.M{"_id": result.InsertedID})
+		http.Error(w, `{"error": "Failed to add delivery fee to transaction"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"message": "Job scheduled successfully",
+		"job":     job,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetAllDeliveryJobs lists delivery jobs, filtered by ?date=yyyy-mm-dd (time window start),
+// ?status=, ?courier_id= and ?transaction_id=
+func GetAllDeliveryJobs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := bson.M{}
+	for _, field := range []string{"status", "courier_id", "transaction_id", "type"} {
+		if value := query.Get(field); value != "" {
+			filter[field] = value
+		}
+	}
+	if date := query.Get("date"); date != "" {
+		day, err := time.ParseInLocation("2006-01-02", date, config.Location())
+		if err != nil {
+			http.Error(w, "Invalid date, use yyyy-mm-dd", http.StatusBadRequest)
+			return
+		}
+		filter["window_start"] = bson.M{"$gte": day, "$lt": day.AddDate(0, 0, 1)}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := config.DeliveryJobCollection.Find(ctx, filter, options.Find().SetSort(bson.M{"window_start": 1}))
+	if err != nil {
+		http.Error(w, "Failed to fetch jobs", http.StatusInternalServerError)
+		return
+	}
+	jobs, err := decodeJobs(ctx, cursor)
+	if err != nil {
+		http.Error(w, "Failed to read job data", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(jobs)
+}
+
+// GetDeliveryJobByID retrieves a delivery job by its ID
+func GetDeliveryJobByID(w http.ResponseWriter, r *http.Request) {
+	jobID := r.URL.Query().Get("id")
+	if jobID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(jobID)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var job models.DeliveryJob
+	err = config.DeliveryJobCollection.FindOne(context.TODO(), bson.M{"_id": id}).Decode(&job)
+	if err != nil {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// UpdateDeliveryJob reschedules a job or (re)assigns its courier, address and notes
+func UpdateDeliveryJob(w http.ResponseWriter, r *http.Request) {
+	jobID := r.URL.Query().Get("id")
+	if jobID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(jobID)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var updatedJob models.DeliveryJob
+	if err := json.NewDecoder(r.Body).Decode(&updatedJob); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	if updatedJob.WindowStart.IsZero() || updatedJob.WindowEnd.Before(updatedJob.WindowStart) {
+		http.Error(w, "A valid time window is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	courierName := ""
+	if updatedJob.CourierID != "" {
+		courier, err := findCourier(ctx, updatedJob.CourierID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		courierName = courier.Username
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"address":      updatedJob.Address,
+			"window_start": updatedJob.WindowStart,
+			"window_end":   updatedJob.WindowEnd,
+			"courier_id":   updatedJob.CourierID,
+			"courier_name": courierName,
+			"notes":        updatedJob.Notes,
+			"updated_at":   time.Now(),
+		},
+	}
+
+	// Pekerjaan yang sudah selesai tidak bisa dijadwalkan ulang
+	result, err := config.DeliveryJobCollection.UpdateOne(ctx, bson.M{"_id": id, "status": bson.M{"$ne": JobDone}}, update)
+	if err != nil {
+		http.Error(w, "Failed to update job", http.StatusInternalServerError)
+		return
+	}
+
+	if result.MatchedCount == 0 {
+		http.Error(w, "Job not found or already done", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Job updated successfully"})
+}
+
+// DeleteDeliveryJob removes a job that has not been done and takes its fee off the transaction
+func DeleteDeliveryJob(w http.ResponseWriter, r *http.Request) {
+	jobID := r.URL.Query().Get("id")
+	if jobID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(jobID)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var job models.DeliveryJob
+	err = config.DeliveryJobCollection.FindOneAndDelete(ctx, bson.M{"_id": id, "status": bson.M{"$ne": JobDone}}).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		http.Error(w, "Job not found or already done", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to delete job", http.StatusInternalServerError)
+		return
+	}
+
+	if err := adjustDeliveryFee(ctx, job.TransactionID, -job.Fee); err != nil {
+		http.Error(w, "Job deleted but failed to remove fee from transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Job deleted successfully"})
+}
+
+// updateJobStatus moves a job to a new status; when courierID is set the job must belong to that courier
+func updateJobStatus(w http.ResponseWriter, r *http.Request, courierID string) {
+	jobID := r.URL.Query().Get("id")
+	if jobID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(jobID)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		Status string `json:"status"`
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := jobTransitions[request.Status]; !ok {
+		http.Error(w, "Invalid status", http.StatusBadRequest)
+		return
+	}
+	if request.Status == JobFailed && request.Reason == "" {
+		http.Error(w, "A reason is required for failed jobs", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": id}
+	if courierID != "" {
+		filter["courier_id"] = courierID
+	}
+
+	var job models.DeliveryJob
+	if err := config.DeliveryJobCollection.FindOne(ctx, filter).Decode(&job); err != nil {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	if !canMoveJob(job.Status, request.Status) {
+		http.Error(w, "Cannot move job from "+job.Status+" to "+request.Status, http.StatusConflict)
+		return
+	}
+
+	now := time.Now()
+	set := bson.M{"status": request.Status, "updated_at": now}
+	switch request.Status {
+	case JobDone:
+		set["completed_at"] = now
+	case JobFailed:
+		set["failure_reason"] = request.Reason
+	case JobScheduled:
+		set["failure_reason"] = ""
+	}
+
+	// Filter status lama mencegah dua perubahan bersamaan saling menimpa
+	filter["status"] = job.Status
+	result, err := config.DeliveryJobCollection.UpdateOne(ctx, filter, bson.M{"$set": set})
+	if err != nil {
+		http.Error(w, "Failed to update job status", http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, "Job was changed by someone else, please retry", http.StatusConflict)
+		return
+	}
+
+	if request.Status == JobDone && job.Type == JobDelivery {
+		if transaction, err := findTransaction(ctx, job.TransactionID); err == nil {
+			notifyTransaction(transaction, EventDelivered)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Job status updated successfully"})
+}
+
+// UpdateDeliveryJobStatus lets an admin move any job to a new status
+func UpdateDeliveryJobStatus(w http.ResponseWriter, r *http.Request) {
+	updateJobStatus(w, r, "")
+}
+
+// UpdateCourierJobStatus lets the logged-in courier update the status of one of their own jobs
+func UpdateCourierJobStatus(w http.ResponseWriter, r *http.Request) {
+	updateJobStatus(w, r, r.Header.Get("User-ID"))
+}
+
+// GetCourierJobs lists today's jobs assigned to the logged-in courier, in time-window order
+func GetCourierJobs(w http.ResponseWriter, r *http.Request) {
+	courierID := r.Header.Get("User-ID")
+	if courierID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	start, end := todayRange(time.Now())
+	filter := bson.M{
+		"courier_id":   courierID,
+		"window_start": bson.M{"$gte": start, "$lt": end},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := config.DeliveryJobCollection.Find(ctx, filter, options.Find().SetSort(bson.M{"window_start": 1}))
+	if err != nil {
+		http.Error(w, "Failed to fetch jobs", http.StatusInternalServerError)
+		return
+	}
+	jobs, err := decodeJobs(ctx, cursor)
+	if err != nil {
+		http.Error(w, "Failed to read job data", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(jobs)
+}
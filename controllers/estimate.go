@@ -0,0 +1,266 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"time"
+
+	"apkclaundry/config"
+	"apkclaundry/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxEstimateDays limits how far ahead holidays are loaded and working hours are searched
+const maxEstimateDays = 60
+
+var (
+	errServiceNotFound     = errors.New("service not found")
+	errExpressNotAvailable = errors.New("express is not available for this service")
+)
+
+// findServiceByName looks up a service by its name, ignoring case
+func findServiceByName(ctx context.Context, name string) (models.Service, error) {
+	var service models.Service
+	filter := bson.M{"name": bson.M{"$regex": "^" + regexp.QuoteMeta(name) + "$", "$options": "i"}}
+	err := config.ServiceCollection.FindOne(ctx, filter).Decode(&service)
+	if err == mongo.ErrNoDocuments {
+		return service, errServiceNotFound
+	}
+	return service, err
+}
+
+// loadHolidays returns the holidays from the given day onwards, keyed by yyyy-mm-dd
+func loadHolidays(ctx context.Context, from time.Time) (map[string]bool, error) {
+	start, _ := todayRange(from)
+	cursor, err := config.HolidayCollection.Find(ctx, bson.M{
+		"date": bson.M{"$gte": start, "$lt": start.AddDate(0, 0, maxEstimateDays)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	holidays := map[string]bool{}
+	for cursor.Next(ctx) {
+		var holiday models.Holiday
+		if err := cursor.Decode(&holiday); err != nil {
+			return nil, err
+		}
+		holidays[holiday.Date.In(config.Location()).Format("2006-01-02")] = true
+	}
+	return holidays, nil
+}
+
+// addWorkingHours returns the moment that lies the given number of working hours after start,
+// counting only time inside operating hours on days that are not closed or holidays
+func addWorkingHours(start time.Time, hours float64, operating config.OperatingHours, holidays map[string]bool) time.Time {
+	remaining := time.Duration(hours * float64(time.Hour))
+	current := start.In(config.Location())
+
+	for day := 0; day <= maxEstimateDays; day++ {
+		midnight := time.Date(current.Year(), current.Month(), current.Day(), 0, 0, 0, 0, current.Location())
+		open := midnight.Add(time.Duration(operating.OpenMinute) * time.Minute)
+		closing := midnight.Add(time.Duration(operating.CloseMinute) * time.Minute)
+
+		workingDay := !operating.ClosedDays[int(midnight.Weekday())] && !holidays[midnight.Format("2006-01-02")]
+		if workingDay && current.Before(closing) {
+			if current.Before(open) {
+				current = open
+			}
+			available := closing.Sub(current)
+			if remaining <= available {
+				return current.Add(remaining)
+			}
+			remaining -= available
+		}
+
+		current = midnight.AddDate(0, 0, 1)
+	}
+	return current.Add(remaining)
+}
+
+// queuedKilograms sums the weight of orders that are still being processed
+func queuedKilograms(ctx context.Context) (float64, error) {
+	cursor, err := config.TransactionCollection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"status": bson.M{"$in": openStatuses}}}},
+		{{Key: "$group", Value: bson.M{"_id": nil, "kg": bson.M{"$sum": "$weight_per_kg"}}}},
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var result []struct {
+		Kg float64 `bson:"kg"`
+	}
+	if err := cursor.All(ctx, &result); err != nil || len(result) == 0 {
+		return 0, err
+	}
+	return result[0].Kg, nil
+}
+
+// estimateReadyAt computes when an order will be ready: the service's standard (or express)
+// turnaround plus the time needed to work through the kilograms already queued, laid out over
+// operating hours and skipping closed days and holidays. Express orders skip the queue.
+func estimateReadyAt(ctx context.Context, transaction models.Transaction, now time.Time) (time.Time, error) {
+	service, err := findServiceByName(ctx, transaction.ServiceType)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	hours := service.TurnaroundHours
+	if transaction.Express {
+		if service.ExpressTurnaroundHours <= 0 {
+			return time.Time{}, errExpressNotAvailable
+		}
+		hours = service.ExpressTurnaroundHours
+	}
+
+	operating := config.Operating()
+	if !transaction.Express && operating.DailyCapacityKg > 0 {
+		queued, err := queuedKilograms(ctx)
+		if err != nil {
+			return time.Time{}, err
+		}
+		hoursPerDay := float64(operating.CloseMinute-operating.OpenMinute) / 60
+		hours += queued / (operating.DailyCapacityKg / hoursPerDay)
+	}
+
+	holidays, err := loadHolidays(ctx, now)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// Bulatkan ke atas ke 15 menit berikutnya agar mudah dibaca pelanggan
+	estimate := addWorkingHours(now, hours, operating, holidays)
+	rounded := estimate.Truncate(15 * time.Minute)
+	if rounded.Before(estimate) {
+		rounded = rounded.Add(15 * time.Minute)
+	}
+	return rounded, nil
+}
+
+// GetOverdueTransactions lists orders still in process whose estimated ready time has passed
+func GetOverdueTransactions(w http.ResponseWriter, r *http.Request) {
+	filter := bson.M{
+		"status":             bson.M{"$in": openStatuses},
+		"estimated_ready_at": bson.M{"$lt": time.Now()},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := config.TransactionCollection.Find(ctx, filter, options.Find().SetSort(bson.M{"estimated_ready_at": 1}))
+	if err != nil {
+		http.Error(w, "Failed to fetch transactions", http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	transactions := []models.Transaction{}
+	for cursor.Next(ctx) {
+		var transaction models.Transaction
+		if err := cursor.Decode(&transaction); err != nil {
+			http.Error(w, "Failed to read transaction data", http.StatusInternalServerError)
+			return
+		}
+		transaction.TransactionDateFormatted = formatDate(transaction.TransactionDate)
+		transactions = append(transactions, transaction)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transactions)
+}
+
+// CreateHoliday handles the creation of a new holiday
+func CreateHoliday(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Date string `json:"date"` // yyyy-mm-dd
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, `{"error": "Invalid input"}`, http.StatusBadRequest)
+		return
+	}
+
+	date, err := time.ParseInLocation("2006-01-02", request.Date, config.Location())
+	if err != nil {
+		http.Error(w, `{"error": "Invalid date, use yyyy-mm-dd"}`, http.StatusBadRequest)
+		return
+	}
+	holiday := models.Holiday{Date: date, Name: request.Name}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := config.HolidayCollection.InsertOne(ctx, holiday)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to create holiday"}`, http.StatusInternalServerError)
+		return
+	}
+
+	holiday.ID = result.InsertedID.(primitive.ObjectID).Hex()
+
+	response := map[string]interface{}{
+		"message": "Holiday created successfully",
+		"holiday": holiday,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetAllHolidays retrieves all holidays, earliest first
+func GetAllHolidays(w http.ResponseWriter, r *http.Request) {
+	cursor, err := config.HolidayCollection.Find(context.TODO(), bson.M{}, options.Find().SetSort(bson.M{"date": 1}))
+	if err != nil {
+		http.Error(w, "Failed to fetch holidays", http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(context.TODO())
+
+	holidays := []models.Holiday{}
+	if err := cursor.All(context.TODO(), &holidays); err != nil {
+		http.Error(w, "Failed to read holiday data", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(holidays)
+}
+
+// DeleteHoliday deletes a holiday by its ID
+func DeleteHoliday(w http.ResponseWriter, r *http.Request) {
+	holidayID := r.URL.Query().Get("id")
+	if holidayID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(holidayID)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	result, err := config.HolidayCollection.DeleteOne(context.TODO(), bson.M{"_id": id})
+	if err != nil {
+		http.Error(w, "Failed to delete holiday", http.StatusInternalServerError)
+		return
+	}
+
+	if result.DeletedCount == 0 {
+		http.Error(w, "Holiday not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Holiday deleted successfully"})
+}
@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"apkclaundry/config"
+	"apkclaundry/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// nextSequence atomically increments and returns the counter with the given key.
+// The upsert makes the first call for a new key start at 1, and because the
+// increment happens inside MongoDB it is safe across concurrent serverless instances.
+func nextSequence(ctx context.Context, key string) (int64, error) {
+	var counter models.Counter
+	err := config.CounterCollection.FindOneAndUpdate(ctx,
+		bson.M{"_id": key},
+		bson.M{"$inc": bson.M{"seq": 1}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&counter)
+	if err != nil {
+		return 0, err
+	}
+	return counter.Seq, nil
+}
+
+// invoicePeriod returns the date part of an invoice number; the counter resets whenever it changes.
+// INVOICE_RESET=monthly gives 202610, anything else resets daily and gives 20261017.
+func invoicePeriod(at time.Time) string {
+	local := at.In(config.Location())
+	if config.GetEnv("INVOICE_RESET", "daily") == "monthly" {
+		return local.Format("200601")
+	}
+	return local.Format("20060102")
+}
+
+// formatInvoiceNumber fills the INVOICE_FORMAT template ({prefix}, {outlet}, {date}, {seq}).
+// A negative seq leaves the {seq} placeholder in place.
+func formatInvoiceNumber(outlet, period string, seq int64) string {
+	digits := config.GetEnvInt("INVOICE_SEQ_DIGITS", 4)
+	number := config.GetEnv("INVOICE_FORMAT", "{prefix}-{outlet}-{date}-{seq}")
+
+	number = strings.ReplaceAll(number, "{prefix}", config.GetEnv("INVOICE_PREFIX", "LDY"))
+	number = strings.ReplaceAll(number, "{outlet}", outlet)
+	number = strings.ReplaceAll(number, "{date}", period)
+	if seq >= 0 {
+		number = strings.ReplaceAll(number, "{seq}", fmt.Sprintf("%0*d", digits, seq))
+	}
+
+	// Rapikan pemisah ganda jika {outlet} kosong
+	number = strings.ReplaceAll(number, "--", "-")
+	return strings.Trim(number, "-")
+}
+
+// nextInvoiceNumber generates the next invoice number for an outlet. The period comes from the
+// server clock, never from a date sent by the client.
+func nextInvoiceNumber(ctx context.Context, outlet string) (string, error) {
+	period := invoicePeriod(time.Now())
+	// Counter dikunci pada nomor yang sudah dirender tanpa {seq}, sehingga format tanpa {outlet}
+	// atau perubahan prefix tidak menghasilkan nomor kembar
+	seq, err := nextSequence(ctx, "invoice:"+formatInvoiceNumber(outlet, period, -1))
+	if err != nil {
+		return "", err
+	}
+	return formatInvoiceNumber(outlet, period, seq), nil
+}
+
+// SearchTransactionsByInvoice finds transactions whose invoice number starts with ?number=
+// (case-insensitive), so staff can look up an order from what the customer reads over the phone
+func SearchTransactionsByInvoice(w http.ResponseWriter, r *http.Request) {
+	number := strings.TrimSpace(r.URL.Query().Get("number"))
+	if number == "" {
+		http.Error(w, "Invoice number not provided", http.StatusBadRequest)
+		return
+	}
+
+	filter := bson.M{"invoice_number": bson.M{
+		"$regex":   "^" + regexp.QuoteMeta(number),
+		"$options": "i",
+	}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := config.TransactionCollection.Find(ctx, filter,
+		options.Find().SetSort(bson.M{"invoice_number": 1}).SetLimit(50))
+	if err != nil {
+		http.Error(w, "Failed to fetch transactions", http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var transactions []models.Transaction
+	for cursor.Next(ctx) {
+		var transaction models.Transaction
+		if err := cursor.Decode(&transaction); err != nil {
+			http.Error(w, "Failed to read transaction data", http.StatusInternalServerError)
+			return
+		}
+		transaction.TransactionDateFormatted = formatDate(transaction.TransactionDate)
+		transactions = append(transactions, transaction)
+	}
+
+	if len(transactions) == 0 {
+		http.Error(w, "No transactions found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transactions)
+}
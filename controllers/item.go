@@ -94,42 +94,11 @@ func GetItemByID(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(item)
 }
 
-// UpdateItem updates an item's data by its ID
+// UpdateItem updates an item's data by its ID. The body is a JSON merge patch:
+// only the fields it contains are changed, and each change is kept in the revision history
 func UpdateItem(w http.ResponseWriter, r *http.Request) {
-	itemID := r.URL.Query().Get("id")
-	if itemID == "" {
-		http.Error(w, "ID not provided", http.StatusBadRequest)
-		return
-	}
-
-	id, err := primitive.ObjectIDFromHex(itemID)
-	if err != nil {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
-		return
-	}
-
-	var updatedItem models.Item
-	if err := json.NewDecoder(r.Body).Decode(&updatedItem); err != nil {
-		http.Error(w, "Invalid input", http.StatusBadRequest)
-		return
-	}
-
-	update := bson.M{
-		"$set": bson.M{
-			"item_name": updatedItem.ItemName,
-			"quantity":  updatedItem.Quantity,
-			"price":     updatedItem.Price,
-		},
-	}
-
-	result, err := config.ItemCollection.UpdateOne(context.TODO(), bson.M{"_id": id}, update)
-	if err != nil {
-		http.Error(w, "Failed to update item", http.StatusInternalServerError)
-		return
-	}
-
-	if result.MatchedCount == 0 {
-		http.Error(w, "Item not found", http.StatusNotFound)
+	var item models.Item
+	if !patchDocument(w, r, config.ItemCollection, "item", &item, itemPatchFields, nil) {
 		return
 	}
 
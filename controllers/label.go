@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"apkclaundry/config"
+	"apkclaundry/models"
+	"apkclaundry/receipt"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// GetTransactionLabels renders the labels of an order: a PDF with the order label and one label
+// per garment (default), or the barcode of a single label as PNG (?format=png&piece=n, piece 0 is
+// the order label). ?symbology=code128|qr chooses the barcode, LABEL_SYMBOLOGY sets the default.
+// The page size follows LABEL_WIDTH_MM × LABEL_HEIGHT_MM (default 50 × 30). A PDF holds at most
+// LABEL_MAX_PER_PRINT garment labels (default 100); ?from=n prints the batch starting at piece n and
+// X-Next-Piece tells where the next batch starts.
+func GetTransactionLabels(w http.ResponseWriter, r *http.Request) {
+	transactionID := r.URL.Query().Get("id")
+	if transactionID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "pdf"
+	}
+	if format != "pdf" && format != "png" {
+		http.Error(w, "Invalid format, use pdf or png", http.StatusBadRequest)
+		return
+	}
+	symbology := r.URL.Query().Get("symbology")
+	if symbology == "" {
+		symbology = config.GetEnv("LABEL_SYMBOLOGY", receipt.SymbologyCode128)
+	}
+	if symbology != receipt.SymbologyCode128 && symbology != receipt.SymbologyQR {
+		http.Error(w, "Invalid symbology, use code128 or qr", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	transaction, err := findTransaction(ctx, transactionID)
+	if err != nil {
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+		return
+	}
+
+	if format == "png" {
+		piece := 0
+		if value := r.URL.Query().Get("piece"); value != "" {
+			piece, err = strconv.Atoi(value)
+			if err != nil || piece < 0 || piece > transaction.Pieces {
+				http.Error(w, "Invalid piece", http.StatusBadRequest)
+				return
+			}
+		}
+
+		// Label potongan ada di akhir; piece 0 hanya menghasilkan label pesanan
+		labels := receipt.LabelsForTransaction(transaction, piece, 1)
+		var image bytes.Buffer
+		if err := labels[len(labels)-1].WritePNG(&image, symbology); err != nil {
+			http.Error(w, "Failed to render label", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(image.Bytes())
+		return
+	}
+
+	from := 1
+	if value := r.URL.Query().Get("from"); value != "" {
+		from, err = strconv.Atoi(value)
+		if err != nil || from < 1 || (from > transaction.Pieces && from > 1) {
+			http.Error(w, "Invalid from piece", http.StatusBadRequest)
+			return
+		}
+	}
+	limit := config.GetEnvInt("LABEL_MAX_PER_PRINT", 100)
+	if limit < 1 {
+		limit = 1
+	}
+	labels := receipt.LabelsForTransaction(transaction, from, limit)
+	if next := from + limit; next <= transaction.Pieces {
+		w.Header().Set("X-Next-Piece", strconv.Itoa(next))
+	}
+
+	var pdf bytes.Buffer
+	width := config.GetEnvFloat("LABEL_WIDTH_MM", 50)
+	height := config.GetEnvFloat("LABEL_HEIGHT_MM", 30)
+	if err := receipt.WriteLabelsPDF(&pdf, labels, symbology, width, height); err != nil {
+		http.Error(w, "Failed to render labels", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `inline; filename="label-`+transaction.ID+`.pdf"`)
+	w.Write(pdf.Bytes())
+}
+
+// ScanLabel looks up the order behind a scanned label code. GET /scan?code= only returns the
+// order; POST /scan {"code": "...", "advance": true} also moves it to the next status, or to
+// {"status": "..."} when given, so a handheld scanner can work an order in one call.
+func ScanLabel(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Code    string `json:"code"`
+		Advance bool   `json:"advance"`
+		Status  string `json:"status"`
+	}
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+	} else {
+		request.Code = r.URL.Query().Get("code")
+	}
+
+	invoiceNumber, piece := receipt.ParseLabelCode(request.Code)
+	if invoiceNumber == "" {
+		http.Error(w, "Code not provided", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var transaction models.Transaction
+	if err := config.TransactionCollection.FindOne(ctx, bson.M{"invoice_number": invoiceNumber}).Decode(&transaction); err != nil {
+		// Label transaksi lama tanpa nomor nota berisi ID transaksi
+		if transaction, err = findTransaction(ctx, invoiceNumber); err != nil {
+			http.Error(w, "No order found for this code", http.StatusNotFound)
+			return
+		}
+	}
+	if piece > transaction.Pieces {
+		http.Error(w, "Piece "+strconv.Itoa(piece)+" does not belong to this order", http.StatusNotFound)
+		return
+	}
+
+	advanced := false
+	if request.Advance || request.Status != "" {
+		status := request.Status
+		if status == "" {
+			status = nextStatus(transaction.Status)
+		}
+		if status == "" {
+			http.Error(w, "Order is already finished", http.StatusConflict)
+			return
+		}
+		if code, msg := changeTransactionStatus(ctx, transaction, status); code != http.StatusOK {
+			http.Error(w, msg, code)
+			return
+		}
+		transaction, _ = findTransaction(ctx, transaction.ID)
+		advanced = true
+	}
+
+	transaction.TransactionDateFormatted = formatDate(transaction.TransactionDate)
+	response := map[string]interface{}{
+		"transaction": transaction,
+		"piece":       piece,
+		"next_status": nextStatus(transaction.Status),
+		"advanced":    advanced,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
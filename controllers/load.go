@@ -0,0 +1,579 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"apkclaundry/config"
+	"apkclaundry/models"
+	"apkclaundry/receipt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	LoadOpen   = "open"
+	LoadClosed = "closed"
+)
+
+// boardStages are the columns of the production board, in order
+var boardStages = []string{StatusReceived, StatusWashing, StatusDrying, StatusIroning, StatusReady}
+
+// loadTemperature returns the wash temperature shared by all orders of a load. Orders are
+// compatible when their services have the same wash temperature; services without a temperature
+// are only compatible with orders of the same service.
+func loadTemperature(ctx context.Context, transactions []models.Transaction) (int, string, error) {
+	temperatures := map[string]int{}
+	for _, transaction := range transactions {
+		name := strings.ToLower(transaction.ServiceType)
+		if _, seen := temperatures[name]; seen {
+			continue
+		}
+		service, err := findServiceByName(ctx, transaction.ServiceType)
+		if err != nil && err != errServiceNotFound {
+			return 0, "", err
+		}
+		temperatures[name] = service.WashTemperature
+	}
+
+	temperature, serviceType := -1, ""
+	for _, transaction := range transactions {
+		current := temperatures[strings.ToLower(transaction.ServiceType)]
+		if temperature < 0 {
+			temperature, serviceType = current, transaction.ServiceType
+			continue
+		}
+		if current != temperature || (current == 0 && !strings.EqualFold(transaction.ServiceType, serviceType)) {
+			return 0, "", fmt.Errorf("Order %s (%s) cannot be washed together with %s", transaction.InvoiceNumber, transaction.ServiceType, serviceType)
+		}
+		if !strings.EqualFold(transaction.ServiceType, serviceType) {
+			serviceType = "mixed"
+		}
+	}
+	return temperature, serviceType, nil
+}
+
+// findLoad loads a load by the ?id= of the request
+func findLoad(ctx context.Context, r *http.Request) (models.Load, int, string) {
+	var load models.Load
+	loadID := r.URL.Query().Get("id")
+	if loadID == "" {
+		return load, http.StatusBadRequest, "ID not provided"
+	}
+	id, err := primitive.ObjectIDFromHex(loadID)
+	if err != nil {
+		return load, http.StatusBadRequest, "Invalid ID"
+	}
+	if err := config.LoadCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&load); err != nil {
+		return load, http.StatusNotFound, "Load not found"
+	}
+	return load, http.StatusOK, ""
+}
+
+// releaseLoad removes the load reference from its orders so they can join another load
+func releaseLoad(ctx context.Context, loadID string) error {
+	_, err := config.TransactionCollection.UpdateMany(ctx, bson.M{"load_id": loadID}, bson.M{"$unset": bson.M{"load_id": ""}})
+	return err
+}
+
+// CreateLoad groups orders into one wash load:
+// {"transaction_ids": [...], "color_group": "dark", "fabric": "cotton", "machine_id": "..."}.
+// The orders must be waiting to be washed, not be in another load and have compatible services;
+// when a machine is given the load must fit its capacity.
+func CreateLoad(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		TransactionIDs []string `json:"transaction_ids"`
+		ColorGroup     string   `json:"color_group"`
+		Fabric         string   `json:"fabric"`
+		MachineID      string   `json:"machine_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, `{"error": "Invalid input"}`, http.StatusBadRequest)
+		return
+	}
+	if len(request.TransactionIDs) == 0 || strings.TrimSpace(request.ColorGroup) == "" {
+		http.Error(w, `{"error": "Transactions and color group are required"}`, http.StatusBadRequest)
+		return
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(request.TransactionIDs))
+	for _, transactionID := range request.TransactionIDs {
+		id, err := primitive.ObjectIDFromHex(transactionID)
+		if err != nil {
+			http.Error(w, `{"error": "Invalid transaction ID"}`, http.StatusBadRequest)
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	cursor, err := config.TransactionCollection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		http.Error(w, `{"error": "Failed to fetch transactions"}`, http.StatusInternalServerError)
+		return
+	}
+	var transactions []models.Transaction
+	if err := cursor.All(ctx, &transactions); err != nil {
+		http.Error(w, `{"error": "Failed to read transaction data"}`, http.StatusInternalServerError)
+		return
+	}
+	if len(transactions) != len(ids) {
+		http.Error(w, `{"error": "Some transactions were not found"}`, http.StatusNotFound)
+		return
+	}
+
+	now := time.Now()
+	load := models.Load{
+		ColorGroup:     strings.ToLower(strings.TrimSpace(request.ColorGroup)),
+		Fabric:         request.Fabric,
+		TransactionIDs: []string{},
+		InvoiceNumbers: []string{},
+		Stage:          StatusReceived,
+		Status:         LoadOpen,
+		CreatedBy:      r.Header.Get("Username"),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	for _, transaction := range transactions {
+		if statusIndex(transaction.Status) != 0 {
+			http.Error(w, `{"error": "Order `+transaction.InvoiceNumber+` is already `+transaction.Status+`"}`, http.StatusConflict)
+			return
+		}
+		if transaction.LoadID != "" {
+			http.Error(w, `{"error": "Order `+transaction.InvoiceNumber+` is already in another load"}`, http.StatusConflict)
+			return
+		}
+		load.TransactionIDs = append(load.TransactionIDs, transaction.ID)
+		load.InvoiceNumbers = append(load.InvoiceNumbers, transaction.InvoiceNumber)
+		load.TotalKg += transaction.WeightPerKg
+	}
+
+	load.Temperature, load.ServiceType, err = loadTemperature(ctx, transactions)
+	if err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusUnprocessableEntity)
+		return
+	}
+
+	if request.MachineID != "" {
+		var machine models.Machine
+		machineID, _ := primitive.ObjectIDFromHex(request.MachineID)
+		if err := config.MachineCollection.FindOne(ctx, bson.M{"_id": machineID}).Decode(&machine); err != nil {
+			http.Error(w, `{"error": "Machine not found"}`, http.StatusNotFound)
+			return
+		}
+		if load.TotalKg > machine.CapacityKg {
+			http.Error(w, fmt.Sprintf(`{"error": "Load of %.1f kg exceeds the %.1f kg capacity of %s"}`, load.TotalKg, machine.CapacityKg, machine.Code), http.StatusUnprocessableEntity)
+			return
+		}
+	}
+
+	seq, err := nextSequence(ctx, "load:"+now.In(config.Location()).Format("20060102"))
+	if err != nil {
+		http.Error(w, `{"error": "Failed to generate load code"}`, http.StatusInternalServerError)
+		return
+	}
+	load.Code = fmt.Sprintf("L%s-%02d", now.In(config.Location()).Format("20060102"), seq)
+
+	result, err := config.LoadCollection.InsertOne(ctx, load)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to create load"}`, http.StatusInternalServerError)
+		return
+	}
+	load.ID = result.InsertedID.(primitive.ObjectID).Hex()
+
+	// Tandai pesanan secara atomik; jika ada yang sudah diambil muatan lain, batalkan semuanya
+	assigned, err := config.TransactionCollection.UpdateMany(ctx,
+		bson.M{"_id": bson.M{"$in": ids}, "load_id": nil},
+		bson.M{"$set": bson.M{"load_id": load.ID}},
+	)
+	if err != nil || assigned.ModifiedCount != int64(len(ids)) {
+		releaseLoad(ctx, load.ID)
+		config.LoadCollection.DeleteOne(ctx, bson.M{"_id": result.InsertedID})
+		http.Error(w, `{"error": "Some orders were added to another load, please retry"}`, http.StatusConflict)
+		return
+	}
+
+	response := map[string]interface{}{
+		"message": "Load created successfully",
+		"load":    load,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetAllLoads lists open loads, oldest first, filtered by ?stage=; ?status=closed shows finished loads
+func GetAllLoads(w http.ResponseWriter, r *http.Request) {
+	filter := bson.M{"status": LoadOpen}
+	if status := r.URL.Query().Get("status"); status != "" {
+		filter["status"] = status
+	}
+	if stage := r.URL.Query().Get("stage"); stage != "" {
+		filter["stage"] = stage
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := config.LoadCollection.Find(ctx, filter, options.Find().SetSort(bson.M{"created_at": 1}).SetLimit(500))
+	if err != nil {
+		http.Error(w, "Failed to fetch loads", http.StatusInternalServerError)
+		return
+	}
+	loads := []models.Load{}
+	if err := cursor.All(ctx, &loads); err != nil {
+		http.Error(w, "Failed to read load data", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(loads)
+}
+
+// GetLoadByID retrieves a load by its ID
+func GetLoadByID(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	load, code, msg := findLoad(ctx, r)
+	if code != http.StatusOK {
+		http.Error(w, msg, code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(load)
+}
+
+// DeleteLoad dissolves a load that has not been washed yet, releasing its orders
+func DeleteLoad(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	load, code, msg := findLoad(ctx, r)
+	if code != http.StatusOK {
+		http.Error(w, msg, code)
+		return
+	}
+
+	id, _ := primitive.ObjectIDFromHex(load.ID)
+	result, err := config.LoadCollection.DeleteOne(ctx, bson.M{"_id": id, "stage": StatusReceived, "status": LoadOpen})
+	if err != nil {
+		http.Error(w, "Failed to delete load", http.StatusInternalServerError)
+		return
+	}
+	if result.DeletedCount == 0 {
+		http.Error(w, "Only a load that has not been washed can be dissolved", http.StatusConflict)
+		return
+	}
+	if err := releaseLoad(ctx, load.ID); err != nil {
+		log.Printf("Failed to release orders of load %s: %v", load.Code, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Load dissolved successfully"})
+}
+
+// AdvanceLoad moves a whole load to its next stage, or to {"stage": "..."}, updating the status
+// of every order in it. Moving to washing or drying with {"machine_id": "..."} starts a cycle on
+// that machine (which checks the load against its capacity); the cycle of the previous stage is
+// finished automatically. Reaching ready closes the load.
+func AdvanceLoad(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Stage     string `json:"stage"`
+		MachineID string `json:"machine_id"`
+		Program   string `json:"program"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	load, code, msg := findLoad(ctx, r)
+	if code != http.StatusOK {
+		http.Error(w, msg, code)
+		return
+	}
+	if load.Status != LoadOpen {
+		http.Error(w, "Load is already closed", http.StatusConflict)
+		return
+	}
+
+	stage := request.Stage
+	if stage == "" {
+		stage = nextStatus(load.Stage)
+	}
+	from, to := statusIndex(load.Stage), statusIndex(stage)
+	if to <= from || to > statusIndex(StatusReady) {
+		http.Error(w, "Cannot move load from "+load.Stage+" to "+stage, http.StatusConflict)
+		return
+	}
+
+	var machine models.Machine
+	if request.MachineID != "" {
+		machineID, _ := primitive.ObjectIDFromHex(request.MachineID)
+		if err := config.MachineCollection.FindOne(ctx, bson.M{"_id": machineID}).Decode(&machine); err != nil {
+			http.Error(w, "Machine not found", http.StatusNotFound)
+			return
+		}
+		if machineStage[machine.Type] != stage {
+			http.Error(w, "A "+machine.Type+" cannot be used for "+stage, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Klaim perpindahan tahap dulu agar dua operator tidak memajukan muatan yang sama
+	now := time.Now()
+	id, _ := primitive.ObjectIDFromHex(load.ID)
+	claimed, err := config.LoadCollection.UpdateOne(ctx,
+		bson.M{"_id": id, "stage": load.Stage, "status": LoadOpen},
+		bson.M{"$set": bson.M{"stage": stage, "updated_at": now}},
+	)
+	if err != nil {
+		http.Error(w, "Failed to update load", http.StatusInternalServerError)
+		return
+	}
+	if claimed.ModifiedCount == 0 {
+		http.Error(w, "Load was changed by someone else, please retry", http.StatusConflict)
+		return
+	}
+
+	// Selesaikan siklus mesin dari tahap sebelumnya jika masih berjalan
+	if load.CycleID != "" {
+		var previous models.Machine
+		previousID, _ := primitive.ObjectIDFromHex(load.MachineID)
+		if err := config.MachineCollection.FindOne(ctx, bson.M{"_id": previousID}).Decode(&previous); err == nil &&
+			previous.Status == MachineRunning && previous.CurrentCycleID == load.CycleID {
+			if _, code, msg := finishMachineCycle(ctx, previous, r.Header.Get("Username")); code != http.StatusOK {
+				log.Printf("Failed to finish cycle of machine %s: %s", previous.Code, msg)
+			}
+		}
+	}
+
+	set := bson.M{}
+	unset := bson.M{"machine_id": "", "cycle_id": ""}
+	if machine.ID != "" {
+		// Pesanan yang sudah dibatalkan tidak ikut masuk mesin
+		var ids []string
+		for _, transactionID := range load.TransactionIDs {
+			if transaction, err := findTransaction(ctx, transactionID); err == nil && statusIndex(transaction.Status) >= 0 {
+				ids = append(ids, transactionID)
+			}
+		}
+		cycle, code, msg := startMachineCycle(ctx, machine, ids, request.Program, r.Header.Get("Username"))
+		if code != http.StatusOK {
+			config.LoadCollection.UpdateOne(ctx, bson.M{"_id": id, "stage": stage}, bson.M{"$set": bson.M{"stage": load.Stage}})
+			http.Error(w, msg, code)
+			return
+		}
+		set["machine_id"], set["cycle_id"] = machine.ID, cycle.ID
+		unset = bson.M{}
+	} else {
+		for _, transactionID := range load.TransactionIDs {
+			transaction, err := findTransaction(ctx, transactionID)
+			if err != nil || statusIndex(transaction.Status) < 0 || statusIndex(transaction.Status) >= to {
+				continue
+			}
+			if code, msg := changeTransactionStatus(ctx, transaction, stage); code != http.StatusOK {
+				log.Printf("Failed to move transaction %s to %s: %s", transaction.ID, stage, msg)
+			}
+		}
+	}
+
+	if stage == StatusReady {
+		set["status"], set["closed_at"] = LoadClosed, now
+	}
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	if len(update) > 0 {
+		if _, err := config.LoadCollection.UpdateOne(ctx, bson.M{"_id": id}, update); err != nil {
+			log.Printf("Failed to update load %s: %v", load.Code, err)
+		}
+	}
+	if stage == StatusReady {
+		if err := releaseLoad(ctx, load.ID); err != nil {
+			log.Printf("Failed to release orders of load %s: %v", load.Code, err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Load moved to " + stage})
+}
+
+// boardColumn is one stage on the production board
+type boardColumn struct {
+	Stage         string  `json:"stage"`
+	Orders        int     `json:"orders"`
+	Kg            float64 `json:"kg"`
+	ExpressOrders int     `json:"express_orders"`
+	Loads         int     `json:"loads"`
+	LoadKg        float64 `json:"load_kg"`
+	Unbatched     int     `json:"unbatched_orders"` // Pesanan yang belum masuk muatan
+}
+
+// GetProductionBoard summarizes the orders and loads in each production stage, plus machine availability
+func GetProductionBoard(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Pesanan lama tanpa status dihitung sebagai diterima
+	status := bson.M{"$ifNull": bson.A{"$status", StatusReceived}}
+	cursor, err := config.TransactionCollection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"status": bson.M{"$in": bson.A{StatusReceived, StatusWashing, StatusDrying, StatusIroning, StatusReady, "", nil}}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":       status,
+			"orders":    bson.M{"$sum": 1},
+			"kg":        bson.M{"$sum": "$weight_per_kg"},
+			"express":   bson.M{"$sum": bson.M{"$cond": bson.A{"$express", 1, 0}}},
+			"unbatched": bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$ifNull": bson.A{"$load_id", false}}, 0, 1}}},
+		}}},
+	})
+	if err != nil {
+		http.Error(w, "Failed to build production board", http.StatusInternalServerError)
+		return
+	}
+	var orders []struct {
+		Stage     string  `bson:"_id"`
+		Orders    int     `bson:"orders"`
+		Kg        float64 `bson:"kg"`
+		Express   int     `bson:"express"`
+		Unbatched int     `bson:"unbatched"`
+	}
+	if err := cursor.All(ctx, &orders); err != nil {
+		http.Error(w, "Failed to read production board", http.StatusInternalServerError)
+		return
+	}
+
+	cursor, err = config.LoadCollection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"status": LoadOpen}}},
+		{{Key: "$group", Value: bson.M{"_id": "$stage", "loads": bson.M{"$sum": 1}, "kg": bson.M{"$sum": "$total_kg"}}}},
+	})
+	if err != nil {
+		http.Error(w, "Failed to build production board", http.StatusInternalServerError)
+		return
+	}
+	var loads []struct {
+		Stage string  `bson:"_id"`
+		Loads int     `bson:"loads"`
+		Kg    float64 `bson:"kg"`
+	}
+	if err := cursor.All(ctx, &loads); err != nil {
+		http.Error(w, "Failed to read production board", http.StatusInternalServerError)
+		return
+	}
+
+	cursor, err = config.MachineCollection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{"_id": bson.M{"type": "$type", "status": "$status"}, "count": bson.M{"$sum": 1}}}},
+	})
+	if err != nil {
+		http.Error(w, "Failed to build production board", http.StatusInternalServerError)
+		return
+	}
+	var machineCounts []struct {
+		Key struct {
+			Type   string `bson:"type"`
+			Status string `bson:"status"`
+		} `bson:"_id"`
+		Count int `bson:"count"`
+	}
+	if err := cursor.All(ctx, &machineCounts); err != nil {
+		http.Error(w, "Failed to read production board", http.StatusInternalServerError)
+		return
+	}
+
+	// Pesanan dengan instruksi khusus ditampilkan agar operator tidak melewatkannya
+	cursor, err = config.TransactionCollection.Find(ctx,
+		bson.M{
+			"status": bson.M{"$in": openStatuses},
+			"$or":    bson.A{bson.M{"preferences": bson.M{"$ne": nil}}, bson.M{"special_instructions": bson.M{"$nin": bson.A{nil, ""}}}},
+		},
+		options.Find().SetSort(bson.M{"estimated_ready_at": 1}).SetLimit(200),
+	)
+	if err != nil {
+		http.Error(w, "Failed to build production board", http.StatusInternalServerError)
+		return
+	}
+	var flagged []models.Transaction
+	if err := cursor.All(ctx, &flagged); err != nil {
+		http.Error(w, "Failed to read production board", http.StatusInternalServerError)
+		return
+	}
+	type instructionCard struct {
+		TransactionID string   `json:"transaction_id"`
+		InvoiceNumber string   `json:"invoice_number"`
+		Stage         string   `json:"stage"`
+		LoadID        string   `json:"load_id,omitempty"`
+		Instructions  []string `json:"instructions"`
+	}
+	instructions := []instructionCard{}
+	for _, transaction := range flagged {
+		if lines := receipt.Instructions(transaction); len(lines) > 0 {
+			instructions = append(instructions, instructionCard{
+				TransactionID: transaction.ID,
+				InvoiceNumber: transaction.InvoiceNumber,
+				Stage:         transaction.Status,
+				LoadID:        transaction.LoadID,
+				Instructions:  lines,
+			})
+		}
+	}
+
+	columns := make([]boardColumn, len(boardStages))
+	byStage := map[string]*boardColumn{}
+	for i, stage := range boardStages {
+		columns[i].Stage = stage
+		byStage[stage] = &columns[i]
+	}
+	for _, row := range orders {
+		stage := row.Stage
+		if stage == "" {
+			stage = StatusReceived
+		}
+		if column := byStage[stage]; column != nil {
+			column.Orders += row.Orders
+			column.Kg += row.Kg
+			column.ExpressOrders += row.Express
+			column.Unbatched += row.Unbatched
+		}
+	}
+	for _, row := range loads {
+		if column := byStage[row.Stage]; column != nil {
+			column.Loads, column.LoadKg = row.Loads, row.Kg
+		}
+	}
+
+	machines := map[string]map[string]int{MachineWasher: {}, MachineDryer: {}}
+	for _, row := range machineCounts {
+		if machines[row.Key.Type] == nil {
+			machines[row.Key.Type] = map[string]int{}
+		}
+		machines[row.Key.Type][row.Key.Status] = row.Count
+	}
+
+	response := map[string]interface{}{
+		"stages":       columns,
+		"machines":     machines,
+		"instructions": instructions,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,314 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"apkclaundry/config"
+	"apkclaundry/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	TierSilver = "silver"
+	TierGold   = "gold"
+
+	LoyaltyEarn    = "earn"
+	LoyaltyRedeem  = "redeem"
+	LoyaltyReverse = "reverse"
+)
+
+var errNotEnoughPoints = errors.New("customer does not have enough loyalty points")
+
+// tierForSpend returns the membership tier earned by a lifetime spend
+func tierForSpend(settings config.LoyaltySettings, spend float64) string {
+	switch {
+	case spend >= settings.GoldSpend:
+		return TierGold
+	case spend >= settings.SilverSpend:
+		return TierSilver
+	default:
+		return ""
+	}
+}
+
+// tierDiscountPercent returns the member discount of a tier in percent
+func tierDiscountPercent(settings config.LoyaltySettings, tier string) float64 {
+	switch tier {
+	case TierGold:
+		return settings.GoldDiscount
+	case TierSilver:
+		return settings.SilverDiscount
+	default:
+		return 0
+	}
+}
+
+// findCustomer loads a customer by its hex ID
+func findCustomer(ctx context.Context, customerID string) (models.Customer, error) {
+	var customer models.Customer
+	id, err := primitive.ObjectIDFromHex(customerID)
+	if err != nil {
+		return customer, err
+	}
+	err = config.CustomerCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&customer)
+	return customer, err
+}
+
+// addLoyaltyEntry writes one ledger movement
+func addLoyaltyEntry(ctx context.Context, customerID, transactionID, entryType string, points int, description string) {
+	entry := models.LoyaltyEntry{
+		CustomerID:    customerID,
+		TransactionID: transactionID,
+		Type:          entryType,
+		Points:        points,
+		Description:   description,
+		Date:          time.Now(),
+	}
+	if _, err := config.LoyaltyLedgerCollection.InsertOne(ctx, entry); err != nil {
+		log.Printf("Failed to write loyalty ledger for customer %s: %v", customerID, err)
+	}
+}
+
+// applyMemberBenefits adds the tier discount and redeems points on a new transaction linked to a
+// customer. It returns a rollback that gives the points back if the transaction is not saved.
+func applyMemberBenefits(ctx context.Context, transaction *models.Transaction) (func(), error) {
+	noop := func() {}
+	if transaction.CustomerID == "" {
+		if transaction.RedeemPoints > 0 {
+			return noop, errors.New("points can only be redeemed on orders linked to a customer")
+		}
+		return noop, nil
+	}
+
+	customer, err := findCustomer(ctx, transaction.CustomerID)
+	if err != nil {
+		return noop, errors.New("customer not found")
+	}
+
+	settings := config.Loyalty()
+	remaining := transaction.Subtotal - totalDiscount(*transaction)
+
+	if percent := tierDiscountPercent(settings, customer.Tier); percent > 0 && remaining > 0 {
+		discount := math.Min(math.Round(remaining*percent/100), remaining)
+		transaction.Discounts = append(transaction.Discounts, models.TransactionDiscount{
+			Description: "Diskon member " + customer.Tier,
+			Amount:      discount,
+		})
+		remaining -= discount
+	}
+
+	if transaction.RedeemPoints <= 0 || settings.PointValue <= 0 {
+		return noop, nil
+	}
+
+	// Jangan tukar poin melebihi sisa tagihan
+	points := transaction.RedeemPoints
+	if maxPoints := int(remaining / settings.PointValue); points > maxPoints {
+		points = maxPoints
+	}
+	if points <= 0 {
+		return noop, nil
+	}
+
+	customerID, _ := primitive.ObjectIDFromHex(transaction.CustomerID)
+	result, err := config.CustomerCollection.UpdateOne(ctx,
+		bson.M{"_id": customerID, "loyalty_points": bson.M{"$gte": points}},
+		bson.M{"$inc": bson.M{"loyalty_points": -points}},
+	)
+	if err != nil {
+		return noop, err
+	}
+	if result.MatchedCount == 0 {
+		return noop, errNotEnoughPoints
+	}
+
+	transaction.PointsRedeemed = points
+	transaction.Discounts = append(transaction.Discounts, models.TransactionDiscount{
+		Description: "Tukar poin",
+		Amount:      float64(points) * settings.PointValue,
+	})
+
+	return func() {
+		config.CustomerCollection.UpdateOne(ctx, bson.M{"_id": customerID}, bson.M{"$inc": bson.M{"loyalty_points": points}})
+	}, nil
+}
+
+// recordPointsRedeemed writes the ledger entry for points redeemed on a saved transaction
+func recordPointsRedeemed(ctx context.Context, transaction models.Transaction) {
+	if transaction.PointsRedeemed > 0 {
+		addLoyaltyEntry(ctx, transaction.CustomerID, transaction.ID, LoyaltyRedeem, -transaction.PointsRedeemed,
+			"Tukar poin untuk nota "+transaction.InvoiceNumber)
+	}
+}
+
+// awardLoyaltyPoints credits points and lifetime spend for a fully paid transaction and upgrades
+// the customer's tier when a threshold is crossed. The loyalty_awarded flag makes it safe to
+// call more than once, e.g. from both order creation and payment recording.
+func awardLoyaltyPoints(ctx context.Context, transaction models.Transaction) error {
+	if transaction.CustomerID == "" || transaction.TotalPrice <= 0 || transaction.AmountPaid < transaction.TotalPrice {
+		return nil
+	}
+
+	settings := config.Loyalty()
+	points := 0
+	if settings.RupiahPerPoint > 0 {
+		points = int(transaction.TotalPrice / settings.RupiahPerPoint)
+	}
+
+	transactionID, err := primitive.ObjectIDFromHex(transaction.ID)
+	if err != nil {
+		return err
+	}
+	claim, err := config.TransactionCollection.UpdateOne(ctx,
+		bson.M{"_id": transactionID, "loyalty_awarded": bson.M{"$ne": true}},
+		bson.M{"$set": bson.M{"loyalty_awarded": true, "points_earned": points}},
+	)
+	if err != nil || claim.ModifiedCount == 0 {
+		return err
+	}
+
+	customerID, err := primitive.ObjectIDFromHex(transaction.CustomerID)
+	if err != nil {
+		return err
+	}
+	var customer models.Customer
+	err = config.CustomerCollection.FindOneAndUpdate(ctx,
+		bson.M{"_id": customerID},
+		bson.M{"$inc": bson.M{"loyalty_points": points, "lifetime_spend": transaction.TotalPrice}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&customer)
+	if err != nil {
+		return err
+	}
+
+	if points > 0 {
+		addLoyaltyEntry(ctx, transaction.CustomerID, transaction.ID, LoyaltyEarn, points,
+			"Poin dari nota "+transaction.InvoiceNumber)
+	}
+
+	// Tier hanya naik otomatis, tidak pernah turun
+	newTier := tierForSpend(settings, customer.LifetimeSpend)
+	if tierRank(newTier) > tierRank(customer.Tier) {
+		_, err = config.CustomerCollection.UpdateOne(ctx, bson.M{"_id": customerID}, bson.M{"$set": bson.M{"tier": newTier}})
+	}
+	return err
+}
+
+func tierRank(tier string) int {
+	switch tier {
+	case TierGold:
+		return 2
+	case TierSilver:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// reverseLoyaltyPoints undoes the loyalty effects of a transaction that is deleted or refunded:
+// earned points and spend are taken back and redeemed points are returned to the customer
+func reverseLoyaltyPoints(ctx context.Context, transaction models.Transaction) error {
+	if transaction.CustomerID == "" {
+		return nil
+	}
+	customerID, err := primitive.ObjectIDFromHex(transaction.CustomerID)
+	if err != nil {
+		return err
+	}
+
+	points := transaction.PointsRedeemed
+	spend := 0.0
+	if transaction.LoyaltyAwarded {
+		points -= transaction.PointsEarned
+		spend = transaction.TotalPrice
+	}
+	if points == 0 && spend == 0 {
+		return nil
+	}
+
+	// Saldo poin tidak boleh negatif meskipun poin yang didapat sudah terpakai
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"loyalty_points": bson.M{"$max": bson.A{0, bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$loyalty_points", 0}}, points}}}},
+		"lifetime_spend": bson.M{"$max": bson.A{0, bson.M{"$subtract": bson.A{bson.M{"$ifNull": bson.A{"$lifetime_spend", 0}}, spend}}}},
+	}}}}
+	if _, err := config.CustomerCollection.UpdateOne(ctx, bson.M{"_id": customerID}, update); err != nil {
+		return err
+	}
+
+	if points != 0 {
+		addLoyaltyEntry(ctx, transaction.CustomerID, transaction.ID, LoyaltyReverse, points,
+			"Pembatalan poin nota "+transaction.InvoiceNumber)
+	}
+	return nil
+}
+
+// GetCustomerLoyalty returns a customer's points balance, tier and points ledger
+func GetCustomerLoyalty(w http.ResponseWriter, r *http.Request) {
+	customerID := r.URL.Query().Get("id")
+	if customerID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	if !primitive.IsValidObjectID(customerID) {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	customer, err := findCustomer(ctx, customerID)
+	if err != nil {
+		http.Error(w, "Customer not found", http.StatusNotFound)
+		return
+	}
+
+	cursor, err := config.LoyaltyLedgerCollection.Find(ctx, bson.M{"customer_id": customerID},
+		options.Find().SetSort(bson.M{"date": -1}))
+	if err != nil {
+		http.Error(w, "Failed to fetch loyalty ledger", http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	ledger := []models.LoyaltyEntry{}
+	if err := cursor.All(ctx, &ledger); err != nil {
+		http.Error(w, "Failed to read loyalty ledger", http.StatusInternalServerError)
+		return
+	}
+
+	settings := config.Loyalty()
+	nextTier, nextSpend := TierSilver, settings.SilverSpend
+	if customer.Tier == TierSilver {
+		nextTier, nextSpend = TierGold, settings.GoldSpend
+	} else if customer.Tier == TierGold {
+		nextTier, nextSpend = "", 0
+	}
+
+	response := map[string]interface{}{
+		"customer_id":     customer.ID,
+		"name":            customer.Name,
+		"loyalty_points":  customer.LoyaltyPoints,
+		"points_value":    float64(customer.LoyaltyPoints) * settings.PointValue,
+		"tier":            customer.Tier,
+		"tier_discount":   tierDiscountPercent(settings, customer.Tier),
+		"lifetime_spend":  customer.LifetimeSpend,
+		"next_tier":       nextTier,
+		"next_tier_spend": nextSpend,
+		"ledger":          ledger,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
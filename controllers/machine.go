@@ -0,0 +1,743 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"apkclaundry/config"
+	"apkclaundry/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	MachineWasher = "washer"
+	MachineDryer  = "dryer"
+
+	MachineAvailable   = "available"
+	MachineRunning     = "running"
+	MachineMaintenance = "maintenance"
+
+	CycleRunning  = "running"
+	CycleFinished = "finished"
+)
+
+// machineStage is the order status a load moves to when it goes into a machine of each type
+var machineStage = map[string]string{
+	MachineWasher: StatusWashing,
+	MachineDryer:  StatusDrying,
+}
+
+// validateMachine checks the fields of a machine sent by the client
+func validateMachine(machine models.Machine) string {
+	if strings.TrimSpace(machine.Code) == "" {
+		return "Machine code is required"
+	}
+	if machine.Type != MachineWasher && machine.Type != MachineDryer {
+		return "Type must be washer or dryer"
+	}
+	if machine.CapacityKg <= 0 {
+		return "Capacity must be greater than zero"
+	}
+	return ""
+}
+
+// findMachine loads a machine by the ?id= of the request
+func findMachine(ctx context.Context, r *http.Request) (models.Machine, int, string) {
+	var machine models.Machine
+	machineID := r.URL.Query().Get("id")
+	if machineID == "" {
+		return machine, http.StatusBadRequest, "ID not provided"
+	}
+	id, err := primitive.ObjectIDFromHex(machineID)
+	if err != nil {
+		return machine, http.StatusBadRequest, "Invalid ID"
+	}
+	if err := config.MachineCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&machine); err != nil {
+		return machine, http.StatusNotFound, "Machine not found"
+	}
+	return machine, http.StatusOK, ""
+}
+
+// setMachineStatus moves a machine between statuses; the current status is part of the filter so
+// that two operators cannot both start the same machine
+func setMachineStatus(ctx context.Context, machineID, from string, set bson.M) (bool, error) {
+	id, _ := primitive.ObjectIDFromHex(machineID)
+	update := bson.M{"$set": set}
+	if set["status"] != MachineRunning {
+		update["$unset"] = bson.M{"current_cycle_id": ""}
+	}
+	result, err := config.MachineCollection.UpdateOne(ctx, bson.M{"_id": id, "status": from}, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// CreateMachine handles the registration of a new washer or dryer
+func CreateMachine(w http.ResponseWriter, r *http.Request) {
+	var machine models.Machine
+	if err := json.NewDecoder(r.Body).Decode(&machine); err != nil {
+		http.Error(w, `{"error": "Invalid input"}`, http.StatusBadRequest)
+		return
+	}
+
+	machine.Code = strings.ToUpper(strings.TrimSpace(machine.Code))
+	if msg := validateMachine(machine); msg != "" {
+		http.Error(w, `{"error": "`+msg+`"}`, http.StatusBadRequest)
+		return
+	}
+	machine.Status = MachineAvailable
+	machine.CurrentCycleID = ""
+	machine.CreatedAt = time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := config.MachineCollection.InsertOne(ctx, machine)
+	if mongo.IsDuplicateKeyError(err) {
+		http.Error(w, `{"error": "Machine code already exists"}`, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error": "Failed to create machine"}`, http.StatusInternalServerError)
+		return
+	}
+
+	machine.ID = result.InsertedID.(primitive.ObjectID).Hex()
+
+	response := map[string]interface{}{
+		"message": "Machine created successfully",
+		"machine": machine,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetAllMachines retrieves all machines, filtered by ?type= and ?status=
+func GetAllMachines(w http.ResponseWriter, r *http.Request) {
+	filter := bson.M{}
+	if machineType := r.URL.Query().Get("type"); machineType != "" {
+		filter["type"] = machineType
+	}
+	if status := r.URL.Query().Get("status"); status != "" {
+		filter["status"] = status
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := config.MachineCollection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "type", Value: -1}, {Key: "code", Value: 1}}))
+	if err != nil {
+		http.Error(w, "Failed to fetch machines", http.StatusInternalServerError)
+		return
+	}
+	machines := []models.Machine{}
+	if err := cursor.All(ctx, &machines); err != nil {
+		http.Error(w, "Failed to read machine data", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(machines)
+}
+
+// GetMachineByID retrieves a machine with its running cycle, if any
+func GetMachineByID(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	machine, code, msg := findMachine(ctx, r)
+	if code != http.StatusOK {
+		http.Error(w, msg, code)
+		return
+	}
+
+	response := map[string]interface{}{"machine": machine}
+	if machine.CurrentCycleID != "" {
+		var cycle models.MachineCycle
+		cycleID, _ := primitive.ObjectIDFromHex(machine.CurrentCycleID)
+		if err := config.MachineCycleCollection.FindOne(ctx, bson.M{"_id": cycleID}).Decode(&cycle); err == nil {
+			response["cycle"] = cycle
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateMachine updates the code, name and capacity of a machine; its type and status cannot be edited
+func UpdateMachine(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	machine, code, msg := findMachine(ctx, r)
+	if code != http.StatusOK {
+		http.Error(w, msg, code)
+		return
+	}
+
+	var updatedMachine models.Machine
+	if err := json.NewDecoder(r.Body).Decode(&updatedMachine); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	updatedMachine.Code = strings.ToUpper(strings.TrimSpace(updatedMachine.Code))
+	updatedMachine.Type = machine.Type
+	if msg := validateMachine(updatedMachine); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	id, _ := primitive.ObjectIDFromHex(machine.ID)
+	_, err := config.MachineCollection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"code":        updatedMachine.Code,
+		"name":        updatedMachine.Name,
+		"capacity_kg": updatedMachine.CapacityKg,
+	}})
+	if mongo.IsDuplicateKeyError(err) {
+		http.Error(w, "Machine code already exists", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update machine", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Machine updated successfully"})
+}
+
+// DeleteMachine removes a machine that is not running; its cycle history is kept for reports
+func DeleteMachine(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	machine, code, msg := findMachine(ctx, r)
+	if code != http.StatusOK {
+		http.Error(w, msg, code)
+		return
+	}
+
+	id, _ := primitive.ObjectIDFromHex(machine.ID)
+	result, err := config.MachineCollection.DeleteOne(ctx, bson.M{"_id": id, "status": bson.M{"$ne": MachineRunning}})
+	if err != nil {
+		http.Error(w, "Failed to delete machine", http.StatusInternalServerError)
+		return
+	}
+	if result.DeletedCount == 0 {
+		http.Error(w, "Machine is running, finish the cycle first", http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Machine deleted successfully"})
+}
+
+// startMachineCycle puts a load of orders into a machine: it checks that the orders fit, records
+// the cycle, marks the machine running and moves each order to the machine's stage
+func startMachineCycle(ctx context.Context, machine models.Machine, transactionIDs []string, program, startedBy string) (models.MachineCycle, int, string) {
+	var cycle models.MachineCycle
+	if machine.Status != MachineAvailable {
+		return cycle, http.StatusConflict, "Machine is " + machine.Status
+	}
+	if len(transactionIDs) == 0 {
+		return cycle, http.StatusBadRequest, "At least one transaction is required"
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(transactionIDs))
+	for _, transactionID := range transactionIDs {
+		id, err := primitive.ObjectIDFromHex(transactionID)
+		if err != nil {
+			return cycle, http.StatusBadRequest, "Invalid transaction ID " + transactionID
+		}
+		ids = append(ids, id)
+	}
+
+	cursor, err := config.TransactionCollection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return cycle, http.StatusInternalServerError, "Failed to fetch transactions"
+	}
+	var transactions []models.Transaction
+	if err := cursor.All(ctx, &transactions); err != nil {
+		return cycle, http.StatusInternalServerError, "Failed to read transaction data"
+	}
+	if len(transactions) != len(ids) {
+		return cycle, http.StatusNotFound, "Some transactions were not found"
+	}
+
+	cycle = models.MachineCycle{
+		MachineID:      machine.ID,
+		MachineCode:    machine.Code,
+		MachineType:    machine.Type,
+		TransactionIDs: []string{},
+		InvoiceNumbers: []string{},
+		Program:        program,
+		Status:         CycleRunning,
+		StartedBy:      startedBy,
+		StartedAt:      time.Now(),
+	}
+	for _, transaction := range transactions {
+		if i := statusIndex(transaction.Status); i < 0 || i > statusIndex(StatusIroning) {
+			return cycle, http.StatusConflict, "Order " + transaction.InvoiceNumber + " is " + transaction.Status
+		}
+		cycle.TransactionIDs = append(cycle.TransactionIDs, transaction.ID)
+		cycle.InvoiceNumbers = append(cycle.InvoiceNumbers, transaction.InvoiceNumber)
+		cycle.LoadKg += transaction.WeightPerKg
+	}
+	if cycle.LoadKg > machine.CapacityKg {
+		return cycle, http.StatusUnprocessableEntity, "Load exceeds machine capacity"
+	}
+
+	// Satu pesanan tidak boleh berada di dua mesin sekaligus
+	busy, err := config.MachineCycleCollection.CountDocuments(ctx, bson.M{"status": CycleRunning, "transaction_ids": bson.M{"$in": cycle.TransactionIDs}})
+	if err != nil {
+		return cycle, http.StatusInternalServerError, "Failed to check running cycles"
+	}
+	if busy > 0 {
+		return cycle, http.StatusConflict, "Some orders are already in a running machine"
+	}
+
+	result, err := config.MachineCycleCollection.InsertOne(ctx, cycle)
+	if err != nil {
+		return cycle, http.StatusInternalServerError, "Failed to start cycle"
+	}
+	cycle.ID = result.InsertedID.(primitive.ObjectID).Hex()
+
+	started, err := setMachineStatus(ctx, machine.ID, MachineAvailable, bson.M{"status": MachineRunning, "current_cycle_id": cycle.ID})
+	if err != nil || !started {
+		config.MachineCycleCollection.DeleteOne(ctx, bson.M{"_id": result.InsertedID})
+		if err != nil {
+			return cycle, http.StatusInternalServerError, "Failed to start machine"
+		}
+		return cycle, http.StatusConflict, "Machine was started by someone else"
+	}
+
+	// Pesanan yang sudah melewati tahap mesin ini (mis. dicuci ulang) tidak dimundurkan
+	stage := machineStage[machine.Type]
+	for _, transaction := range transactions {
+		if statusIndex(transaction.Status) < statusIndex(stage) {
+			if code, msg := changeTransactionStatus(ctx, transaction, stage); code != http.StatusOK {
+				log.Printf("Failed to move transaction %s to %s: %s", transaction.ID, stage, msg)
+			}
+		}
+	}
+	return cycle, http.StatusOK, ""
+}
+
+// StartMachineCycle starts a machine with a load of orders:
+// {"transaction_ids": ["...", "..."], "program": "cold 30°C"}
+func StartMachineCycle(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		TransactionIDs []string `json:"transaction_ids"`
+		Program        string   `json:"program"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	machine, code, msg := findMachine(ctx, r)
+	if code != http.StatusOK {
+		http.Error(w, msg, code)
+		return
+	}
+
+	cycle, code, msg := startMachineCycle(ctx, machine, request.TransactionIDs, request.Program, r.Header.Get("Username"))
+	if code != http.StatusOK {
+		http.Error(w, msg, code)
+		return
+	}
+
+	response := map[string]interface{}{
+		"message": "Cycle started successfully",
+		"cycle":   cycle,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// finishMachineCycle ends the running cycle of a machine and makes the machine available again
+func finishMachineCycle(ctx context.Context, machine models.Machine, finishedBy string) (models.MachineCycle, int, string) {
+	var cycle models.MachineCycle
+	if machine.Status != MachineRunning {
+		return cycle, http.StatusConflict, "Machine is not running"
+	}
+
+	finished, err := setMachineStatus(ctx, machine.ID, MachineRunning, bson.M{"status": MachineAvailable})
+	if err != nil {
+		return cycle, http.StatusInternalServerError, "Failed to update machine"
+	}
+	if !finished {
+		return cycle, http.StatusConflict, "Machine was changed by someone else, please retry"
+	}
+
+	now := time.Now()
+	cycleID, _ := primitive.ObjectIDFromHex(machine.CurrentCycleID)
+	err = config.MachineCycleCollection.FindOneAndUpdate(ctx,
+		bson.M{"_id": cycleID, "status": CycleRunning},
+		bson.M{"$set": bson.M{"status": CycleFinished, "finished_by": finishedBy, "finished_at": now}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&cycle)
+	if err != nil {
+		log.Printf("Failed to finish cycle %s of machine %s: %v", machine.CurrentCycleID, machine.Code, err)
+	}
+	return cycle, http.StatusOK, ""
+}
+
+// FinishMachineCycle ends the running cycle of a machine
+func FinishMachineCycle(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	machine, code, msg := findMachine(ctx, r)
+	if code != http.StatusOK {
+		http.Error(w, msg, code)
+		return
+	}
+
+	cycle, code, msg := finishMachineCycle(ctx, machine, r.Header.Get("Username"))
+	if code != http.StatusOK {
+		http.Error(w, msg, code)
+		return
+	}
+
+	response := map[string]interface{}{
+		"message": "Cycle finished successfully",
+		"cycle":   cycle,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetMachineCycles lists cycles newest first, filtered by ?machine_id=, ?transaction_id= and ?from=&to=
+func GetMachineCycles(w http.ResponseWriter, r *http.Request) {
+	filter := bson.M{}
+	if machineID := r.URL.Query().Get("machine_id"); machineID != "" {
+		filter["machine_id"] = machineID
+	}
+	if transactionID := r.URL.Query().Get("transaction_id"); transactionID != "" {
+		filter["transaction_ids"] = transactionID
+	}
+	dateFilter, err := dateRangeFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if dateFilter != nil {
+		filter["started_at"] = dateFilter
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := config.MachineCycleCollection.Find(ctx, filter, options.Find().SetSort(bson.M{"started_at": -1}).SetLimit(500))
+	if err != nil {
+		http.Error(w, "Failed to fetch cycles", http.StatusInternalServerError)
+		return
+	}
+	cycles := []models.MachineCycle{}
+	if err := cursor.All(ctx, &cycles); err != nil {
+		http.Error(w, "Failed to read cycle data", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cycles)
+}
+
+// StartMaintenance takes an idle machine out of service: {"reason": "..."}
+func StartMaintenance(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || strings.TrimSpace(request.Reason) == "" {
+		http.Error(w, "Reason is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	machine, code, msg := findMachine(ctx, r)
+	if code != http.StatusOK {
+		http.Error(w, msg, code)
+		return
+	}
+
+	changed, err := setMachineStatus(ctx, machine.ID, MachineAvailable, bson.M{"status": MachineMaintenance})
+	if err != nil {
+		http.Error(w, "Failed to update machine", http.StatusInternalServerError)
+		return
+	}
+	if !changed {
+		http.Error(w, "Machine is "+machine.Status+", only an available machine can go into maintenance", http.StatusConflict)
+		return
+	}
+
+	entry := models.MaintenanceLog{
+		MachineID: machine.ID,
+		Reason:    request.Reason,
+		StartedBy: r.Header.Get("Username"),
+		StartedAt: time.Now(),
+	}
+	result, err := config.MaintenanceLogCollection.InsertOne(ctx, entry)
+	if err != nil {
+		setMachineStatus(ctx, machine.ID, MachineMaintenance, bson.M{"status": MachineAvailable})
+		http.Error(w, "Failed to save maintenance log", http.StatusInternalServerError)
+		return
+	}
+	entry.ID = result.InsertedID.(primitive.ObjectID).Hex()
+
+	response := map[string]interface{}{
+		"message":     "Machine taken out of service",
+		"maintenance": entry,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// EndMaintenance returns a machine to service and closes its maintenance log: {"notes": "...", "cost": 150000}
+func EndMaintenance(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Notes string  `json:"notes"`
+		Cost  float64 `json:"cost"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Cost < 0 {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	machine, code, msg := findMachine(ctx, r)
+	if code != http.StatusOK {
+		http.Error(w, msg, code)
+		return
+	}
+
+	changed, err := setMachineStatus(ctx, machine.ID, MachineMaintenance, bson.M{"status": MachineAvailable})
+	if err != nil {
+		http.Error(w, "Failed to update machine", http.StatusInternalServerError)
+		return
+	}
+	if !changed {
+		http.Error(w, "Machine is not in maintenance", http.StatusConflict)
+		return
+	}
+
+	_, err = config.MaintenanceLogCollection.UpdateMany(ctx,
+		bson.M{"machine_id": machine.ID, "ended_at": nil},
+		bson.M{"$set": bson.M{"notes": request.Notes, "cost": request.Cost, "ended_by": r.Header.Get("Username"), "ended_at": time.Now()}},
+	)
+	if err != nil {
+		log.Printf("Failed to close maintenance log of machine %s: %v", machine.Code, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Machine returned to service"})
+}
+
+// GetMaintenanceLogs lists the maintenance history of a machine, newest first
+func GetMaintenanceLogs(w http.ResponseWriter, r *http.Request) {
+	machineID := r.URL.Query().Get("id")
+	if machineID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := config.MaintenanceLogCollection.Find(ctx, bson.M{"machine_id": machineID}, options.Find().SetSort(bson.M{"started_at": -1}))
+	if err != nil {
+		http.Error(w, "Failed to fetch maintenance logs", http.StatusInternalServerError)
+		return
+	}
+	logs := []models.MaintenanceLog{}
+	if err := cursor.All(ctx, &logs); err != nil {
+		http.Error(w, "Failed to read maintenance logs", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(logs)
+}
+
+// machineUtilization is one row of the utilization report
+type machineUtilization struct {
+	MachineID          string  `json:"machine_id"`
+	Code               string  `json:"code"`
+	Type               string  `json:"type"`
+	CapacityKg         float64 `json:"capacity_kg"`
+	Cycles             int     `json:"cycles"`
+	LoadKg             float64 `json:"load_kg"`
+	AverageFillPercent float64 `json:"average_fill_percent"`
+	RunningMinutes     float64 `json:"running_minutes"`
+	MaintenanceMinutes float64 `json:"maintenance_minutes"`
+	UtilizationPercent float64 `json:"utilization_percent"` // Waktu berjalan dibanding jam operasional
+}
+
+// overlapMinutes returns how many minutes of [from, to) fall inside [start, end)
+func overlapMinutes(from, to, start, end time.Time) float64 {
+	if from.Before(start) {
+		from = start
+	}
+	if to.After(end) {
+		to = end
+	}
+	if !to.After(from) {
+		return 0
+	}
+	return to.Sub(from).Minutes()
+}
+
+// GetMachineUtilization reports per machine the cycles run, kilograms washed, running and
+// maintenance time and utilization against operating hours for ?from=&to= (default the last 7 days)
+func GetMachineUtilization(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	today, _ := todayRange(now)
+	start, end := today.AddDate(0, 0, -6), today.AddDate(0, 0, 1)
+	dateFilter, err := dateRangeFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if from, ok := dateFilter["$gte"].(time.Time); ok {
+		start = from
+	}
+	if to, ok := dateFilter["$lt"].(time.Time); ok {
+		end = to
+	}
+	if !end.After(start) {
+		http.Error(w, "to must not be before from", http.StatusBadRequest)
+		return
+	}
+	// Siklus dan perawatan yang masih berjalan dihitung sampai saat ini
+	until := end
+	if now.Before(until) {
+		until = now
+	}
+
+	operating := config.Operating()
+	availableMinutes := 0.0
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		if !operating.ClosedDays[int(day.Weekday())] {
+			availableMinutes += float64(operating.CloseMinute - operating.OpenMinute)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	cursor, err := config.MachineCollection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "type", Value: -1}, {Key: "code", Value: 1}}))
+	if err != nil {
+		http.Error(w, "Failed to fetch machines", http.StatusInternalServerError)
+		return
+	}
+	var machines []models.Machine
+	if err := cursor.All(ctx, &machines); err != nil {
+		http.Error(w, "Failed to read machine data", http.StatusInternalServerError)
+		return
+	}
+
+	rows := make([]machineUtilization, len(machines))
+	byID := map[string]*machineUtilization{}
+	for i, machine := range machines {
+		rows[i] = machineUtilization{MachineID: machine.ID, Code: machine.Code, Type: machine.Type, CapacityKg: machine.CapacityKg}
+		byID[machine.ID] = &rows[i]
+	}
+
+	overlapping := bson.M{"started_at": bson.M{"$lt": end}, "$or": bson.A{bson.M{"finished_at": nil}, bson.M{"finished_at": bson.M{"$gt": start}}}}
+	cursor, err = config.MachineCycleCollection.Find(ctx, overlapping)
+	if err != nil {
+		http.Error(w, "Failed to fetch cycles", http.StatusInternalServerError)
+		return
+	}
+	var cycles []models.MachineCycle
+	if err := cursor.All(ctx, &cycles); err != nil {
+		http.Error(w, "Failed to read cycle data", http.StatusInternalServerError)
+		return
+	}
+	for _, cycle := range cycles {
+		row := byID[cycle.MachineID]
+		if row == nil {
+			continue
+		}
+		finishedAt := until
+		if cycle.FinishedAt != nil {
+			finishedAt = *cycle.FinishedAt
+		}
+		row.RunningMinutes += overlapMinutes(cycle.StartedAt, finishedAt, start, until)
+		if !cycle.StartedAt.Before(start) {
+			row.Cycles++
+			row.LoadKg += cycle.LoadKg
+		}
+	}
+
+	overlapping = bson.M{"started_at": bson.M{"$lt": end}, "$or": bson.A{bson.M{"ended_at": nil}, bson.M{"ended_at": bson.M{"$gt": start}}}}
+	cursor, err = config.MaintenanceLogCollection.Find(ctx, overlapping)
+	if err != nil {
+		http.Error(w, "Failed to fetch maintenance logs", http.StatusInternalServerError)
+		return
+	}
+	var logs []models.MaintenanceLog
+	if err := cursor.All(ctx, &logs); err != nil {
+		http.Error(w, "Failed to read maintenance logs", http.StatusInternalServerError)
+		return
+	}
+	for _, entry := range logs {
+		row := byID[entry.MachineID]
+		if row == nil {
+			continue
+		}
+		endedAt := until
+		if entry.EndedAt != nil {
+			endedAt = *entry.EndedAt
+		}
+		row.MaintenanceMinutes += overlapMinutes(entry.StartedAt, endedAt, start, until)
+	}
+
+	for i := range rows {
+		row := &rows[i]
+		if row.Cycles > 0 && row.CapacityKg > 0 {
+			row.AverageFillPercent = roundPercent(row.LoadKg / (float64(row.Cycles) * row.CapacityKg))
+		}
+		if availableMinutes > 0 {
+			row.UtilizationPercent = roundPercent(row.RunningMinutes / availableMinutes)
+		}
+		row.RunningMinutes = float64(int(row.RunningMinutes + 0.5))
+		row.MaintenanceMinutes = float64(int(row.MaintenanceMinutes + 0.5))
+	}
+
+	response := map[string]interface{}{
+		"from":              start,
+		"to":                end,
+		"available_minutes": availableMinutes,
+		"machines":          rows,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// roundPercent converts a ratio to a percentage with one decimal
+func roundPercent(ratio float64) float64 {
+	return math.Round(ratio*1000) / 10
+}
@@ -0,0 +1,472 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"text/template"
+	"time"
+
+	"apkclaundry/config"
+	"apkclaundry/models"
+	"apkclaundry/notify"
+	"apkclaundry/receipt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Peristiwa pesanan yang memicu notifikasi ke pelanggan
+const (
+	EventReceived  = "received"
+	EventReady     = "ready"
+	EventDelivered = "delivered"
+	EventUnclaimed = "unclaimed"
+)
+
+// notificationEvents lists every event in the order shown to admins
+var notificationEvents = []string{EventReceived, EventReady, EventDelivered, EventUnclaimed}
+
+const (
+	NotificationPending = "pending"
+	NotificationSending = "sending"
+	NotificationSent    = "sent"
+	NotificationFailed  = "failed"
+)
+
+// notificationLease is how long a claimed outbox entry stays locked; an entry still "sending"
+// after that is assumed to belong to a crashed process and is picked up again
+const notificationLease = 2 * time.Minute
+
+// defaultTemplates are used for events without a template stored in the database
+var defaultTemplates = map[string]models.NotificationTemplate{
+	EventReceived: {
+		Event:   EventReceived,
+		Subject: "Pesanan {{.InvoiceNumber}} diterima",
+		Body: "Halo {{.CustomerName}}, cucian Anda dengan nota {{.InvoiceNumber}}{{if .ServiceType}} ({{.ServiceType}}){{end}} sudah kami terima." +
+			"{{if .EstimatedReadyAt}} Perkiraan selesai: {{.EstimatedReadyAt}}.{{end}} Total: {{.Total}}." +
+			"{{if .TrackingURL}}\nLacak pesanan: {{.TrackingURL}}{{end}}\n\n{{.BusinessName}}",
+	},
+	EventReady: {
+		Event:   EventReady,
+		Subject: "Pesanan {{.InvoiceNumber}} siap diambil",
+		Body: "Halo {{.CustomerName}}, cucian Anda dengan nota {{.InvoiceNumber}} sudah selesai dan siap diambil." +
+			"{{if .BalanceDue}} Sisa tagihan: {{.BalanceDue}}.{{end}}\n\n{{.BusinessName}} {{.BusinessPhone}}",
+	},
+	EventDelivered: {
+		Event:   EventDelivered,
+		Subject: "Pesanan {{.InvoiceNumber}} sudah diantar",
+		Body:    "Halo {{.CustomerName}}, cucian Anda dengan nota {{.InvoiceNumber}} sudah diantar. Terima kasih telah menggunakan {{.BusinessName}}.",
+	},
+	EventUnclaimed: {
+		Event:   EventUnclaimed,
+		Subject: "Pengingat: pesanan {{.InvoiceNumber}} belum diambil",
+		Body: "Halo {{.CustomerName}}, cucian Anda dengan nota {{.InvoiceNumber}} sudah siap sejak {{.DaysWaiting}} hari lalu dan belum diambil." +
+			"{{if .StorageFee}} Biaya penyimpanan saat ini: {{.StorageFee}}.{{end}}" +
+			"{{if .FinalReminder}} Ini pengingat terakhir: jika belum diambil sampai {{.DisposalDate}}, cucian akan kami donasikan.{{end}}" +
+			"\n\n{{.BusinessName}} {{.BusinessPhone}}",
+	},
+}
+
+// notificationData is the data available to message templates
+type notificationData struct {
+	CustomerName     string
+	InvoiceNumber    string
+	ServiceType      string
+	Total            string
+	BalanceDue       string // Kosong jika sudah lunas
+	EstimatedReadyAt string
+	TrackingURL      string
+	BusinessName     string
+	BusinessPhone    string
+	DaysWaiting      int    // Hari sejak pesanan siap
+	StorageFee       string // Kosong jika tidak ada biaya simpan
+	DisposalDate     string
+	FinalReminder    bool
+}
+
+func newNotificationData(transaction models.Transaction) notificationData {
+	business := config.Business()
+	data := notificationData{
+		CustomerName:  transaction.CustomerName,
+		InvoiceNumber: transaction.InvoiceNumber,
+		ServiceType:   transaction.ServiceType,
+		Total:         receipt.FormatRupiah(transaction.TotalPrice),
+		TrackingURL:   trackingURL(transaction),
+		BusinessName:  business.Name,
+		BusinessPhone: business.Phone,
+	}
+	if due := math.Max(0, transaction.TotalPrice-transaction.AmountPaid); due > 0 {
+		data.BalanceDue = receipt.FormatRupiah(due)
+	}
+	if transaction.EstimatedReadyAt != nil {
+		data.EstimatedReadyAt = transaction.EstimatedReadyAt.In(config.Location()).Format("02/01/2006 15:04")
+	}
+	if data.InvoiceNumber == "" {
+		data.InvoiceNumber = transaction.ID
+	}
+	if transaction.ReadyAt != nil {
+		policy := config.Unclaimed()
+		data.DaysWaiting = daysSince(*transaction.ReadyAt, time.Now())
+		data.DisposalDate = transaction.ReadyAt.AddDate(0, 0, policy.DisposeAfterDays).In(config.Location()).Format("02/01/2006")
+		if n := len(policy.ReminderDays); n > 0 {
+			data.FinalReminder = data.DaysWaiting >= policy.ReminderDays[n-1]
+		}
+	}
+	if transaction.StorageFee > 0 {
+		data.StorageFee = receipt.FormatRupiah(transaction.StorageFee)
+	}
+	return data
+}
+
+// renderTemplate executes a subject and body template against the data
+func renderTemplate(tmpl models.NotificationTemplate, data notificationData) (string, string, error) {
+	var rendered [2]string
+	for i, text := range []string{tmpl.Subject, tmpl.Body} {
+		parsed, err := template.New(tmpl.Event).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return "", "", err
+		}
+		var buf bytes.Buffer
+		if err := parsed.Execute(&buf, data); err != nil {
+			return "", "", err
+		}
+		rendered[i] = buf.String()
+	}
+	return rendered[0], rendered[1], nil
+}
+
+// loadTemplate returns the stored template of an event, falling back to the default
+func loadTemplate(ctx context.Context, event string) models.NotificationTemplate {
+	var tmpl models.NotificationTemplate
+	if err := config.NotificationTemplateCollection.FindOne(ctx, bson.M{"event": event}).Decode(&tmpl); err != nil {
+		return defaultTemplates[event]
+	}
+	return tmpl
+}
+
+// notificationRecipient picks the address for a channel: the customer's email for email,
+// otherwise the phone number on the transaction (or on the customer when the transaction has none)
+func notificationRecipient(ctx context.Context, transaction models.Transaction, channel string) string {
+	if channel != notify.ChannelEmail && transaction.PhoneNumber != "" {
+		return transaction.PhoneNumber
+	}
+	if transaction.CustomerID == "" {
+		return ""
+	}
+	customer, err := findCustomer(ctx, transaction.CustomerID)
+	if err != nil {
+		return ""
+	}
+	if channel == notify.ChannelEmail {
+		return customer.Email
+	}
+	return customer.Phone
+}
+
+// notificationBackoff returns the wait before the next attempt: 1, 2, 4, ... minutes, at most 6 hours
+func notificationBackoff(attempts int) time.Duration {
+	wait := time.Minute << uint(attempts-1)
+	if attempts > 10 || wait > 6*time.Hour {
+		return 6 * time.Hour
+	}
+	return wait
+}
+
+// notifyTransaction writes a notification for an order event to the outbox. Sending is left to
+// the dispatcher cron so the request that triggered the event never waits on the provider.
+func notifyTransaction(transaction models.Transaction, event string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	notifier, err := notify.FromSettings()
+	if err != nil {
+		log.Printf("Notifications disabled: %v", err)
+		return
+	}
+
+	recipient := notificationRecipient(ctx, transaction, notifier.Channel())
+	if recipient == "" {
+		log.Printf("No %s recipient for transaction %s, skipping %s notification", notifier.Channel(), transaction.ID, event)
+		return
+	}
+
+	subject, body, err := renderTemplate(loadTemplate(ctx, event), newNotificationData(transaction))
+	if err != nil {
+		log.Printf("Failed to render %s notification for transaction %s: %v", event, transaction.ID, err)
+		return
+	}
+
+	now := time.Now()
+	notification := models.Notification{
+		TransactionID: transaction.ID,
+		InvoiceNumber: transaction.InvoiceNumber,
+		Event:         event,
+		Channel:       notifier.Channel(),
+		Recipient:     recipient,
+		Subject:       subject,
+		Body:          body,
+		Status:        NotificationPending,
+		NextAttemptAt: now,
+		History:       []models.NotificationAttempt{},
+		CreatedAt:     now,
+	}
+	if _, err := config.NotificationCollection.InsertOne(ctx, notification); err != nil {
+		log.Printf("Failed to queue %s notification for transaction %s: %v", event, transaction.ID, err)
+	}
+}
+
+// attemptNotification sends a claimed outbox entry once and records the outcome. Failed entries
+// are rescheduled with exponential backoff until NOTIFY_MAX_ATTEMPTS (default 5) is reached.
+func attemptNotification(ctx context.Context, notifier notify.Notifier, notification models.Notification) bool {
+	var sendErr error
+	if notification.Channel != notifier.Channel() {
+		sendErr = fmt.Errorf("queued for %s but the active provider is %s", notification.Channel, notifier.Channel())
+	} else {
+		sendErr = notifier.Send(ctx, notify.Message{
+			To:      notification.Recipient,
+			Subject: notification.Subject,
+			Body:    notification.Body,
+		})
+	}
+
+	now := time.Now()
+	attempts := notification.Attempts + 1
+	attempt := models.NotificationAttempt{At: now}
+	set := bson.M{"attempts": attempts}
+
+	if sendErr == nil {
+		set["status"] = NotificationSent
+		set["sent_at"] = now
+		set["last_error"] = ""
+	} else {
+		attempt.Error = sendErr.Error()
+		set["last_error"] = sendErr.Error()
+		if attempts >= config.GetEnvInt("NOTIFY_MAX_ATTEMPTS", 5) {
+			set["status"] = NotificationFailed
+		} else {
+			set["status"] = NotificationPending
+			set["next_attempt_at"] = now.Add(notificationBackoff(attempts))
+		}
+	}
+
+	id, _ := primitive.ObjectIDFromHex(notification.ID)
+	_, err := config.NotificationCollection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set":   set,
+		"$unset": bson.M{"locked_until": ""},
+		"$push":  bson.M{"history": attempt},
+	})
+	if err != nil {
+		log.Printf("Failed to record notification attempt %s: %v", notification.ID, err)
+	}
+	return sendErr == nil
+}
+
+// claimNotification locks one due outbox entry matching the filter so only one dispatcher sends it
+func claimNotification(ctx context.Context, filter bson.M, now time.Time) (models.Notification, error) {
+	due := bson.M{"$or": bson.A{
+		bson.M{"status": NotificationPending, "next_attempt_at": bson.M{"$lte": now}},
+		bson.M{"status": NotificationSending, "locked_until": bson.M{"$lt": now}},
+	}}
+
+	var notification models.Notification
+	err := config.NotificationCollection.FindOneAndUpdate(ctx,
+		bson.M{"$and": bson.A{filter, due}},
+		bson.M{"$set": bson.M{"status": NotificationSending, "locked_until": now.Add(notificationLease)}},
+		options.FindOneAndUpdate().SetSort(bson.M{"next_attempt_at": 1}).SetReturnDocument(options.After),
+	).Decode(&notification)
+	return notification, err
+}
+
+// DispatchNotifications sends the outbox entries that are due. It is meant to be called
+// periodically by a cron job; each call handles at most NOTIFY_BATCH_SIZE (default 50) entries.
+func DispatchNotifications(w http.ResponseWriter, r *http.Request) {
+	notifier, err := notify.FromSettings()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Second)
+	defer cancel()
+
+	sent, failed := 0, 0
+	for i := 0; i < config.GetEnvInt("NOTIFY_BATCH_SIZE", 50) && ctx.Err() == nil; i++ {
+		notification, err := claimNotification(ctx, bson.M{}, time.Now())
+		if err == mongo.ErrNoDocuments {
+			break
+		}
+		if err != nil {
+			http.Error(w, "Failed to read notification outbox", http.StatusInternalServerError)
+			return
+		}
+
+		if attemptNotification(ctx, notifier, notification) {
+			sent++
+		} else {
+			failed++
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"sent": sent, "failed": failed})
+}
+
+// RetryNotification puts a failed notification back in the queue and tries to send it immediately
+func RetryNotification(w http.ResponseWriter, r *http.Request) {
+	notificationID := r.URL.Query().Get("id")
+	if notificationID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(notificationID)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	notifier, err := notify.FromSettings()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	result, err := config.NotificationCollection.UpdateOne(ctx,
+		bson.M{"_id": id, "status": NotificationFailed},
+		bson.M{"$set": bson.M{"status": NotificationPending, "attempts": 0, "next_attempt_at": now}},
+	)
+	if err != nil {
+		http.Error(w, "Failed to update notification", http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, "Failed notification not found", http.StatusNotFound)
+		return
+	}
+
+	notification, err := claimNotification(ctx, bson.M{"_id": id}, now)
+	if err != nil {
+		http.Error(w, "Notification is already being sent", http.StatusConflict)
+		return
+	}
+	sent := attemptNotification(ctx, notifier, notification)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Notification retried", "sent": sent})
+}
+
+// GetTransactionNotifications returns the delivery log of a transaction's notifications
+func GetTransactionNotifications(w http.ResponseWriter, r *http.Request) {
+	transactionID := r.URL.Query().Get("id")
+	if transactionID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := config.NotificationCollection.Find(ctx, bson.M{"transaction_id": transactionID},
+		options.Find().SetSort(bson.M{"created_at": 1}))
+	if err != nil {
+		http.Error(w, "Failed to fetch notifications", http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	notifications := []models.Notification{}
+	if err := cursor.All(ctx, &notifications); err != nil {
+		http.Error(w, "Failed to read notification data", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(notifications)
+}
+
+// GetNotificationTemplates returns the effective template of every event
+func GetNotificationTemplates(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	templates := []models.NotificationTemplate{}
+	for _, event := range notificationEvents {
+		templates = append(templates, loadTemplate(ctx, event))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(templates)
+}
+
+// UpdateNotificationTemplate stores a custom template for an event (?event=received|ready|delivered|unclaimed)
+func UpdateNotificationTemplate(w http.ResponseWriter, r *http.Request) {
+	event := r.URL.Query().Get("event")
+	if _, ok := defaultTemplates[event]; !ok {
+		http.Error(w, "Invalid event", http.StatusBadRequest)
+		return
+	}
+
+	var tmpl models.NotificationTemplate
+	if err := json.NewDecoder(r.Body).Decode(&tmpl); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	tmpl.Event = event
+	if tmpl.Body == "" {
+		http.Error(w, "Body is required", http.StatusBadRequest)
+		return
+	}
+
+	// Uji template dengan data contoh agar kesalahan ketik ketahuan sekarang, bukan saat mengirim
+	sample := notificationData{CustomerName: "Budi", InvoiceNumber: "LDY-20240101-0001", ServiceType: "Cuci Kering",
+		Total: "Rp 35.000", BalanceDue: "Rp 10.000", EstimatedReadyAt: "02/01/2024 17:00", BusinessName: config.Business().Name,
+		DaysWaiting: 14, StorageFee: "Rp 6.000", DisposalDate: "01/03/2024", FinalReminder: true}
+	if _, _, err := renderTemplate(tmpl, sample); err != nil {
+		http.Error(w, "Invalid template: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := config.NotificationTemplateCollection.UpdateOne(ctx,
+		bson.M{"event": event},
+		bson.M{"$set": bson.M{"subject": tmpl.Subject, "body": tmpl.Body, "updated_at": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		http.Error(w, "Failed to update template", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Template updated successfully"})
+}
+
+// ResetNotificationTemplate removes the custom template of an event so the default is used again
+func ResetNotificationTemplate(w http.ResponseWriter, r *http.Request) {
+	event := r.URL.Query().Get("event")
+	if _, ok := defaultTemplates[event]; !ok {
+		http.Error(w, "Invalid event", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := config.NotificationTemplateCollection.DeleteOne(context.TODO(), bson.M{"event": event}); err != nil {
+		http.Error(w, "Failed to reset template", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Template reset to default"})
+}
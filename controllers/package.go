@@ -0,0 +1,547 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"apkclaundry/config"
+	"apkclaundry/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	PackageKilo    = "kilo"
+	PackageDeposit = "deposit"
+
+	WalletPurchase = "purchase"
+	WalletDebit    = "debit"
+	WalletRefund   = "refund"
+)
+
+var errNoPackageBalance = errors.New("customer has no active package with enough balance")
+
+// activePackageFilter matches a customer's packages of a type that have not expired
+func activePackageFilter(customerID, packageType string, now time.Time) bson.M {
+	return bson.M{
+		"customer_id": customerID,
+		"type":        packageType,
+		"$or": bson.A{
+			bson.M{"expires_at": bson.M{"$exists": false}},
+			bson.M{"expires_at": nil},
+			bson.M{"expires_at": bson.M{"$gt": now}},
+		},
+	}
+}
+
+// findActivePackages returns a customer's usable packages, soonest-expiring first
+// so that balances about to lapse are used before those that never expire
+func findActivePackages(ctx context.Context, filter bson.M) ([]models.CustomerPackage, error) {
+	cursor, err := config.CustomerPackageCollection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var packages []models.CustomerPackage
+	if err := cursor.All(ctx, &packages); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(packages, func(i, j int) bool {
+		a, b := packages[i].ExpiresAt, packages[j].ExpiresAt
+		if a == nil || b == nil {
+			return b == nil && a != nil
+		}
+		return a.Before(*b)
+	})
+	return packages, nil
+}
+
+// addWalletEntry writes one movement to the customer's balance statement
+func addWalletEntry(ctx context.Context, entry models.WalletEntry) {
+	entry.Date = time.Now()
+	if _, err := config.WalletLedgerCollection.InsertOne(ctx, entry); err != nil {
+		log.Printf("Failed to write wallet ledger for customer %s: %v", entry.CustomerID, err)
+	}
+}
+
+// debitCustomerPackage pays a new transaction from the customer's prepaid balance. A kilo
+// package covers the whole order when it has enough kilograms left; a deposit covers as much of
+// the amount due as its credit allows. The debit is recorded as payment on the transaction and
+// the returned rollback restores the balance if the transaction is not saved.
+func debitCustomerPackage(ctx context.Context, transaction *models.Transaction, now time.Time) (func(), error) {
+	noop := func() {}
+	if transaction.UsePackage == "" {
+		return noop, nil
+	}
+	if transaction.CustomerID == "" {
+		return noop, errors.New("packages can only be used on orders linked to a customer")
+	}
+
+	due := transaction.TotalPrice - transaction.AmountPaid
+	if due <= 0 {
+		return noop, nil
+	}
+
+	var field string
+	var minimum float64
+	switch transaction.UsePackage {
+	case PackageKilo:
+		if transaction.WeightPerKg <= 0 {
+			return noop, errors.New("weight is required to use a kilo package")
+		}
+		field, minimum = "kilograms_remaining", transaction.WeightPerKg
+	case PackageDeposit:
+		field, minimum = "credit_remaining", 0.01
+	default:
+		return noop, errors.New("use_package must be kilo or deposit")
+	}
+
+	filter := activePackageFilter(transaction.CustomerID, transaction.UsePackage, now)
+	filter[field] = bson.M{"$gte": minimum}
+	candidates, err := findActivePackages(ctx, filter)
+	if err != nil {
+		return noop, err
+	}
+
+	for _, candidate := range candidates {
+		debit := models.PackageDebit{
+			CustomerPackageID: candidate.ID,
+			PackageName:       candidate.PackageName,
+		}
+		amount := minimum
+		if transaction.UsePackage == PackageKilo {
+			debit.Kilograms = amount
+			debit.Amount = due
+		} else {
+			amount = math.Min(candidate.CreditRemaining, due)
+			debit.Credit = amount
+			debit.Amount = amount
+		}
+
+		// Syarat saldo dicek ulang saat update agar dua pesanan bersamaan tidak memakai saldo yang sama
+		packageID, _ := primitive.ObjectIDFromHex(candidate.ID)
+		result, err := config.CustomerPackageCollection.UpdateOne(ctx,
+			bson.M{"_id": packageID, field: bson.M{"$gte": amount}},
+			bson.M{"$inc": bson.M{field: -amount}},
+		)
+		if err != nil {
+			return noop, err
+		}
+		if result.ModifiedCount == 0 {
+			continue
+		}
+
+		transaction.PackageDebits = append(transaction.PackageDebits, debit)
+		transaction.AmountPaid += debit.Amount
+		if transaction.PaymentMethod == "" {
+			transaction.PaymentMethod = "paket"
+		}
+
+		return func() {
+			config.CustomerPackageCollection.UpdateOne(ctx, bson.M{"_id": packageID}, bson.M{"$inc": bson.M{field: amount}})
+		}, nil
+	}
+
+	return noop, errNoPackageBalance
+}
+
+// recordPackageDebits writes the statement entries for package debits of a saved transaction
+func recordPackageDebits(ctx context.Context, transaction models.Transaction) {
+	for _, debit := range transaction.PackageDebits {
+		addWalletEntry(ctx, models.WalletEntry{
+			CustomerID:        transaction.CustomerID,
+			CustomerPackageID: debit.CustomerPackageID,
+			TransactionID:     transaction.ID,
+			Type:              WalletDebit,
+			Kilograms:         -debit.Kilograms,
+			Credit:            -debit.Credit,
+			Description:       "Pemakaian " + debit.PackageName + " untuk nota " + transaction.InvoiceNumber,
+		})
+	}
+}
+
+// refundPackageDebits returns the package balance used by a deleted or refunded transaction
+func refundPackageDebits(ctx context.Context, transaction models.Transaction) error {
+	for _, debit := range transaction.PackageDebits {
+		packageID, err := primitive.ObjectIDFromHex(debit.CustomerPackageID)
+		if err != nil {
+			return err
+		}
+		_, err = config.CustomerPackageCollection.UpdateOne(ctx, bson.M{"_id": packageID},
+			bson.M{"$inc": bson.M{"kilograms_remaining": debit.Kilograms, "credit_remaining": debit.Credit}})
+		if err != nil {
+			return err
+		}
+		addWalletEntry(ctx, models.WalletEntry{
+			CustomerID:        transaction.CustomerID,
+			CustomerPackageID: debit.CustomerPackageID,
+			TransactionID:     transaction.ID,
+			Type:              WalletRefund,
+			Kilograms:         debit.Kilograms,
+			Credit:            debit.Credit,
+			Description:       "Pengembalian " + debit.PackageName + " dari nota " + transaction.InvoiceNumber,
+		})
+	}
+	return nil
+}
+
+// validatePackage checks the fields of a package sent by the client
+func validatePackage(pkg models.Package) string {
+	if pkg.Name == "" {
+		return "Package name is required"
+	}
+	switch pkg.Type {
+	case PackageKilo:
+		if pkg.Kilograms <= 0 {
+			return "Kilo package needs kilograms"
+		}
+	case PackageDeposit:
+		if pkg.Credit <= 0 {
+			return "Deposit package needs credit"
+		}
+	default:
+		return "Package type must be kilo or deposit"
+	}
+	if pkg.Price < 0 || pkg.ValidityDays < 0 {
+		return "Invalid price or validity"
+	}
+	return ""
+}
+
+// CreatePackage handles the creation of a new prepaid package
+func CreatePackage(w http.ResponseWriter, r *http.Request) {
+	var pkg models.Package
+	if err := json.NewDecoder(r.Body).Decode(&pkg); err != nil {
+		http.Error(w, `{"error": "Invalid input"}`, http.StatusBadRequest)
+		return
+	}
+
+	if msg := validatePackage(pkg); msg != "" {
+		http.Error(w, `{"error": "`+msg+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := config.PackageCollection.InsertOne(ctx, pkg)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to create package"}`, http.StatusInternalServerError)
+		return
+	}
+
+	pkg.ID = result.InsertedID.(primitive.ObjectID).Hex()
+
+	response := map[string]interface{}{
+		"message": "Package created successfully",
+		"package": pkg,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetAllPackages retrieves all prepaid packages from the database
+func GetAllPackages(w http.ResponseWriter, r *http.Request) {
+	cursor, err := config.PackageCollection.Find(context.TODO(), bson.M{})
+	if err != nil {
+		http.Error(w, "Failed to fetch packages", http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(context.TODO())
+
+	var packages []models.Package
+	for cursor.Next(context.TODO()) {
+		var pkg models.Package
+		if err := cursor.Decode(&pkg); err != nil {
+			http.Error(w, "Failed to read package data", http.StatusInternalServerError)
+			return
+		}
+		packages = append(packages, pkg)
+	}
+
+	if len(packages) == 0 {
+		http.Error(w, "No packages found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(packages)
+}
+
+// GetPackageByID retrieves a prepaid package by its ID
+func GetPackageByID(w http.ResponseWriter, r *http.Request) {
+	packageID := r.URL.Query().Get("id")
+	if packageID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(packageID)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var pkg models.Package
+	err = config.PackageCollection.FindOne(context.TODO(), bson.M{"_id": id}).Decode(&pkg)
+	if err != nil {
+		http.Error(w, "Package not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pkg)
+}
+
+// UpdatePackage updates a prepaid package by its ID. Packages already bought keep their terms.
+func UpdatePackage(w http.ResponseWriter, r *http.Request) {
+	packageID := r.URL.Query().Get("id")
+	if packageID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(packageID)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var updatedPackage models.Package
+	if err := json.NewDecoder(r.Body).Decode(&updatedPackage); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	if msg := validatePackage(updatedPackage); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"name":          updatedPackage.Name,
+			"type":          updatedPackage.Type,
+			"kilograms":     updatedPackage.Kilograms,
+			"credit":        updatedPackage.Credit,
+			"price":         updatedPackage.Price,
+			"validity_days": updatedPackage.ValidityDays,
+			"active":        updatedPackage.Active,
+		},
+	}
+
+	result, err := config.PackageCollection.UpdateOne(context.TODO(), bson.M{"_id": id}, update)
+	if err != nil {
+		http.Error(w, "Failed to update package", http.StatusInternalServerError)
+		return
+	}
+
+	if result.MatchedCount == 0 {
+		http.Error(w, "Package not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Package updated successfully"})
+}
+
+// DeletePackage deletes a prepaid package by its ID
+func DeletePackage(w http.ResponseWriter, r *http.Request) {
+	packageID := r.URL.Query().Get("id")
+	if packageID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(packageID)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	result, err := config.PackageCollection.DeleteOne(context.TODO(), bson.M{"_id": id})
+	if err != nil {
+		http.Error(w, "Failed to delete package", http.StatusInternalServerError)
+		return
+	}
+
+	if result.DeletedCount == 0 {
+		http.Error(w, "Package not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Package deleted successfully"})
+}
+
+// PurchasePackage sells a package to a customer and adds its kilograms or credit to their balance
+func PurchasePackage(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		CustomerID    string `json:"customer_id"`
+		PackageID     string `json:"package_id"`
+		PaymentMethod string `json:"payment_method"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, `{"error": "Invalid input"}`, http.StatusBadRequest)
+		return
+	}
+
+	packageID, err := primitive.ObjectIDFromHex(request.PackageID)
+	if err != nil {
+		http.Error(w, `{"error": "Invalid package ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	customer, err := findCustomer(ctx, request.CustomerID)
+	if err != nil {
+		http.Error(w, `{"error": "Customer not found"}`, http.StatusNotFound)
+		return
+	}
+
+	var pkg models.Package
+	if err := config.PackageCollection.FindOne(ctx, bson.M{"_id": packageID}).Decode(&pkg); err != nil {
+		http.Error(w, `{"error": "Package not found"}`, http.StatusNotFound)
+		return
+	}
+	if !pkg.Active {
+		http.Error(w, `{"error": "Package is not for sale"}`, http.StatusUnprocessableEntity)
+		return
+	}
+
+	now := time.Now()
+	purchase := models.CustomerPackage{
+		CustomerID:         customer.ID,
+		PackageID:          pkg.ID,
+		PackageName:        pkg.Name,
+		Type:               pkg.Type,
+		KilogramsTotal:     pkg.Kilograms,
+		KilogramsRemaining: pkg.Kilograms,
+		CreditTotal:        pkg.Credit,
+		CreditRemaining:    pkg.Credit,
+		PricePaid:          pkg.Price,
+		PaymentMethod:      request.PaymentMethod,
+		PurchasedAt:        now,
+	}
+	if pkg.ValidityDays > 0 {
+		expiresAt := now.AddDate(0, 0, pkg.ValidityDays)
+		purchase.ExpiresAt = &expiresAt
+	}
+
+	result, err := config.CustomerPackageCollection.InsertOne(ctx, purchase)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to record package purchase"}`, http.StatusInternalServerError)
+		return
+	}
+	purchase.ID = result.InsertedID.(primitive.ObjectID).Hex()
+
+	addWalletEntry(ctx, models.WalletEntry{
+		CustomerID:        customer.ID,
+		CustomerPackageID: purchase.ID,
+		Type:              WalletPurchase,
+		Kilograms:         purchase.KilogramsTotal,
+		Credit:            purchase.CreditTotal,
+		Description:       "Pembelian " + pkg.Name,
+	})
+
+	response := map[string]interface{}{
+		"message":          "Package purchased successfully",
+		"customer_package": purchase,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetCustomerBalance returns a customer's prepaid kilogram and rupiah balance, their packages and
+// a statement of movements, optionally limited with ?from=yyyy-mm-dd&to=yyyy-mm-dd
+func GetCustomerBalance(w http.ResponseWriter, r *http.Request) {
+	customerID := r.URL.Query().Get("id")
+	if customerID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	if !primitive.IsValidObjectID(customerID) {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	statementFilter := bson.M{"customer_id": customerID}
+	dateFilter, err := dateRangeFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if dateFilter != nil {
+		statementFilter["date"] = dateFilter
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	customer, err := findCustomer(ctx, customerID)
+	if err != nil {
+		http.Error(w, "Customer not found", http.StatusNotFound)
+		return
+	}
+
+	cursor, err := config.CustomerPackageCollection.Find(ctx, bson.M{"customer_id": customerID},
+		options.Find().SetSort(bson.M{"purchased_at": -1}))
+	if err != nil {
+		http.Error(w, "Failed to fetch customer packages", http.StatusInternalServerError)
+		return
+	}
+	packages := []models.CustomerPackage{}
+	if err := cursor.All(ctx, &packages); err != nil {
+		http.Error(w, "Failed to read customer packages", http.StatusInternalServerError)
+		return
+	}
+
+	// Saldo hanya dihitung dari paket yang belum kedaluwarsa
+	now := time.Now()
+	kilograms, credit := 0.0, 0.0
+	for _, pkg := range packages {
+		if pkg.ExpiresAt != nil && !pkg.ExpiresAt.After(now) {
+			continue
+		}
+		kilograms += pkg.KilogramsRemaining
+		credit += pkg.CreditRemaining
+	}
+
+	cursor, err = config.WalletLedgerCollection.Find(ctx, statementFilter, options.Find().SetSort(bson.M{"date": 1}))
+	if err != nil {
+		http.Error(w, "Failed to fetch balance statement", http.StatusInternalServerError)
+		return
+	}
+	statement := []models.WalletEntry{}
+	if err := cursor.All(ctx, &statement); err != nil {
+		http.Error(w, "Failed to read balance statement", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"customer_id":       customer.ID,
+		"name":              customer.Name,
+		"kilograms_balance": kilograms,
+		"credit_balance":    credit,
+		"packages":          packages,
+		"statement":         statement,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
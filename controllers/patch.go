@@ -0,0 +1,264 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"apkclaundry/config"
+	"apkclaundry/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Fields that may be changed through the update endpoints, by JSON name
+var (
+	customerPatchFields    = []string{"name", "phone", "address", "email", "preferences", "account_id"}
+	itemPatchFields        = []string{"item_name", "quantity", "unit", "price"}
+	supplierPatchFields    = []string{"supplier_name", "phone_number", "address", "email", "supplied_products"}
+	transactionPatchFields = []string{"customer_name", "phone_number", "service_type", "pieces", "items", "special_instructions"}
+)
+
+// tagName returns the name part of a struct tag, e.g. "invoice_number" for `json:"invoice_number,omitempty"`
+func tagName(field reflect.StructField, key string) string {
+	return strings.Split(field.Tag.Get(key), ",")[0]
+}
+
+// structField finds the struct field with the given JSON name
+func structField(value reflect.Value, jsonName string) (reflect.Value, string, bool) {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if tagName(field, "json") == jsonName {
+			return value.Field(i), tagName(field, "bson"), true
+		}
+	}
+	return reflect.Value{}, "", false
+}
+
+// toDocument converts a model to its stored fields. Embedded documents stay bson.D so their field
+// order is kept, which MongoDB needs to match them by equality.
+func toDocument(model interface{}) (map[string]interface{}, error) {
+	data, err := bson.Marshal(model)
+	if err != nil {
+		return nil, err
+	}
+	var document bson.D
+	if err := bson.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{}
+	for _, element := range document {
+		fields[element.Key] = element.Value
+	}
+	return fields, nil
+}
+
+// emptyValues lists how a field that is empty in the model can be stored: missing or null, or
+// its zero value or an empty array or document when it was written without omitempty
+func emptyValues(field reflect.Value) bson.A {
+	values := bson.A{nil}
+	switch field.Kind() {
+	case reflect.Slice, reflect.Array:
+		values = append(values, bson.A{})
+	case reflect.Map:
+		values = append(values, bson.D{})
+	case reflect.Ptr, reflect.Interface:
+	default:
+		values = append(values, reflect.Zero(field.Type()).Interface())
+	}
+	return values
+}
+
+// patchDocument applies a JSON merge patch (RFC 7396) from the request body to the document with
+// the given ID. Only fields present in the body change: objects such as preferences are merged,
+// arrays are replaced and null clears a field. Each effective change is stored as a revision with
+// the editor and timestamp. model must be a pointer to the document's struct type; validate, if
+// not nil, can reject the effective changes before anything is saved.
+// It writes the error response itself and returns false when the patch was not applied.
+func patchDocument(w http.ResponseWriter, r *http.Request, collection *mongo.Collection, entity string, model interface{}, fields []string, validate func(ctx context.Context, changes []models.FieldChange) error) bool {
+	documentID := r.URL.Query().Get("id")
+	if documentID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return false
+	}
+
+	id, err := primitive.ObjectIDFromHex(documentID)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return false
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return false
+	}
+	var patch map[string]json.RawMessage
+	if err := json.Unmarshal(body, &patch); err != nil {
+		http.Error(w, "Invalid input, expected a JSON object", http.StatusBadRequest)
+		return false
+	}
+
+	editable := map[string]bool{}
+	for _, field := range fields {
+		editable[field] = true
+	}
+	keys := make([]string, 0, len(patch))
+	for key := range patch {
+		if !editable[key] {
+			http.Error(w, "Field "+key+" cannot be updated", http.StatusBadRequest)
+			return false
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(model); err != nil {
+		http.Error(w, strings.ToUpper(entity[:1])+entity[1:]+" not found", http.StatusNotFound)
+		return false
+	}
+	before, err := toDocument(model)
+	if err != nil {
+		http.Error(w, "Failed to read "+entity, http.StatusInternalServerError)
+		return false
+	}
+
+	// null mengosongkan field; decoder JSON sendiri mengabaikan null untuk tipe non-pointer
+	value := reflect.ValueOf(model).Elem()
+	for _, key := range keys {
+		if bytes.Equal(bytes.TrimSpace(patch[key]), []byte("null")) {
+			if field, _, ok := structField(value, key); ok {
+				field.Set(reflect.Zero(field.Type()))
+			}
+		}
+	}
+	// Decoder JSON mengisi struct yang sudah ada, sehingga objek bersarang ikut di-merge
+	if err := json.Unmarshal(body, model); err != nil {
+		http.Error(w, "Invalid input: "+err.Error(), http.StatusBadRequest)
+		return false
+	}
+	after, err := toDocument(model)
+	if err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return false
+	}
+
+	// Nilai lama menjadi syarat update agar perubahan orang lain di antaranya tidak tertimpa
+	filter := bson.M{"_id": id}
+	set, unset := bson.M{}, bson.M{}
+	var changes []models.FieldChange
+	for _, key := range keys {
+		field, name, _ := structField(value, key)
+		old, updated := before[name], after[name]
+		if reflect.DeepEqual(old, updated) {
+			continue
+		}
+		// Field yang kosong bisa tersimpan sebagai null, nilai nol atau tidak ada sama sekali
+		if old != nil {
+			filter[name] = old
+		} else {
+			filter[name] = bson.M{"$in": emptyValues(field)}
+		}
+		if updated == nil {
+			unset[name] = ""
+		} else {
+			set[name] = updated
+		}
+		changes = append(changes, models.FieldChange{Field: key, Old: old, New: updated})
+	}
+	if len(changes) == 0 {
+		return true
+	}
+	if validate != nil {
+		if err := validate(ctx, changes); err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return false
+		}
+	}
+
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		http.Error(w, "Failed to update "+entity, http.StatusInternalServerError)
+		return false
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, strings.ToUpper(entity[:1])+entity[1:]+" was changed by someone else, please retry", http.StatusConflict)
+		return false
+	}
+
+	if err := recordRevision(ctx, entity, documentID, changes, r); err != nil {
+		log.Printf("Failed to record revision of %s %s: %v", entity, documentID, err)
+	}
+	return true
+}
+
+// recordRevision stores the changes of one update as the next revision of a document
+func recordRevision(ctx context.Context, entity, documentID string, changes []models.FieldChange, r *http.Request) error {
+	// Counter atomik agar dua update bersamaan tidak mendapat nomor yang sama
+	number, err := nextSequence(ctx, "revision:"+entity+":"+documentID)
+	if err != nil {
+		return err
+	}
+
+	_, err = config.RevisionCollection.InsertOne(ctx, models.Revision{
+		Collection: entity,
+		DocumentID: documentID,
+		Number:     int(number),
+		Changes:    changes,
+		EditedByID: r.Header.Get("User-ID"),
+		EditedBy:   r.Header.Get("Username"),
+		EditedAt:   time.Now(),
+	})
+	return err
+}
+
+// GetRevisions returns the change history of a document, newest first:
+// ?collection=transaction|customer|item|supplier&id=...
+func GetRevisions(w http.ResponseWriter, r *http.Request) {
+	entity := r.URL.Query().Get("collection")
+	documentID := r.URL.Query().Get("id")
+	if entity == "" || documentID == "" {
+		http.Error(w, "collection and id are required", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := config.RevisionCollection.Find(ctx,
+		bson.M{"collection": entity, "document_id": documentID},
+		options.Find().SetSort(bson.D{{Key: "number", Value: -1}}),
+	)
+	if err != nil {
+		http.Error(w, "Failed to fetch revisions", http.StatusInternalServerError)
+		return
+	}
+	revisions := []models.Revision{}
+	if err := cursor.All(ctx, &revisions); err != nil {
+		http.Error(w, "Failed to decode revisions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(revisions)
+}
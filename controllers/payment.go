@@ -0,0 +1,478 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"apkclaundry/config"
+	"apkclaundry/models"
+	"apkclaundry/payment"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var errPaymentExceedsBalance = errors.New("Amount exceeds the balance due or the transaction is cancelled")
+
+// balanceDue is what is still to be paid on a transaction
+func balanceDue(transaction models.Transaction) float64 {
+	return math.Max(0, transaction.TotalPrice-transaction.AmountPaid)
+}
+
+// recordPayment adds a payment to a transaction's AmountPaid exactly once per Reference and
+// awards loyalty points once the order is fully paid. It returns false when the reference had
+// already been recorded, so webhook retries and double submits are harmless, and
+// errPaymentExceedsBalance when the order is cancelled or the payment would overpay it.
+func recordPayment(ctx context.Context, paid models.Payment) (bool, error) {
+	transactionID, err := primitive.ObjectIDFromHex(paid.TransactionID)
+	if err != nil {
+		return false, err
+	}
+
+	// payment_refs pada dokumen transaksi menjamin penjumlahan terjadi sekali saja, dan total harga
+	// menjadi batas agar dua pembayaran bersamaan tidak melunasi pesanan dua kali
+	var transaction models.Transaction
+	err = config.TransactionCollection.FindOneAndUpdate(ctx,
+		bson.M{
+			"_id":          transactionID,
+			"payment_refs": bson.M{"$ne": paid.Reference},
+			"status":       bson.M{"$ne": StatusCancelled},
+			"$expr":        bson.M{"$lte": bson.A{bson.M{"$add": bson.A{"$amount_paid", paid.Amount}}, "$total_price"}},
+		},
+		bson.M{"$inc": bson.M{"amount_paid": paid.Amount}, "$push": bson.M{"payment_refs": paid.Reference}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&transaction)
+	recorded := err == nil
+	if err == mongo.ErrNoDocuments {
+		if transaction, err = findTransaction(ctx, paid.TransactionID); err != nil {
+			return false, err
+		}
+		if !slices.Contains(transaction.PaymentRefs, paid.Reference) {
+			return false, errPaymentExceedsBalance
+		}
+	} else if err != nil {
+		return false, err
+	}
+	if recorded {
+		before := transaction
+		before.AmountPaid -= paid.Amount
+		syncAccountOutstanding(ctx, before, transaction)
+	}
+
+	if paid.Date.IsZero() {
+		paid.Date = time.Now()
+	}
+	paid.CustomerID = transaction.CustomerID
+	if _, err := config.PaymentCollection.InsertOne(ctx, paid); err != nil && !mongo.IsDuplicateKeyError(err) {
+		log.Printf("Failed to write payment %s: %v", paid.Reference, err)
+	}
+
+	if err := awardLoyaltyPoints(ctx, transaction); err != nil {
+		log.Printf("Failed to award loyalty points for transaction %s: %v", transaction.ID, err)
+	}
+	return recorded, nil
+}
+
+// recordOverpayment handles a gateway payment that no longer fits its order, e.g. because the order
+// was paid at the counter or cancelled in the meantime. What still fits is credited; the rest is
+// kept as a payment flagged for refund instead of being added to AmountPaid.
+func recordOverpayment(ctx context.Context, paid models.Payment) error {
+	transaction, err := findTransaction(ctx, paid.TransactionID)
+	if err != nil {
+		return err
+	}
+
+	overflow := paid.Amount
+	if credit := balanceDue(transaction); credit > 0 && credit < paid.Amount && transaction.Status != StatusCancelled {
+		part := paid
+		part.Amount = credit
+		_, err := recordPayment(ctx, part)
+		if err == nil {
+			overflow -= credit
+		} else if err != errPaymentExceedsBalance {
+			return err
+		}
+	}
+
+	if paid.Date.IsZero() {
+		paid.Date = time.Now()
+	}
+	paid.CustomerID = transaction.CustomerID
+	paid.Amount = overflow
+	paid.Reference += ":overpaid"
+	paid.RefundDue = true
+	if _, err := config.PaymentCollection.InsertOne(ctx, paid); err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	log.Printf("Payment %s overpaid transaction %s by %.2f, flagged for refund", paid.Reference, transaction.ID, overflow)
+	return nil
+}
+
+// expirePendingCharges closes the open gateway charges of an order, except keepOrderID, so the
+// customer cannot pay the same balance twice through different bills
+func expirePendingCharges(ctx context.Context, transactionID, keepOrderID string) {
+	cursor, err := config.PaymentChargeCollection.Find(ctx, bson.M{
+		"transaction_id": transactionID,
+		"status":         payment.StatusPending,
+		"order_id":       bson.M{"$ne": keepOrderID},
+	})
+	if err != nil {
+		log.Printf("Failed to read pending charges of transaction %s: %v", transactionID, err)
+		return
+	}
+	var charges []models.PaymentCharge
+	if err := cursor.All(ctx, &charges); err != nil || len(charges) == 0 {
+		return
+	}
+
+	provider, providerErr := payment.FromSettings()
+	for _, charge := range charges {
+		// Tagihan yang tetap dibayar di gateway masuk sebagai kelebihan bayar
+		if providerErr == nil && charge.Provider == provider.Name() {
+			if err := provider.ExpireCharge(ctx, charge.OrderID); err != nil {
+				log.Printf("Failed to expire charge %s at the gateway: %v", charge.OrderID, err)
+			}
+		}
+		_, err := config.PaymentChargeCollection.UpdateOne(ctx,
+			bson.M{"order_id": charge.OrderID, "status": payment.StatusPending},
+			bson.M{"$set": bson.M{"status": payment.StatusExpired}},
+		)
+		if err != nil {
+			log.Printf("Failed to expire charge %s: %v", charge.OrderID, err)
+		}
+	}
+}
+
+// RecordTransactionPayment records a payment taken at the counter, e.g. {"amount": 20000, "method": "cash"}.
+// An optional "reference" makes retries from the client safe. Cash goes into the cashier's open shift.
+func RecordTransactionPayment(w http.ResponseWriter, r *http.Request) {
+	transactionID := r.URL.Query().Get("id")
+	if transactionID == "" {
+		http.Error(w, `{"error": "ID not provided"}`, http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		Amount    float64 `json:"amount"`
+		Method    string  `json:"method"`
+		Reference string  `json:"reference"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, `{"error": "Invalid input"}`, http.StatusBadRequest)
+		return
+	}
+	if request.Amount <= 0 || request.Method == "" {
+		http.Error(w, `{"error": "Amount and method are required"}`, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	transaction, err := findTransaction(ctx, transactionID)
+	if err != nil {
+		http.Error(w, `{"error": "Transaction not found"}`, http.StatusNotFound)
+		return
+	}
+	if transaction.Status == StatusCancelled {
+		http.Error(w, `{"error": "Transaction is cancelled"}`, http.StatusConflict)
+		return
+	}
+	if request.Amount > balanceDue(transaction) {
+		http.Error(w, `{"error": "Amount exceeds the balance due"}`, http.StatusUnprocessableEntity)
+		return
+	}
+
+	reference := "manual:" + primitive.NewObjectID().Hex()
+	if request.Reference != "" {
+		reference = "manual:" + transaction.ID + ":" + request.Reference
+	}
+	recorded, err := recordPayment(ctx, models.Payment{
+		TransactionID: transaction.ID,
+		Amount:        request.Amount,
+		PaymentType:   request.Method,
+		Reference:     reference,
+	})
+	if err == errPaymentExceedsBalance {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error": "Failed to record payment"}`, http.StatusInternalServerError)
+		return
+	}
+	if recorded {
+		// Tagihan QRIS/VA yang masih terbuka memakai sisa tagihan lama
+		expirePendingCharges(ctx, transaction.ID, "")
+		if isCashMethod(request.Method) {
+			recordCashSale(ctx, r.Header.Get("User-ID"), transaction, request.Amount)
+		}
+	}
+
+	message := "Payment recorded successfully"
+	if !recorded {
+		message = "Payment was already recorded"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+// CreatePaymentCharge creates a QRIS or virtual-account bill for the outstanding balance of a
+// transaction, e.g. {"method": "qris"} or {"method": "va", "bank": "bca"}. A still-valid bill
+// for the same method and amount is returned instead of creating a new one.
+func CreatePaymentCharge(w http.ResponseWriter, r *http.Request) {
+	transactionID := r.URL.Query().Get("id")
+	if transactionID == "" {
+		http.Error(w, `{"error": "ID not provided"}`, http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		Method string `json:"method"`
+		Bank   string `json:"bank"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, `{"error": "Invalid input"}`, http.StatusBadRequest)
+		return
+	}
+	if request.Method != payment.MethodQRIS && request.Method != payment.MethodVA {
+		http.Error(w, `{"error": "Method must be qris or va"}`, http.StatusBadRequest)
+		return
+	}
+	if request.Method == payment.MethodVA && request.Bank == "" {
+		http.Error(w, `{"error": "Bank is required for virtual account"}`, http.StatusBadRequest)
+		return
+	}
+	if request.Method == payment.MethodQRIS {
+		request.Bank = ""
+	}
+
+	provider, err := payment.FromSettings()
+	if err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	transaction, err := findTransaction(ctx, transactionID)
+	if err != nil {
+		http.Error(w, `{"error": "Transaction not found"}`, http.StatusNotFound)
+		return
+	}
+	if transaction.Status == StatusCancelled {
+		http.Error(w, `{"error": "Transaction is cancelled"}`, http.StatusConflict)
+		return
+	}
+	amount := balanceDue(transaction)
+	if amount <= 0 {
+		http.Error(w, `{"error": "Transaction is already paid"}`, http.StatusConflict)
+		return
+	}
+
+	now := time.Now()
+	var charge models.PaymentCharge
+	err = config.PaymentChargeCollection.FindOne(ctx, bson.M{
+		"transaction_id": transaction.ID,
+		"method":         request.Method,
+		"bank":           bson.M{"$in": bson.A{request.Bank, nil}},
+		"amount":         amount,
+		"status":         payment.StatusPending,
+		"expires_at":     bson.M{"$gt": now},
+	}).Decode(&charge)
+	if err == nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"message": "Existing charge is still valid", "charge": charge})
+		return
+	}
+
+	// order_id harus unik di gateway, jadi tiap tagihan baru diberi nomor urut dari counter atomik
+	seq, err := nextSequence(ctx, "charge:"+transaction.ID)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to create charge"}`, http.StatusInternalServerError)
+		return
+	}
+	reference := transaction.InvoiceNumber
+	if reference == "" {
+		reference = transaction.ID
+	}
+	orderID := reference + "-P" + strconv.FormatInt(seq, 10)
+
+	created, err := provider.CreateCharge(ctx, payment.ChargeRequest{
+		OrderID: orderID,
+		Method:  request.Method,
+		Bank:    request.Bank,
+		Amount:  amount,
+		Expiry:  time.Duration(config.GetEnvInt("PAYMENT_EXPIRY_MINUTES", 30)) * time.Minute,
+	})
+	if err != nil {
+		log.Printf("Payment provider rejected charge %s: %v", orderID, err)
+		http.Error(w, `{"error": "Payment provider error: `+err.Error()+`"}`, http.StatusBadGateway)
+		return
+	}
+
+	charge = models.PaymentCharge{
+		TransactionID: transaction.ID,
+		OrderID:       orderID,
+		Provider:      provider.Name(),
+		ProviderRef:   created.ProviderRef,
+		Method:        request.Method,
+		Bank:          request.Bank,
+		Amount:        amount,
+		QRString:      created.QRString,
+		VANumber:      created.VANumber,
+		Status:        payment.StatusPending,
+		ExpiresAt:     created.ExpiresAt,
+		CreatedAt:     now,
+	}
+	result, err := config.PaymentChargeCollection.InsertOne(ctx, charge)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to save charge"}`, http.StatusInternalServerError)
+		return
+	}
+	charge.ID = result.InsertedID.(primitive.ObjectID).Hex()
+	expirePendingCharges(ctx, transaction.ID, orderID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Charge created successfully", "charge": charge})
+}
+
+// GetTransactionPayments returns the gateway charges and recorded payments of a transaction
+func GetTransactionPayments(w http.ResponseWriter, r *http.Request) {
+	transactionID := r.URL.Query().Get("id")
+	if transactionID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	transaction, err := findTransaction(ctx, transactionID)
+	if err != nil {
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+		return
+	}
+
+	charges := []models.PaymentCharge{}
+	cursor, err := config.PaymentChargeCollection.Find(ctx, bson.M{"transaction_id": transaction.ID},
+		options.Find().SetSort(bson.M{"created_at": 1}))
+	if err == nil {
+		err = cursor.All(ctx, &charges)
+	}
+	if err != nil {
+		http.Error(w, "Failed to fetch charges", http.StatusInternalServerError)
+		return
+	}
+
+	payments := []models.Payment{}
+	cursor, err = config.PaymentCollection.Find(ctx, bson.M{"transaction_id": transaction.ID},
+		options.Find().SetSort(bson.M{"date": 1}))
+	if err == nil {
+		err = cursor.All(ctx, &payments)
+	}
+	if err != nil {
+		http.Error(w, "Failed to fetch payments", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"total_price": transaction.TotalPrice,
+		"amount_paid": transaction.AmountPaid,
+		"balance_due": balanceDue(transaction),
+		"charges":     charges,
+		"payments":    payments,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// PaymentWebhook receives payment notifications from the gateway. The signature is verified by
+// the provider; a paid notification is recorded against the transaction at most once.
+func PaymentWebhook(w http.ResponseWriter, r *http.Request) {
+	provider, err := payment.FromSettings()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	notification, err := provider.ParseWebhook(r)
+	if errors.Is(err, payment.ErrInvalidSignature) {
+		log.Printf("Rejected payment webhook: %v", err)
+		http.Error(w, "Invalid signature", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var charge models.PaymentCharge
+	if err := config.PaymentChargeCollection.FindOne(ctx, bson.M{"order_id": notification.OrderID}).Decode(&charge); err != nil {
+		http.Error(w, "Charge not found", http.StatusNotFound)
+		return
+	}
+
+	switch notification.Status {
+	case payment.StatusPaid:
+		// Jumlah yang tidak sesuai tagihan tidak dikreditkan; diselesaikan manual oleh admin
+		if notification.Amount != charge.Amount {
+			log.Printf("Rejected payment for charge %s: paid %.2f instead of %.2f", charge.OrderID, notification.Amount, charge.Amount)
+			http.Error(w, "Amount does not match the charge", http.StatusUnprocessableEntity)
+			return
+		}
+		paid := models.Payment{
+			TransactionID: charge.TransactionID,
+			Amount:        notification.Amount,
+			PaymentType:   charge.Method,
+			Provider:      charge.Provider,
+			Reference:     charge.Provider + ":" + charge.OrderID,
+			Date:          notification.PaidAt,
+		}
+		_, err := recordPayment(ctx, paid)
+		if err == errPaymentExceedsBalance {
+			// Pesanan sudah lunas atau dibatalkan: uangnya dicatat untuk dikembalikan
+			err = recordOverpayment(ctx, paid)
+		}
+		if err != nil {
+			// Balas error agar gateway mengirim ulang notifikasinya
+			http.Error(w, "Failed to record payment", http.StatusInternalServerError)
+			return
+		}
+		_, err = config.PaymentChargeCollection.UpdateOne(ctx,
+			bson.M{"order_id": charge.OrderID},
+			bson.M{"$set": bson.M{"status": payment.StatusPaid, "paid_at": notification.PaidAt}},
+		)
+		if err != nil {
+			log.Printf("Failed to mark charge %s as paid: %v", charge.OrderID, err)
+		}
+		expirePendingCharges(ctx, charge.TransactionID, charge.OrderID)
+	case payment.StatusExpired, payment.StatusFailed:
+		_, err := config.PaymentChargeCollection.UpdateOne(ctx,
+			bson.M{"order_id": charge.OrderID, "status": payment.StatusPending},
+			bson.M{"$set": bson.M{"status": notification.Status}},
+		)
+		if err != nil {
+			http.Error(w, "Failed to update charge", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "OK"})
+}
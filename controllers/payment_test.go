@@ -0,0 +1,223 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"apkclaundry/config"
+	"apkclaundry/models"
+	"apkclaundry/payment"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const testServerKey = "test-key"
+
+// useTestDatabase points the collections used by payments at a throwaway database.
+// The test is skipped unless MONGODB_TEST_URI is set.
+func useTestDatabase(t *testing.T) context.Context {
+	t.Helper()
+	uri := os.Getenv("MONGODB_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGODB_TEST_URI not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	t.Cleanup(cancel)
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	database := client.Database("apkclaundry_test_" + strconv.FormatInt(time.Now().UnixNano(), 36))
+	t.Cleanup(func() {
+		database.Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+
+	config.TransactionCollection = database.Collection("transaksi")
+	config.CustomerCollection = database.Collection("pelanggan")
+	config.PaymentCollection = database.Collection("pembayaran")
+	config.PaymentChargeCollection = database.Collection("tagihan_bayar")
+	config.LoyaltyLedgerCollection = database.Collection("poin")
+	config.CounterCollection = database.Collection("counter")
+	_, err = config.PaymentCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "reference", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		t.Fatalf("create index: %v", err)
+	}
+
+	t.Setenv("PAYMENT_PROVIDER", "midtrans")
+	t.Setenv("PAYMENT_SERVER_KEY", testServerKey)
+	return ctx
+}
+
+// settlement builds a signed Midtrans settlement notification
+func settlement(orderID string, amount float64) *http.Request {
+	statusCode, grossAmount := "200", strconv.FormatFloat(amount, 'f', 2, 64)
+	sum := sha512.Sum512([]byte(orderID + statusCode + grossAmount + testServerKey))
+	payload, _ := json.Marshal(map[string]string{
+		"transaction_id":     "trx-" + orderID,
+		"order_id":           orderID,
+		"status_code":        statusCode,
+		"gross_amount":       grossAmount,
+		"transaction_status": "settlement",
+		"signature_key":      hex.EncodeToString(sum[:]),
+	})
+	return httptest.NewRequest(http.MethodPost, "/payment-webhook", bytes.NewReader(payload))
+}
+
+// pendingCharge stores a customer, an unpaid transaction and a pending QRIS charge for it
+func pendingCharge(t *testing.T, ctx context.Context, orderID string, amount float64) (customerID, transactionID primitive.ObjectID) {
+	t.Helper()
+	customerID, transactionID = primitive.NewObjectID(), primitive.NewObjectID()
+	_, err := config.CustomerCollection.InsertOne(ctx, bson.M{"_id": customerID, "name": "Budi"})
+	if err == nil {
+		_, err = config.TransactionCollection.InsertOne(ctx, bson.M{
+			"_id":            transactionID,
+			"customer_id":    customerID.Hex(),
+			"invoice_number": "LDY-20261017-0001",
+			"total_price":    amount,
+			"amount_paid":    0.0,
+			"status":         StatusReceived,
+		})
+	}
+	if err == nil {
+		_, err = config.PaymentChargeCollection.InsertOne(ctx, models.PaymentCharge{
+			TransactionID: transactionID.Hex(),
+			OrderID:       orderID,
+			Provider:      "midtrans",
+			Method:        payment.MethodQRIS,
+			Amount:        amount,
+			Status:        payment.StatusPending,
+			CreatedAt:     time.Now(),
+		})
+	}
+	if err != nil {
+		t.Fatalf("insert fixtures: %v", err)
+	}
+	return customerID, transactionID
+}
+
+func TestPaymentWebhookReplayIsIdempotent(t *testing.T) {
+	ctx := useTestDatabase(t)
+	customerID, transactionID := pendingCharge(t, ctx, "LDY-20261017-0001-P1", 25000)
+
+	// Gateway mengirim ulang notifikasi yang sama bila balasan sebelumnya tidak sampai
+	for i := 0; i < 3; i++ {
+		recorder := httptest.NewRecorder()
+		PaymentWebhook(recorder, settlement("LDY-20261017-0001-P1", 25000))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("delivery %d: status %d: %s", i+1, recorder.Code, recorder.Body.String())
+		}
+	}
+
+	var transaction models.Transaction
+	if err := config.TransactionCollection.FindOne(ctx, bson.M{"_id": transactionID}).Decode(&transaction); err != nil {
+		t.Fatal(err)
+	}
+	if transaction.AmountPaid != 25000 {
+		t.Errorf("amount_paid = %.2f, want 25000", transaction.AmountPaid)
+	}
+	if count, _ := config.PaymentCollection.CountDocuments(ctx, bson.M{"transaction_id": transactionID.Hex()}); count != 1 {
+		t.Errorf("payments = %d, want 1", count)
+	}
+	var customer models.Customer
+	if err := config.CustomerCollection.FindOne(ctx, bson.M{"_id": customerID}).Decode(&customer); err != nil {
+		t.Fatal(err)
+	}
+	if want := int(25000 / config.Loyalty().RupiahPerPoint); customer.LoyaltyPoints != want {
+		t.Errorf("loyalty_points = %d, want %d", customer.LoyaltyPoints, want)
+	}
+	var charge models.PaymentCharge
+	if err := config.PaymentChargeCollection.FindOne(ctx, bson.M{"order_id": "LDY-20261017-0001-P1"}).Decode(&charge); err != nil {
+		t.Fatal(err)
+	}
+	if charge.Status != payment.StatusPaid {
+		t.Errorf("charge status = %q, want %q", charge.Status, payment.StatusPaid)
+	}
+}
+
+func TestPaymentWebhookRejectsWrongAmount(t *testing.T) {
+	ctx := useTestDatabase(t)
+	_, transactionID := pendingCharge(t, ctx, "LDY-20261017-0001-P1", 25000)
+
+	recorder := httptest.NewRecorder()
+	PaymentWebhook(recorder, settlement("LDY-20261017-0001-P1", 1000))
+	if recorder.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusUnprocessableEntity)
+	}
+
+	var transaction models.Transaction
+	if err := config.TransactionCollection.FindOne(ctx, bson.M{"_id": transactionID}).Decode(&transaction); err != nil {
+		t.Fatal(err)
+	}
+	if transaction.AmountPaid != 0 {
+		t.Errorf("amount_paid = %.2f, want 0", transaction.AmountPaid)
+	}
+}
+
+func TestPaymentWebhookRejectsBadSignature(t *testing.T) {
+	t.Setenv("PAYMENT_PROVIDER", "midtrans")
+	t.Setenv("PAYMENT_SERVER_KEY", "another-key")
+
+	recorder := httptest.NewRecorder()
+	PaymentWebhook(recorder, settlement("LDY-20261017-0001-P1", 25000))
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestPaymentWebhookFlagsOverpayment(t *testing.T) {
+	ctx := useTestDatabase(t)
+	_, transactionID := pendingCharge(t, ctx, "LDY-20261017-0001-P1", 25000)
+
+	// Pesanan dilunasi di kasir sebelum QRIS dibayar
+	_, err := config.TransactionCollection.UpdateOne(ctx,
+		bson.M{"_id": transactionID},
+		bson.M{"$set": bson.M{"amount_paid": 25000.0, "payment_refs": bson.A{"counter-1"}}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		recorder := httptest.NewRecorder()
+		PaymentWebhook(recorder, settlement("LDY-20261017-0001-P1", 25000))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("delivery %d: status %d: %s", i+1, recorder.Code, recorder.Body.String())
+		}
+	}
+
+	var transaction models.Transaction
+	if err := config.TransactionCollection.FindOne(ctx, bson.M{"_id": transactionID}).Decode(&transaction); err != nil {
+		t.Fatal(err)
+	}
+	if transaction.AmountPaid != 25000 {
+		t.Errorf("amount_paid = %.2f, want 25000", transaction.AmountPaid)
+	}
+	var refunds []models.Payment
+	cursor, err := config.PaymentCollection.Find(ctx, bson.M{"transaction_id": transactionID.Hex(), "refund_due": true})
+	if err == nil {
+		err = cursor.All(ctx, &refunds)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refunds) != 1 || refunds[0].Amount != 25000 {
+		t.Errorf("refund payments = %+v, want one of 25000", refunds)
+	}
+}
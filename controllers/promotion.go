@@ -0,0 +1,521 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"apkclaundry/config"
+	"apkclaundry/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	DiscountPercentage = "percentage"
+	DiscountFixed      = "fixed"
+)
+
+var (
+	errPromotionNotFound      = errors.New("promo code not found")
+	errPromotionInactive      = errors.New("promo code is not active")
+	errPromotionNotStarted    = errors.New("promo code is not valid yet")
+	errPromotionExpired       = errors.New("promo code has expired")
+	errPromotionMinSpend      = errors.New("order does not reach the minimum spend for this promo")
+	errPromotionService       = errors.New("promo code is not valid for this service")
+	errPromotionUsageLimit    = errors.New("promo code has reached its usage limit")
+	errPromotionCustomerLimit = errors.New("customer has reached the usage limit for this promo")
+	errPromotionNeedsCustomer = errors.New("promo code needs a customer or phone number")
+
+	// promotionErrors are rule violations the cashier can act on, as opposed to database failures
+	promotionErrors = map[error]struct{}{
+		errPromotionInactive:      {},
+		errPromotionNotStarted:    {},
+		errPromotionExpired:       {},
+		errPromotionMinSpend:      {},
+		errPromotionService:       {},
+		errPromotionUsageLimit:    {},
+		errPromotionCustomerLimit: {},
+		errPromotionNeedsCustomer: {},
+	}
+)
+
+// normalizePromoCode membuat kode promo tidak peka huruf besar/kecil
+func normalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// promotionCustomerKey identifies a customer for per-customer limits: the customer ID when
+// the order is linked to one, otherwise the phone number
+func promotionCustomerKey(transaction models.Transaction) string {
+	if transaction.CustomerID != "" {
+		return transaction.CustomerID
+	}
+	return transaction.PhoneNumber
+}
+
+// calculateDiscount checks a promotion's rules against an order and returns the discount amount
+func calculateDiscount(promo models.Promotion, subtotal float64, serviceType string, now time.Time) (float64, error) {
+	if !promo.Active {
+		return 0, errPromotionInactive
+	}
+	if promo.ValidFrom != nil && now.Before(*promo.ValidFrom) {
+		return 0, errPromotionNotStarted
+	}
+	if promo.ValidUntil != nil && now.After(*promo.ValidUntil) {
+		return 0, errPromotionExpired
+	}
+	if subtotal < promo.MinSpend {
+		return 0, errPromotionMinSpend
+	}
+	if len(promo.EligibleServices) > 0 {
+		eligible := false
+		for _, service := range promo.EligibleServices {
+			if strings.EqualFold(service, serviceType) {
+				eligible = true
+				break
+			}
+		}
+		if !eligible {
+			return 0, errPromotionService
+		}
+	}
+
+	var discount float64
+	switch promo.DiscountType {
+	case DiscountPercentage:
+		discount = subtotal * promo.DiscountValue / 100
+		if promo.MaxDiscount > 0 && discount > promo.MaxDiscount {
+			discount = promo.MaxDiscount
+		}
+	default:
+		discount = promo.DiscountValue
+	}
+
+	// Diskon tidak boleh melebihi nilai pesanan
+	return math.Min(math.Round(discount), subtotal), nil
+}
+
+// findPromotionByCode loads a promotion by its (case-insensitive) code
+func findPromotionByCode(ctx context.Context, code string) (models.Promotion, error) {
+	var promo models.Promotion
+	err := config.PromotionCollection.FindOne(ctx, bson.M{"code": normalizePromoCode(code)}).Decode(&promo)
+	if err == mongo.ErrNoDocuments {
+		return promo, errPromotionNotFound
+	}
+	return promo, err
+}
+
+// reservePromotion atomically claims one use of a promotion for a customer. Both the total
+// and per-customer limits are enforced inside MongoDB so concurrent orders cannot overshoot.
+func reservePromotion(ctx context.Context, promo models.Promotion, customerKey string) error {
+	promoID, err := primitive.ObjectIDFromHex(promo.ID)
+	if err != nil {
+		return err
+	}
+	// Tanpa pelanggan, batas per pelanggan tidak bisa ditegakkan
+	if promo.PerCustomerLimit > 0 && customerKey == "" {
+		return errPromotionNeedsCustomer
+	}
+
+	result, err := config.PromotionCollection.UpdateOne(ctx,
+		bson.M{
+			"_id": promoID,
+			"$or": bson.A{
+				bson.M{"usage_limit": bson.M{"$lte": 0}},
+				bson.M{"$expr": bson.M{"$lt": bson.A{"$usage_count", "$usage_limit"}}},
+			},
+		},
+		bson.M{"$inc": bson.M{"usage_count": 1}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errPromotionUsageLimit
+	}
+
+	if promo.PerCustomerLimit <= 0 {
+		return nil
+	}
+
+	// Jika penghitung pelanggan sudah mencapai batas, filter tidak cocok sehingga upsert
+	// mencoba membuat dokumen dengan _id yang sama dan gagal dengan duplicate key
+	_, err = config.CounterCollection.UpdateOne(ctx,
+		bson.M{"_id": promotionCustomerCounter(promo, customerKey), "seq": bson.M{"$lt": promo.PerCustomerLimit}},
+		bson.M{"$inc": bson.M{"seq": 1}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		config.PromotionCollection.UpdateOne(ctx, bson.M{"_id": promoID}, bson.M{"$inc": bson.M{"usage_count": -1}})
+		if mongo.IsDuplicateKeyError(err) {
+			return errPromotionCustomerLimit
+		}
+		return err
+	}
+	return nil
+}
+
+// releasePromotion gives back a use claimed by reservePromotion, e.g. when saving the order fails
+func releasePromotion(ctx context.Context, promo models.Promotion, customerKey string) {
+	if promoID, err := primitive.ObjectIDFromHex(promo.ID); err == nil {
+		config.PromotionCollection.UpdateOne(ctx, bson.M{"_id": promoID}, bson.M{"$inc": bson.M{"usage_count": -1}})
+	}
+	if promo.PerCustomerLimit > 0 && customerKey != "" {
+		config.CounterCollection.UpdateOne(ctx,
+			bson.M{"_id": promotionCustomerCounter(promo, customerKey)},
+			bson.M{"$inc": bson.M{"seq": -1}},
+		)
+	}
+}
+
+func promotionCustomerCounter(promo models.Promotion, customerKey string) string {
+	return "promo:" + promo.ID + ":" + customerKey
+}
+
+// applyPromotion validates the promo code on a new transaction, reserves one use and adds the
+// discount line. The caller must release the reservation if the transaction is not saved.
+func applyPromotion(ctx context.Context, transaction *models.Transaction, now time.Time) (*models.Promotion, error) {
+	promo, err := findPromotionByCode(ctx, transaction.PromoCode)
+	if err != nil {
+		return nil, err
+	}
+
+	discount, err := calculateDiscount(promo, transaction.Subtotal, transaction.ServiceType, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := reservePromotion(ctx, promo, promotionCustomerKey(*transaction)); err != nil {
+		return nil, err
+	}
+
+	transaction.Discounts = append(transaction.Discounts, models.TransactionDiscount{
+		PromotionID: promo.ID,
+		Code:        promo.Code,
+		Description: "Promo " + promo.Name,
+		Amount:      discount,
+	})
+	return &promo, nil
+}
+
+// recordPromotionUsage stores the redemption for reporting
+func recordPromotionUsage(ctx context.Context, promo models.Promotion, transaction models.Transaction) error {
+	usage := models.PromotionUsage{
+		PromotionID:   promo.ID,
+		Code:          promo.Code,
+		TransactionID: transaction.ID,
+		CustomerKey:   promotionCustomerKey(transaction),
+		Date:          transaction.TransactionDate,
+	}
+	for _, discount := range transaction.Discounts {
+		if discount.PromotionID == promo.ID {
+			usage.DiscountAmount += discount.Amount
+		}
+	}
+
+	_, err := config.PromotionUsageCollection.InsertOne(ctx, usage)
+	return err
+}
+
+// validatePromotion checks the fields of a promotion sent by the client
+func validatePromotion(promo *models.Promotion) string {
+	promo.Code = normalizePromoCode(promo.Code)
+	if promo.Code == "" {
+		return "Promo code is required"
+	}
+	if promo.DiscountType != DiscountPercentage && promo.DiscountType != DiscountFixed {
+		return "Discount type must be percentage or fixed"
+	}
+	if promo.DiscountValue <= 0 || (promo.DiscountType == DiscountPercentage && promo.DiscountValue > 100) {
+		return "Invalid discount value"
+	}
+	if promo.ValidFrom != nil && promo.ValidUntil != nil && promo.ValidUntil.Before(*promo.ValidFrom) {
+		return "valid_until must be after valid_from"
+	}
+	return ""
+}
+
+// CreatePromotion handles the creation of a new promotion
+func CreatePromotion(w http.ResponseWriter, r *http.Request) {
+	var promo models.Promotion
+	if err := json.NewDecoder(r.Body).Decode(&promo); err != nil {
+		http.Error(w, `{"error": "Invalid input"}`, http.StatusBadRequest)
+		return
+	}
+
+	if msg := validatePromotion(&promo); msg != "" {
+		http.Error(w, `{"error": "`+msg+`"}`, http.StatusBadRequest)
+		return
+	}
+	promo.UsageCount = 0
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := config.PromotionCollection.InsertOne(ctx, promo)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			http.Error(w, `{"error": "Promo code already exists"}`, http.StatusConflict)
+			return
+		}
+		http.Error(w, `{"error": "Failed to create promotion"}`, http.StatusInternalServerError)
+		return
+	}
+
+	promo.ID = result.InsertedID.(primitive.ObjectID).Hex()
+
+	response := map[string]interface{}{
+		"message":   "Promotion created successfully",
+		"promotion": promo,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetAllPromotions retrieves all promotions from the database
+func GetAllPromotions(w http.ResponseWriter, r *http.Request) {
+	cursor, err := config.PromotionCollection.Find(context.TODO(), bson.M{})
+	if err != nil {
+		http.Error(w, "Failed to fetch promotions", http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(context.TODO())
+
+	var promotions []models.Promotion
+	for cursor.Next(context.TODO()) {
+		var promo models.Promotion
+		if err := cursor.Decode(&promo); err != nil {
+			http.Error(w, "Failed to read promotion data", http.StatusInternalServerError)
+			return
+		}
+		promotions = append(promotions, promo)
+	}
+
+	if len(promotions) == 0 {
+		http.Error(w, "No promotions found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(promotions)
+}
+
+// GetPromotionByID retrieves a promotion by its ID
+func GetPromotionByID(w http.ResponseWriter, r *http.Request) {
+	promoID := r.URL.Query().Get("id")
+	if promoID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(promoID)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var promo models.Promotion
+	err = config.PromotionCollection.FindOne(context.TODO(), bson.M{"_id": id}).Decode(&promo)
+	if err != nil {
+		http.Error(w, "Promotion not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(promo)
+}
+
+// UpdatePromotion updates a promotion's data by its ID. The usage counter is never overwritten.
+func UpdatePromotion(w http.ResponseWriter, r *http.Request) {
+	promoID := r.URL.Query().Get("id")
+	if promoID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(promoID)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var updatedPromo models.Promotion
+	if err := json.NewDecoder(r.Body).Decode(&updatedPromo); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	if msg := validatePromotion(&updatedPromo); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"code":               updatedPromo.Code,
+			"name":               updatedPromo.Name,
+			"discount_type":      updatedPromo.DiscountType,
+			"discount_value":     updatedPromo.DiscountValue,
+			"max_discount":       updatedPromo.MaxDiscount,
+			"min_spend":          updatedPromo.MinSpend,
+			"eligible_services":  updatedPromo.EligibleServices,
+			"valid_from":         updatedPromo.ValidFrom,
+			"valid_until":        updatedPromo.ValidUntil,
+			"usage_limit":        updatedPromo.UsageLimit,
+			"per_customer_limit": updatedPromo.PerCustomerLimit,
+			"active":             updatedPromo.Active,
+		},
+	}
+
+	result, err := config.PromotionCollection.UpdateOne(context.TODO(), bson.M{"_id": id}, update)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			http.Error(w, "Promo code already exists", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to update promotion", http.StatusInternalServerError)
+		return
+	}
+
+	if result.MatchedCount == 0 {
+		http.Error(w, "Promotion not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Promotion updated successfully"})
+}
+
+// DeletePromotion deletes a promotion by its ID
+func DeletePromotion(w http.ResponseWriter, r *http.Request) {
+	promoID := r.URL.Query().Get("id")
+	if promoID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(promoID)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	result, err := config.PromotionCollection.DeleteOne(context.TODO(), bson.M{"_id": id})
+	if err != nil {
+		http.Error(w, "Failed to delete promotion", http.StatusInternalServerError)
+		return
+	}
+
+	if result.DeletedCount == 0 {
+		http.Error(w, "Promotion not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Promotion deleted successfully"})
+}
+
+// CheckPromotion previews the discount a code would give without using it
+// (?code=&subtotal=&service_type=)
+func CheckPromotion(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	subtotal, err := strconv.ParseFloat(query.Get("subtotal"), 64)
+	if err != nil {
+		http.Error(w, "Invalid subtotal", http.StatusBadRequest)
+		return
+	}
+
+	promo, err := findPromotionByCode(context.TODO(), query.Get("code"))
+	if err == errPromotionNotFound {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to fetch promotion", http.StatusInternalServerError)
+		return
+	}
+
+	discount, err := calculateDiscount(promo, subtotal, query.Get("service_type"), time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if promo.UsageLimit > 0 && promo.UsageCount >= promo.UsageLimit {
+		http.Error(w, errPromotionUsageLimit.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"code":        promo.Code,
+		"name":        promo.Name,
+		"discount":    discount,
+		"total_price": subtotal - discount,
+	})
+}
+
+// GetPromotionReport summarizes promotion usage (count and total discount per code),
+// optionally limited to a period with ?from=yyyy-mm-dd&to=yyyy-mm-dd
+func GetPromotionReport(w http.ResponseWriter, r *http.Request) {
+	match := bson.M{}
+	dateFilter, err := dateRangeFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if dateFilter != nil {
+		match["date"] = dateFilter
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id":            "$code",
+			"usage_count":    bson.M{"$sum": 1},
+			"total_discount": bson.M{"$sum": "$discount_amount"},
+			"customers":      bson.M{"$addToSet": "$customer_key"},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":            0,
+			"code":           "$_id",
+			"usage_count":    1,
+			"total_discount": 1,
+			"customer_count": bson.M{"$size": "$customers"},
+		}}},
+		{{Key: "$sort", Value: bson.M{"usage_count": -1}}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := config.PromotionUsageCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		http.Error(w, "Failed to build promotion report", http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var report []struct {
+		Code          string  `json:"code" bson:"code"`
+		UsageCount    int     `json:"usage_count" bson:"usage_count"`
+		TotalDiscount float64 `json:"total_discount" bson:"total_discount"`
+		CustomerCount int     `json:"customer_count" bson:"customer_count"`
+	}
+	if err := cursor.All(ctx, &report); err != nil {
+		http.Error(w, "Failed to read promotion report", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
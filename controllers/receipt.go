@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"apkclaundry/config"
+	"apkclaundry/models"
+	"apkclaundry/receipt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetTransactionReceipt renders the receipt of a transaction as an A6 PDF (default)
+// or as a raw ESC/POS stream (?format=escpos&width=58|80) and increments its print counter
+func GetTransactionReceipt(w http.ResponseWriter, r *http.Request) {
+	transactionID := r.URL.Query().Get("id")
+	if transactionID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(transactionID)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "pdf"
+	}
+	if format != "pdf" && format != "escpos" {
+		http.Error(w, "Invalid format, use pdf or escpos", http.StatusBadRequest)
+		return
+	}
+
+	paperWidth := receipt.Paper58mm
+	if width := r.URL.Query().Get("width"); width != "" {
+		paperWidth, err = strconv.Atoi(width)
+		if err != nil || (paperWidth != receipt.Paper58mm && paperWidth != receipt.Paper80mm) {
+			http.Error(w, "Invalid width, use 58 or 80", http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Naikkan penghitung cetak secara atomik dan ambil dokumen terbaru
+	var transaction models.Transaction
+	err = config.TransactionCollection.FindOneAndUpdate(ctx,
+		bson.M{"_id": id},
+		bson.M{"$inc": bson.M{"receipt_print_count": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&transaction)
+	if err != nil {
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+		return
+	}
+
+	nota := receipt.FromTransaction(transaction, config.Business(), trackingURL(transaction))
+
+	if format == "escpos" {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", `attachment; filename="nota-`+transaction.ID+`.bin"`)
+		w.Write(nota.ESCPOS(paperWidth))
+		return
+	}
+
+	var pdf bytes.Buffer
+	if err := nota.WritePDF(&pdf); err != nil {
+		http.Error(w, "Failed to render receipt", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `inline; filename="nota-`+transaction.ID+`.pdf"`)
+	w.Write(pdf.Bytes())
+}
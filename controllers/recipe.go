@@ -0,0 +1,313 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"apkclaundry/config"
+	"apkclaundry/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Stock movement types, see models.ItemTransaction
+const (
+	StockUsage      = "Pemakaian"
+	StockPurchase   = "Pembelian"
+	StockReversal   = "Pembatalan"
+	StockAdjustment = "Penyesuaian"
+)
+
+// deductConsumables takes the consumables of the service recipe out of stock for an order that
+// goes into washing, writing one usage movement per item. Each item is claimed on the order before
+// its stock changes, so a retry after a failure only deducts the items that are still missing;
+// consumables_deducted is set once all of them are done. Stock may go negative: the detergent was
+// used either way, and the next stock count corrects the figure.
+func deductConsumables(ctx context.Context, transaction models.Transaction) error {
+	service, err := findServiceByName(ctx, transaction.ServiceType)
+	if err == errServiceNotFound || (err == nil && len(service.Recipe) == 0) || transaction.WeightPerKg <= 0 {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	id, _ := primitive.ObjectIDFromHex(transaction.ID)
+	now := time.Now()
+	var failed error
+	for _, line := range service.Recipe {
+		quantity := int(math.Ceil(line.QuantityPerKg * transaction.WeightPerKg))
+		if quantity <= 0 {
+			continue
+		}
+		itemID, err := primitive.ObjectIDFromHex(line.ItemID)
+		if err != nil {
+			failed = err
+			continue
+		}
+
+		// Item diklaim dulu pada pesanan agar tidak pernah dipotong dua kali
+		claimed, err := config.TransactionCollection.UpdateOne(ctx,
+			bson.M{"_id": id, "consumables_deducted": bson.M{"$ne": true}, "consumable_items": bson.M{"$ne": line.ItemID}},
+			bson.M{"$push": bson.M{"consumable_items": line.ItemID}},
+		)
+		if err != nil {
+			failed = err
+			continue
+		}
+		if claimed.ModifiedCount == 0 {
+			continue
+		}
+
+		var item models.Item
+		err = config.ItemCollection.FindOneAndUpdate(ctx,
+			bson.M{"_id": itemID},
+			bson.M{"$inc": bson.M{"quantity": -quantity}},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&item)
+		if err != nil {
+			log.Printf("Failed to deduct %s for transaction %s: %v", line.ItemName, transaction.ID, err)
+			// Klaim dilepas agar item ini dicoba lagi pada perubahan status berikutnya
+			config.TransactionCollection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$pull": bson.M{"consumable_items": line.ItemID}})
+			failed = err
+			continue
+		}
+		if item.Quantity < 0 {
+			log.Printf("Stock of %s is negative (%d) after transaction %s", item.ItemName, item.Quantity, transaction.ID)
+		}
+
+		movement := models.ItemTransaction{
+			ItemID:          line.ItemID,
+			ItemName:        item.ItemName,
+			Date:            now,
+			TransactionType: StockUsage,
+			TransactionID:   transaction.ID,
+			Quantity:        quantity,
+			StockAfter:      item.Quantity,
+		}
+		if _, err := config.ItemTransactionCollection.InsertOne(ctx, movement); err != nil {
+			log.Printf("Failed to write usage of %s for transaction %s: %v", item.ItemName, transaction.ID, err)
+		}
+	}
+	if failed != nil {
+		return failed
+	}
+
+	_, err = config.TransactionCollection.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"consumables_deducted": true}, "$unset": bson.M{"consumable_items": ""}},
+	)
+	return err
+}
+
+// UpdateServiceRecipe replaces the consumables recipe of a service:
+// {"recipe": [{"item_id": "...", "quantity_per_kg": 15}]}. An empty list removes the recipe.
+func UpdateServiceRecipe(w http.ResponseWriter, r *http.Request) {
+	serviceID := r.URL.Query().Get("id")
+	if serviceID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(serviceID)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		Recipe []models.RecipeLine `json:"recipe"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	seen := map[string]bool{}
+	recipe := []models.RecipeLine{}
+	for _, line := range request.Recipe {
+		if line.QuantityPerKg <= 0 {
+			http.Error(w, "Quantity per kg must be greater than zero", http.StatusBadRequest)
+			return
+		}
+		if seen[line.ItemID] {
+			http.Error(w, "Each item may appear only once in a recipe", http.StatusBadRequest)
+			return
+		}
+		seen[line.ItemID] = true
+
+		itemID, err := primitive.ObjectIDFromHex(line.ItemID)
+		if err != nil {
+			http.Error(w, "Invalid item ID", http.StatusBadRequest)
+			return
+		}
+		var item models.Item
+		if err := config.ItemCollection.FindOne(ctx, bson.M{"_id": itemID}).Decode(&item); err != nil {
+			http.Error(w, "Item "+line.ItemID+" not found", http.StatusNotFound)
+			return
+		}
+		line.ItemName = item.ItemName
+		recipe = append(recipe, line)
+	}
+
+	result, err := config.ServiceCollection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"recipe": recipe}})
+	if err != nil {
+		http.Error(w, "Failed to update recipe", http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, "Service not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Recipe updated successfully", "recipe": recipe})
+}
+
+// CountStock records a physical stock count: {"item_id": "...", "counted_quantity": 4200, "note": "..."}.
+// The difference with the system quantity is written as a Penyesuaian movement (negative when
+// stock went missing) and the item quantity is set to what was counted.
+func CountStock(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		ItemID          string `json:"item_id"`
+		CountedQuantity *int   `json:"counted_quantity"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.CountedQuantity == nil || *request.CountedQuantity < 0 {
+		http.Error(w, `{"error": "Item and counted quantity are required"}`, http.StatusBadRequest)
+		return
+	}
+
+	itemID, err := primitive.ObjectIDFromHex(request.ItemID)
+	if err != nil {
+		http.Error(w, `{"error": "Invalid item ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Tukar jumlah secara atomik agar selisih dihitung dari stok tepat sebelum penghitungan
+	var before models.Item
+	err = config.ItemCollection.FindOneAndUpdate(ctx,
+		bson.M{"_id": itemID},
+		bson.M{"$set": bson.M{"quantity": *request.CountedQuantity}},
+	).Decode(&before)
+	if err == mongo.ErrNoDocuments {
+		http.Error(w, `{"error": "Item not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error": "Failed to update stock"}`, http.StatusInternalServerError)
+		return
+	}
+
+	movement := models.ItemTransaction{
+		ItemID:          request.ItemID,
+		ItemName:        before.ItemName,
+		Date:            time.Now(),
+		TransactionType: StockAdjustment,
+		Quantity:        *request.CountedQuantity - before.Quantity,
+		StockAfter:      *request.CountedQuantity,
+	}
+	result, err := config.ItemTransactionCollection.InsertOne(ctx, movement)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to record stock count"}`, http.StatusInternalServerError)
+		return
+	}
+	movement.ID = result.InsertedID.(primitive.ObjectID).Hex()
+
+	response := map[string]interface{}{
+		"message":  "Stock count recorded successfully",
+		"movement": movement,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// consumptionRow compares recipe-based (expected) with actual usage of one item
+type consumptionRow struct {
+	ItemID          string  `json:"item_id" bson:"_id"`
+	ItemName        string  `json:"item_name" bson:"item_name"`
+	Expected        int     `json:"expected" bson:"expected"`         // Pemakaian otomatis dari resep, dikurangi pembatalan
+	ManualUsage     int     `json:"manual_usage" bson:"manual_usage"` // Pemakaian yang dicatat manual
+	Adjustment      int     `json:"adjustment" bson:"adjustment"`     // Selisih stock opname (negatif = hilang)
+	Actual          int     `json:"actual" bson:"-"`
+	Variance        int     `json:"variance" bson:"-"`
+	VariancePercent float64 `json:"variance_percent" bson:"-"`
+}
+
+// GetConsumptionReport compares, per item, the usage expected from service recipes with the actual
+// usage (expected + manual usage − stock count differences) for ?from=&to= (default this month)
+func GetConsumptionReport(w http.ResponseWriter, r *http.Request) {
+	dateFilter, err := dateRangeFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if dateFilter == nil {
+		now := time.Now().In(config.Location())
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		dateFilter = bson.M{"$gte": start, "$lt": start.AddDate(0, 1, 0)}
+	}
+
+	fromOrder := bson.M{"$gt": bson.A{bson.M{"$ifNull": bson.A{"$transaction_id", ""}}, ""}}
+	sumIf := func(condition interface{}, value interface{}) bson.M {
+		return bson.M{"$sum": bson.M{"$cond": bson.A{condition, value, 0}}}
+	}
+	isType := func(movementType string) bson.M {
+		return bson.M{"$eq": bson.A{"$transaction_type", movementType}}
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"date": dateFilter, "transaction_type": bson.M{"$in": bson.A{StockUsage, StockReversal, StockAdjustment}}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":       "$item_id",
+			"item_name": bson.M{"$last": "$item_name"},
+			"expected": bson.M{"$sum": bson.M{"$switch": bson.M{
+				"branches": bson.A{
+					bson.M{"case": bson.M{"$and": bson.A{isType(StockUsage), fromOrder}}, "then": "$quantity"},
+					bson.M{"case": bson.M{"$and": bson.A{isType(StockReversal), fromOrder}}, "then": bson.M{"$multiply": bson.A{"$quantity", -1}}},
+				},
+				"default": 0,
+			}}},
+			"manual_usage": sumIf(bson.M{"$and": bson.A{isType(StockUsage), bson.M{"$not": bson.A{fromOrder}}}}, "$quantity"),
+			"adjustment":   sumIf(isType(StockAdjustment), "$quantity"),
+		}}},
+		{{Key: "$sort", Value: bson.M{"item_name": 1}}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := config.ItemTransactionCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		http.Error(w, "Failed to build consumption report", http.StatusInternalServerError)
+		return
+	}
+	rows := []consumptionRow{}
+	if err := cursor.All(ctx, &rows); err != nil {
+		http.Error(w, "Failed to read consumption report", http.StatusInternalServerError)
+		return
+	}
+	for i := range rows {
+		row := &rows[i]
+		row.Actual = row.Expected + row.ManualUsage - row.Adjustment
+		row.Variance = row.Actual - row.Expected
+		if row.Expected > 0 {
+			row.VariancePercent = roundPercent(float64(row.Variance) / float64(row.Expected))
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rows)
+}
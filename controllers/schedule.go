@@ -0,0 +1,852 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"apkclaundry/config"
+	"apkclaundry/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Recurring schedule statuses and the state of a single occurrence in the calendar
+const (
+	ScheduleActive = "active"
+	SchedulePaused = "paused"
+
+	OccurrencePlanned   = "planned"
+	OccurrenceSkipped   = "skipped"
+	OccurrencePaused    = "paused"
+	OccurrenceGenerated = "generated"
+)
+
+// Fields of a recurring schedule that may be changed through UpdateSchedule, by JSON name
+var schedulePatchFields = []string{"address", "zone_id", "weekdays", "window_start", "window_end", "courier_id",
+	"service_type", "items", "express", "special_instructions", "end_date"}
+
+var errOccurrenceExists = errors.New("Occurrence was already generated")
+
+// parseDay parses a yyyy-mm-dd date as midnight in the business time zone
+func parseDay(day string) (time.Time, error) {
+	return time.ParseInLocation("2006-01-02", day, config.Location())
+}
+
+// clockOn returns the time of day "HH:MM" on the given day
+func clockOn(day time.Time, clock string) (time.Time, error) {
+	parsed, err := time.Parse("15:04", clock)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), parsed.Hour(), parsed.Minute(), 0, 0, day.Location()), nil
+}
+
+// occurrence returns the state of a schedule on a day: "" when it does not run that day,
+// OccurrenceSkipped or OccurrencePaused when it would but is held back, else OccurrencePlanned.
+// Dates are yyyy-mm-dd, so they compare as strings.
+func occurrence(schedule models.RecurringSchedule, day time.Time) string {
+	date := day.Format("2006-01-02")
+	if date < schedule.StartDate || (schedule.EndDate != "" && date > schedule.EndDate) {
+		return ""
+	}
+	runs := false
+	for _, weekday := range schedule.Weekdays {
+		if weekday == int(day.Weekday()) {
+			runs = true
+		}
+	}
+	if !runs {
+		return ""
+	}
+	for _, skip := range schedule.SkipDates {
+		if skip == date {
+			return OccurrenceSkipped
+		}
+	}
+	if schedule.Status == SchedulePaused && (schedule.PausedUntil == "" || date <= schedule.PausedUntil) {
+		return OccurrencePaused
+	}
+	return OccurrencePlanned
+}
+
+// validateSchedule checks the recurrence rule and pickup window of a schedule
+func validateSchedule(schedule models.RecurringSchedule) error {
+	if len(schedule.Weekdays) == 0 {
+		return errors.New("At least one weekday is required")
+	}
+	for _, weekday := range schedule.Weekdays {
+		if weekday < 0 || weekday > 6 {
+			return errors.New("Weekdays must be between 0 (Sunday) and 6 (Saturday)")
+		}
+	}
+	start, err := time.Parse("15:04", schedule.WindowStart)
+	if err != nil {
+		return errors.New("Invalid window_start, use HH:MM")
+	}
+	end, err := time.Parse("15:04", schedule.WindowEnd)
+	if err != nil || end.Before(start) {
+		return errors.New("Invalid window_end, use HH:MM after window_start")
+	}
+	if _, err := parseDay(schedule.StartDate); err != nil {
+		return errors.New("Invalid start_date, use yyyy-mm-dd")
+	}
+	if schedule.EndDate != "" {
+		if _, err := parseDay(schedule.EndDate); err != nil || schedule.EndDate < schedule.StartDate {
+			return errors.New("Invalid end_date, use yyyy-mm-dd on or after start_date")
+		}
+	}
+	return nil
+}
+
+// findSchedule loads a recurring schedule by its hex ID
+func findSchedule(ctx context.Context, scheduleID string) (models.RecurringSchedule, error) {
+	var schedule models.RecurringSchedule
+	id, err := primitive.ObjectIDFromHex(scheduleID)
+	if err != nil {
+		return schedule, err
+	}
+	err = config.ScheduleCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&schedule)
+	return schedule, err
+}
+
+// generateOccurrence creates the draft order and pickup job of a schedule for one day and returns
+// errOccurrenceExists on a second run. Drafts get no invoice number until they are confirmed, so
+// skipped occurrences leave no gaps in the numbering.
+func generateOccurrence(ctx context.Context, schedule models.RecurringSchedule, zone models.DeliveryZone, day time.Time) (models.Transaction, error) {
+	// Cek dulu agar run ulang tidak memesan limit akun untuk pesanan yang sudah ada; unique index
+	// pada schedule_id dan scheduled_for tetap menjaga dua run yang bersamaan
+	existing, err := config.TransactionCollection.CountDocuments(ctx, bson.M{"schedule_id": schedule.ID, "scheduled_for": day.Format("2006-01-02")})
+	if err != nil {
+		return models.Transaction{}, err
+	}
+	if existing > 0 {
+		return models.Transaction{}, errOccurrenceExists
+	}
+
+	windowStart, err := clockOn(day, schedule.WindowStart)
+	if err != nil {
+		return models.Transaction{}, err
+	}
+	windowEnd, err := clockOn(day, schedule.WindowEnd)
+	if err != nil {
+		return models.Transaction{}, err
+	}
+
+	transaction := models.Transaction{
+		CustomerID:          schedule.CustomerID,
+		CustomerName:        schedule.CustomerName,
+		PhoneNumber:         schedule.PhoneNumber,
+		HandledBy:           "scheduler",
+		ServiceType:         schedule.ServiceType,
+		Items:               schedule.Items,
+		Express:             schedule.Express,
+		SpecialInstructions: schedule.SpecialInstructions,
+		Status:              StatusDraft,
+		OutletCode:          config.GetEnv("OUTLET_CODE", ""),
+		ScheduleID:          schedule.ID,
+		ScheduledFor:        day.Format("2006-01-02"),
+		TransactionDate:     windowStart,
+	}
+	if customer, err := findCustomer(ctx, schedule.CustomerID); err == nil {
+		transaction.Preferences = customer.Preferences
+	}
+	// Tarif pajak ditetapkan sekarang agar ongkos jemput ikut dikenai pajak yang sama
+	if err := applyTax(ctx, &transaction); err != nil {
+		return transaction, err
+	}
+	// Pelanggan dengan akun korporat aktif ditagih bulanan; selain itu dibayar seperti biasa
+	releaseCredit, err := chargeToAccount(ctx, &transaction)
+	if err == nil {
+		transaction.PaymentMethod = PaymentAccount
+	}
+
+	result, err := config.TransactionCollection.InsertOne(ctx, transaction)
+	if err != nil {
+		releaseCredit()
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		return transaction, errOccurrenceExists
+	}
+	if err != nil {
+		return transaction, err
+	}
+	transaction.ID = result.InsertedID.(primitive.ObjectID).Hex()
+
+	now := time.Now()
+	job := models.DeliveryJob{
+		TransactionID: transaction.ID,
+		Type:          JobPickup,
+		CustomerName:  schedule.CustomerName,
+		PhoneNumber:   schedule.PhoneNumber,
+		Address:       schedule.Address,
+		ZoneID:        zone.ID,
+		ZoneName:      zone.Name,
+		Fee:           zone.Fee,
+		WindowStart:   windowStart,
+		WindowEnd:     windowEnd,
+		CourierID:     schedule.CourierID,
+		Status:        JobScheduled,
+		Notes:         "Jadwal jemput berulang",
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if job.CourierID != "" {
+		if courier, err := findCourier(ctx, job.CourierID); err == nil {
+			job.CourierName = courier.Username
+		} else {
+			job.CourierID = ""
+		}
+	}
+
+	// Tanpa job jemput, draft dihapus agar dibuat ulang pada run berikutnya
+	if _, err := config.DeliveryJobCollection.InsertOne(ctx, job); err != nil {
+		config.TransactionCollection.DeleteOne(ctx, bson.M{"_id": result.InsertedID})
+		return transaction, err
+	}
+	if err := adjustDeliveryFee(ctx, transaction.ID, job.Fee); err != nil {
+		log.Printf("Failed to add pickup fee to transaction %s: %v", transaction.ID, err)
+	}
+	return transaction, nil
+}
+
+// CreateSchedule handles the creation of a recurring pickup schedule:
+// {"customer_id": "...", "zone_id": "...", "weekdays": [1, 4], "window_start": "09:00",
+// "window_end": "11:00", "service_type": "Cuci Linen", "items": ["40 sprei", "80 handuk"]}
+func CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	var schedule models.RecurringSchedule
+	if err := json.NewDecoder(r.Body).Decode(&schedule); err != nil {
+		http.Error(w, `{"error": "Invalid input"}`, http.StatusBadRequest)
+		return
+	}
+	if schedule.StartDate == "" {
+		schedule.StartDate = time.Now().In(config.Location()).Format("2006-01-02")
+	}
+	if err := validateSchedule(schedule); err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+	if schedule.ServiceType == "" {
+		http.Error(w, `{"error": "Service type is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	customer, err := findCustomer(ctx, schedule.CustomerID)
+	if err != nil {
+		http.Error(w, `{"error": "Customer not found"}`, http.StatusNotFound)
+		return
+	}
+	zoneID, err := primitive.ObjectIDFromHex(schedule.ZoneID)
+	if err != nil {
+		http.Error(w, `{"error": "Invalid zone ID"}`, http.StatusBadRequest)
+		return
+	}
+	if err := config.DeliveryZoneCollection.FindOne(ctx, bson.M{"_id": zoneID}).Err(); err != nil {
+		http.Error(w, `{"error": "Zone not found"}`, http.StatusNotFound)
+		return
+	}
+	if schedule.CourierID != "" {
+		if _, err := findCourier(ctx, schedule.CourierID); err != nil {
+			http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	schedule.CustomerName = customer.Name
+	schedule.PhoneNumber = customer.Phone
+	if schedule.Address == "" {
+		schedule.Address = customer.Address
+	}
+	if schedule.Address == "" {
+		http.Error(w, `{"error": "Address is required"}`, http.StatusBadRequest)
+		return
+	}
+	schedule.Status = ScheduleActive
+	schedule.PausedUntil = ""
+	schedule.CreatedBy = r.Header.Get("Username")
+	schedule.CreatedAt = time.Now()
+	schedule.LastRunAt = nil
+
+	result, err := config.ScheduleCollection.InsertOne(ctx, schedule)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to create schedule"}`, http.StatusInternalServerError)
+		return
+	}
+	schedule.ID = result.InsertedID.(primitive.ObjectID).Hex()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(schedule)
+}
+
+// GetAllSchedules lists recurring schedules, filtered by ?customer_id= and ?status=
+func GetAllSchedules(w http.ResponseWriter, r *http.Request) {
+	filter := bson.M{}
+	if customerID := r.URL.Query().Get("customer_id"); customerID != "" {
+		filter["customer_id"] = customerID
+	}
+	if status := r.URL.Query().Get("status"); status != "" {
+		filter["status"] = status
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := config.ScheduleCollection.Find(ctx, filter)
+	if err != nil {
+		http.Error(w, "Failed to fetch schedules", http.StatusInternalServerError)
+		return
+	}
+	schedules := []models.RecurringSchedule{}
+	if err := cursor.All(ctx, &schedules); err != nil {
+		http.Error(w, "Failed to decode schedules", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schedules)
+}
+
+// GetScheduleByID retrieves a recurring schedule by its ID
+func GetScheduleByID(w http.ResponseWriter, r *http.Request) {
+	scheduleID := r.URL.Query().Get("id")
+	if scheduleID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	schedule, err := findSchedule(ctx, scheduleID)
+	if err != nil {
+		http.Error(w, "Schedule not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schedule)
+}
+
+// UpdateSchedule changes a recurring schedule with a JSON merge patch. Occurrences that were
+// already generated keep their draft order and pickup job.
+func UpdateSchedule(w http.ResponseWriter, r *http.Request) {
+	var schedule models.RecurringSchedule
+	// Jadwal hasil patch harus lolos pemeriksaan yang sama dengan CreateSchedule
+	validate := func(ctx context.Context, changes []models.FieldChange) error {
+		if err := validateSchedule(schedule); err != nil {
+			return err
+		}
+		if schedule.ServiceType == "" {
+			return errors.New("Service type is required")
+		}
+		if schedule.Address == "" {
+			return errors.New("Address is required")
+		}
+		for _, change := range changes {
+			switch change.Field {
+			case "zone_id":
+				zoneID, err := primitive.ObjectIDFromHex(schedule.ZoneID)
+				if err != nil {
+					return errors.New("Invalid zone ID")
+				}
+				if err := config.DeliveryZoneCollection.FindOne(ctx, bson.M{"_id": zoneID}).Err(); err != nil {
+					return errors.New("Zone not found")
+				}
+			case "courier_id":
+				if schedule.CourierID != "" {
+					if _, err := findCourier(ctx, schedule.CourierID); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return nil
+	}
+	if !patchDocument(w, r, config.ScheduleCollection, "schedule", &schedule, schedulePatchFields, validate) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Schedule updated successfully"})
+}
+
+// DeleteSchedule deletes a recurring schedule by its ID; generated orders are kept
+func DeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	scheduleID := r.URL.Query().Get("id")
+	if scheduleID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(scheduleID)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	result, err := config.ScheduleCollection.DeleteOne(context.TODO(), bson.M{"_id": id})
+	if err != nil {
+		http.Error(w, "Failed to delete schedule", http.StatusInternalServerError)
+		return
+	}
+	if result.DeletedCount == 0 {
+		http.Error(w, "Schedule not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Schedule deleted successfully"})
+}
+
+// PauseSchedule pauses a schedule, optionally until a date: {"until": "2026-11-30"}
+func PauseSchedule(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Until string `json:"until"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+	}
+	if request.Until != "" {
+		if _, err := parseDay(request.Until); err != nil {
+			http.Error(w, "Invalid until date, use yyyy-mm-dd", http.StatusBadRequest)
+			return
+		}
+	}
+	setScheduleStatus(w, r, bson.M{"$set": bson.M{"status": SchedulePaused, "paused_until": request.Until}}, "Schedule paused")
+}
+
+// ResumeSchedule resumes a paused schedule
+func ResumeSchedule(w http.ResponseWriter, r *http.Request) {
+	setScheduleStatus(w, r, bson.M{"$set": bson.M{"status": ScheduleActive}, "$unset": bson.M{"paused_until": ""}}, "Schedule resumed")
+}
+
+// setScheduleStatus applies a pause or resume update to the schedule in ?id=
+func setScheduleStatus(w http.ResponseWriter, r *http.Request, update bson.M, message string) {
+	id, err := primitive.ObjectIDFromHex(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	result, err := config.ScheduleCollection.UpdateOne(context.TODO(), bson.M{"_id": id}, update)
+	if err != nil {
+		http.Error(w, "Failed to update schedule", http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, "Schedule not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+// SkipScheduleDate skips one occurrence of a schedule, e.g. a public holiday at the hotel:
+// {"date": "2026-12-25"}, or {"date": "...", "restore": true} to undo. An occurrence that was
+// already generated is removed as long as the courier has not left for the pickup.
+func SkipScheduleDate(w http.ResponseWriter, r *http.Request) {
+	scheduleID := r.URL.Query().Get("id")
+	id, err := primitive.ObjectIDFromHex(scheduleID)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		Date    string `json:"date"`
+		Restore bool   `json:"restore"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if _, err := parseDay(request.Date); err != nil {
+		http.Error(w, "Invalid date, use yyyy-mm-dd", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	update := bson.M{"$addToSet": bson.M{"skip_dates": request.Date}}
+	if request.Restore {
+		update = bson.M{"$pull": bson.M{"skip_dates": request.Date}}
+	}
+	result, err := config.ScheduleCollection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		http.Error(w, "Failed to update schedule", http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, "Schedule not found", http.StatusNotFound)
+		return
+	}
+
+	response := map[string]interface{}{"message": "Date skipped"}
+	if request.Restore {
+		response["message"] = "Date restored; it will be generated on the next scheduler run"
+	} else {
+		removed, err := removeOccurrence(ctx, scheduleID, request.Date)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		response["removed_transaction_id"] = removed
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// removeOccurrence deletes the draft order and pickup job generated for a skipped date. It
+// returns the removed transaction ID, or "" when nothing had been generated yet.
+func removeOccurrence(ctx context.Context, scheduleID, date string) (string, error) {
+	var transaction models.Transaction
+	err := config.TransactionCollection.FindOne(ctx, bson.M{"schedule_id": scheduleID, "scheduled_for": date}).Decode(&transaction)
+	if err == mongo.ErrNoDocuments {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	// Draft yang sudah bernomor nota tidak dihapus agar urutan nota tidak berlubang
+	if transaction.Status != StatusDraft || transaction.InvoiceNumber != "" {
+		return "", errors.New("The order of this date was already confirmed")
+	}
+
+	started, err := config.DeliveryJobCollection.CountDocuments(ctx, bson.M{"transaction_id": transaction.ID, "status": bson.M{"$ne": JobScheduled}})
+	if err != nil {
+		return "", err
+	}
+	if started > 0 {
+		return "", errors.New("The courier is already on the way for this pickup")
+	}
+
+	if _, err := config.DeliveryJobCollection.DeleteMany(ctx, bson.M{"transaction_id": transaction.ID, "status": JobScheduled}); err != nil {
+		return "", err
+	}
+	id, _ := primitive.ObjectIDFromHex(transaction.ID)
+	result, err := config.TransactionCollection.DeleteOne(ctx, bson.M{"_id": id, "status": StatusDraft, "invoice_number": bson.M{"$exists": false}})
+	if err != nil {
+		return "", err
+	}
+	if result.DeletedCount > 0 {
+		syncAccountOutstanding(ctx, transaction, models.Transaction{AccountID: transaction.AccountID})
+	}
+	return transaction.ID, nil
+}
+
+// RunSchedules generates the draft orders and pickup jobs of all schedules for today and the
+// next SCHEDULE_LEAD_DAYS days (default 1), so couriers see tomorrow's pickups in advance.
+// It is called by a cron job and is safe to re-run.
+func RunSchedules(w http.ResponseWriter, r *http.Request) {
+	leadDays := config.GetEnvInt("SCHEDULE_LEAD_DAYS", 1)
+	today, _ := todayRange(time.Now())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Second)
+	defer cancel()
+
+	cursor, err := config.ScheduleCollection.Find(ctx, bson.M{"status": bson.M{"$in": bson.A{ScheduleActive, SchedulePaused}}})
+	if err != nil {
+		http.Error(w, "Failed to fetch schedules", http.StatusInternalServerError)
+		return
+	}
+	var schedules []models.RecurringSchedule
+	if err := cursor.All(ctx, &schedules); err != nil {
+		http.Error(w, "Failed to read schedule data", http.StatusInternalServerError)
+		return
+	}
+
+	summary := map[string]int{"schedules": len(schedules), "created": 0, "existing": 0, "failed": 0}
+	for _, schedule := range schedules {
+		if ctx.Err() != nil {
+			break
+		}
+
+		var zone models.DeliveryZone
+		zoneID, _ := primitive.ObjectIDFromHex(schedule.ZoneID)
+		if err := config.DeliveryZoneCollection.FindOne(ctx, bson.M{"_id": zoneID}).Decode(&zone); err != nil {
+			log.Printf("Schedule %s has no valid zone: %v", schedule.ID, err)
+			summary["failed"]++
+			continue
+		}
+
+		for offset := 0; offset <= leadDays; offset++ {
+			day := today.AddDate(0, 0, offset)
+			if occurrence(schedule, day) != OccurrencePlanned {
+				continue
+			}
+			_, err := generateOccurrence(ctx, schedule, zone, day)
+			switch err {
+			case nil:
+				summary["created"]++
+			case errOccurrenceExists:
+				summary["existing"]++
+			default:
+				log.Printf("Failed to generate schedule %s for %s: %v", schedule.ID, day.Format("2006-01-02"), err)
+				summary["failed"]++
+			}
+		}
+
+		id, _ := primitive.ObjectIDFromHex(schedule.ID)
+		config.ScheduleCollection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"last_run_at": time.Now()}})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
+
+// GetScheduleCalendar lists the occurrences of all schedules between ?from= and ?to=
+// (yyyy-mm-dd, default the next 14 days, at most 62 days), optionally for one ?customer_id=
+func GetScheduleCalendar(w http.ResponseWriter, r *http.Request) {
+	from, _ := todayRange(time.Now())
+	if value := r.URL.Query().Get("from"); value != "" {
+		day, err := parseDay(value)
+		if err != nil {
+			http.Error(w, "Invalid from date, use yyyy-mm-dd", http.StatusBadRequest)
+			return
+		}
+		from = day
+	}
+	to := from.AddDate(0, 0, 13)
+	if value := r.URL.Query().Get("to"); value != "" {
+		day, err := parseDay(value)
+		if err != nil {
+			http.Error(w, "Invalid to date, use yyyy-mm-dd", http.StatusBadRequest)
+			return
+		}
+		to = day
+	}
+	if to.Before(from) || to.Sub(from) > 62*24*time.Hour {
+		http.Error(w, "The calendar covers at most 62 days", http.StatusBadRequest)
+		return
+	}
+
+	filter := bson.M{}
+	if customerID := r.URL.Query().Get("customer_id"); customerID != "" {
+		filter["customer_id"] = customerID
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := config.ScheduleCollection.Find(ctx, filter)
+	if err != nil {
+		http.Error(w, "Failed to fetch schedules", http.StatusInternalServerError)
+		return
+	}
+	var schedules []models.RecurringSchedule
+	if err := cursor.All(ctx, &schedules); err != nil {
+		http.Error(w, "Failed to decode schedules", http.StatusInternalServerError)
+		return
+	}
+
+	// Pesanan yang sudah dibuat scheduler, per jadwal dan tanggal
+	generated := map[string]models.Transaction{}
+	scheduleIDs := bson.A{}
+	for _, schedule := range schedules {
+		scheduleIDs = append(scheduleIDs, schedule.ID)
+	}
+	cursor, err = config.TransactionCollection.Find(ctx, bson.M{
+		"schedule_id":   bson.M{"$in": scheduleIDs},
+		"scheduled_for": bson.M{"$gte": from.Format("2006-01-02"), "$lte": to.Format("2006-01-02")},
+	})
+	if err != nil {
+		http.Error(w, "Failed to fetch generated orders", http.StatusInternalServerError)
+		return
+	}
+	var transactions []models.Transaction
+	if err := cursor.All(ctx, &transactions); err != nil {
+		http.Error(w, "Failed to decode generated orders", http.StatusInternalServerError)
+		return
+	}
+	for _, transaction := range transactions {
+		generated[transaction.ScheduleID+"|"+transaction.ScheduledFor] = transaction
+	}
+
+	type entry struct {
+		Date              string   `json:"date"`
+		ScheduleID        string   `json:"schedule_id"`
+		CustomerName      string   `json:"customer_name"`
+		ServiceType       string   `json:"service_type"`
+		Items             []string `json:"items,omitempty"`
+		WindowStart       string   `json:"window_start"`
+		WindowEnd         string   `json:"window_end"`
+		State             string   `json:"state"` // planned, skipped, paused atau generated
+		TransactionID     string   `json:"transaction_id,omitempty"`
+		InvoiceNumber     string   `json:"invoice_number,omitempty"`
+		TransactionStatus string   `json:"transaction_status,omitempty"`
+	}
+	entries := []entry{}
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		for _, schedule := range schedules {
+			transaction, ok := generated[schedule.ID+"|"+date]
+			state := occurrence(schedule, day)
+			if state == "" && !ok {
+				continue
+			}
+			item := entry{
+				Date:         date,
+				ScheduleID:   schedule.ID,
+				CustomerName: schedule.CustomerName,
+				ServiceType:  schedule.ServiceType,
+				Items:        schedule.Items,
+				WindowStart:  schedule.WindowStart,
+				WindowEnd:    schedule.WindowEnd,
+				State:        state,
+			}
+			if ok {
+				item.State = OccurrenceGenerated
+				item.TransactionID = transaction.ID
+				item.InvoiceNumber = transaction.InvoiceNumber
+				item.TransactionStatus = transaction.Status
+			}
+			entries = append(entries, item)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Date != entries[j].Date {
+			return entries[i].Date < entries[j].Date
+		}
+		return entries[i].WindowStart < entries[j].WindowStart
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// ConfirmScheduledOrder turns a draft order into a received order once the linen has been picked
+// up and weighed: {"weight_per_kg": 12.5, "pieces": 120}. The price follows the service's price per
+// kg unless "total_price" is given; the pickup fee already on the draft is kept. The order gets its
+// invoice number here.
+func ConfirmScheduledOrder(w http.ResponseWriter, r *http.Request) {
+	transactionID := r.URL.Query().Get("id")
+	if transactionID == "" {
+		http.Error(w, `{"error": "ID not provided"}`, http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		WeightPerKg float64 `json:"weight_per_kg"`
+		Pieces      int     `json:"pieces"`
+		TotalPrice  float64 `json:"total_price"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.WeightPerKg <= 0 || request.Pieces < 0 || request.TotalPrice < 0 {
+		http.Error(w, `{"error": "Invalid input, weight_per_kg is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	transaction, err := findTransaction(ctx, transactionID)
+	if err != nil {
+		http.Error(w, `{"error": "Transaction not found"}`, http.StatusNotFound)
+		return
+	}
+	if transaction.Status != StatusDraft {
+		http.Error(w, `{"error": "Only draft orders can be confirmed"}`, http.StatusConflict)
+		return
+	}
+
+	price := request.TotalPrice
+	if price == 0 {
+		service, err := findServiceByName(ctx, transaction.ServiceType)
+		if err != nil {
+			http.Error(w, `{"error": "Service has no price, provide total_price"}`, http.StatusUnprocessableEntity)
+			return
+		}
+		price = math.Round(service.PricePerKg * request.WeightPerKg)
+	}
+
+	// Pajak layanan mengikuti tarif yang ditetapkan saat draft dibuat
+	base, tax := splitTax(price, transaction.TaxRate, transaction.TaxInclusive)
+	charged := price
+	if !transaction.TaxInclusive {
+		charged += tax
+	}
+	taxLines := append([]models.TaxLine(nil), transaction.TaxLines...)
+	for i := range taxLines {
+		if taxLines[i].Description == transaction.ServiceType {
+			taxLines[i] = models.TaxLine{Description: transaction.ServiceType, Amount: price, Base: base, Tax: tax}
+			break
+		}
+	}
+
+	transaction.WeightPerKg = request.WeightPerKg
+	set := bson.M{
+		"status":        StatusReceived,
+		"weight_per_kg": request.WeightPerKg,
+		"pieces":        request.Pieces,
+		"subtotal":      price,
+		"tax_lines":     taxLines,
+		"handled_by_id": r.Header.Get("User-ID"),
+		"handled_by":    r.Header.Get("Username"),
+	}
+	if estimate, err := estimateReadyAt(ctx, transaction, time.Now()); err == nil {
+		set["estimated_ready_at"] = estimate
+	}
+
+	// Harga layanan pesanan korporat harus muat dalam sisa limit akun
+	releaseCredit := func() {}
+	if transaction.AccountID != "" {
+		if err := reserveAccountCredit(ctx, transaction.AccountID, charged); err != nil {
+			http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusUnprocessableEntity)
+			return
+		}
+		releaseCredit = func() { adjustAccountOutstanding(ctx, transaction.AccountID, -charged) }
+	}
+
+	id, _ := primitive.ObjectIDFromHex(transaction.ID)
+	result, err := config.TransactionCollection.UpdateOne(ctx,
+		bson.M{"_id": id, "status": StatusDraft},
+		bson.M{"$set": set, "$inc": bson.M{"total_price": charged, "tax_base": base, "tax_amount": tax}},
+	)
+	if err != nil {
+		releaseCredit()
+		http.Error(w, `{"error": "Failed to confirm order"}`, http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		releaseCredit()
+		http.Error(w, `{"error": "Order was confirmed by someone else"}`, http.StatusConflict)
+		return
+	}
+
+	// Nomor nota baru diambil setelah konfirmasi berhasil agar urutannya tidak berlubang
+	if invoiceNumber, err := nextInvoiceNumber(ctx, transaction.OutletCode); err != nil {
+		log.Printf("Failed to number confirmed order %s: %v", transaction.ID, err)
+	} else {
+		config.TransactionCollection.UpdateOne(ctx,
+			bson.M{"_id": id, "invoice_number": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"invoice_number": invoiceNumber}},
+		)
+		config.DeliveryJobCollection.UpdateMany(ctx,
+			bson.M{"transaction_id": transaction.ID},
+			bson.M{"$set": bson.M{"invoice_number": invoiceNumber}},
+		)
+	}
+
+	transaction, _ = findTransaction(ctx, transaction.ID)
+	notifyTransaction(transaction, EventReceived)
+	transaction.TransactionDateFormatted = formatDate(transaction.TransactionDate)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transaction)
+}
@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"apkclaundry/config"
+	"apkclaundry/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// validateService checks the fields of a service sent by the client
+func validateService(service models.Service) string {
+	if service.Name == "" {
+		return "Service name is required"
+	}
+	if service.PricePerKg < 0 || service.TurnaroundHours <= 0 || service.ExpressTurnaroundHours < 0 || service.WashTemperature < 0 {
+		return "Invalid price or turnaround"
+	}
+	if service.TaxRate != nil && (*service.TaxRate < 0 || *service.TaxRate > 100) {
+		return "Invalid tax rate"
+	}
+	return ""
+}
+
+// CreateService handles the creation of a new laundry service
+func CreateService(w http.ResponseWriter, r *http.Request) {
+	var service models.Service
+	if err := json.NewDecoder(r.Body).Decode(&service); err != nil {
+		http.Error(w, `{"error": "Invalid input"}`, http.StatusBadRequest)
+		return
+	}
+
+	if msg := validateService(service); msg != "" {
+		http.Error(w, `{"error": "`+msg+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := findServiceByName(ctx, service.Name); err == nil {
+		http.Error(w, `{"error": "Service already exists"}`, http.StatusConflict)
+		return
+	}
+
+	result, err := config.ServiceCollection.InsertOne(ctx, service)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to create service"}`, http.StatusInternalServerError)
+		return
+	}
+
+	service.ID = result.InsertedID.(primitive.ObjectID).Hex()
+
+	response := map[string]interface{}{
+		"message": "Service created successfully",
+		"service": service,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetAllServices retrieves all laundry services from the database
+func GetAllServices(w http.ResponseWriter, r *http.Request) {
+	cursor, err := config.ServiceCollection.Find(context.TODO(), bson.M{})
+	if err != nil {
+		http.Error(w, "Failed to fetch services", http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(context.TODO())
+
+	var services []models.Service
+	for cursor.Next(context.TODO()) {
+		var service models.Service
+		if err := cursor.Decode(&service); err != nil {
+			http.Error(w, "Failed to read service data", http.StatusInternalServerError)
+			return
+		}
+		services = append(services, service)
+	}
+
+	if len(services) == 0 {
+		http.Error(w, "No services found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(services)
+}
+
+// GetServiceByID retrieves a laundry service by its ID
+func GetServiceByID(w http.ResponseWriter, r *http.Request) {
+	serviceID := r.URL.Query().Get("id")
+	if serviceID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(serviceID)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var service models.Service
+	err = config.ServiceCollection.FindOne(context.TODO(), bson.M{"_id": id}).Decode(&service)
+	if err != nil {
+		http.Error(w, "Service not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(service)
+}
+
+// UpdateService updates a laundry service by its ID
+func UpdateService(w http.ResponseWriter, r *http.Request) {
+	serviceID := r.URL.Query().Get("id")
+	if serviceID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(serviceID)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var updatedService models.Service
+	if err := json.NewDecoder(r.Body).Decode(&updatedService); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	if msg := validateService(updatedService); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"name":                     updatedService.Name,
+			"price_per_kg":             updatedService.PricePerKg,
+			"turnaround_hours":         updatedService.TurnaroundHours,
+			"express_turnaround_hours": updatedService.ExpressTurnaroundHours,
+			"wash_temperature":         updatedService.WashTemperature,
+			"tax_rate":                 updatedService.TaxRate,
+		},
+	}
+
+	result, err := config.ServiceCollection.UpdateOne(context.TODO(), bson.M{"_id": id}, update)
+	if err != nil {
+		http.Error(w, "Failed to update service", http.StatusInternalServerError)
+		return
+	}
+
+	if result.MatchedCount == 0 {
+		http.Error(w, "Service not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Service updated successfully"})
+}
+
+// DeleteService deletes a laundry service by its ID
+func DeleteService(w http.ResponseWriter, r *http.Request) {
+	serviceID := r.URL.Query().Get("id")
+	if serviceID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(serviceID)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	result, err := config.ServiceCollection.DeleteOne(context.TODO(), bson.M{"_id": id})
+	if err != nil {
+		http.Error(w, "Failed to delete service", http.StatusInternalServerError)
+		return
+	}
+
+	if result.DeletedCount == 0 {
+		http.Error(w, "Service not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Service deleted successfully"})
+}
@@ -0,0 +1,389 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"apkclaundry/config"
+	"apkclaundry/models"
+	"apkclaundry/receipt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	ShiftOpen   = "open"
+	ShiftClosed = "closed"
+
+	CashSale = "sale"
+	CashIn   = "cash_in"
+	CashOut  = "cash_out"
+)
+
+var errNoOpenShift = errors.New("no open shift, please open a shift first")
+
+// isCashMethod reports whether a payment method puts money in the drawer
+func isCashMethod(method string) bool {
+	method = strings.ToLower(strings.TrimSpace(method))
+	return method == "cash" || method == "tunai"
+}
+
+// findOpenShift returns the open shift of a cashier
+func findOpenShift(ctx context.Context, cashierID string) (models.Shift, error) {
+	var shift models.Shift
+	err := config.ShiftCollection.FindOne(ctx, bson.M{"cashier_id": cashierID, "status": ShiftOpen}).Decode(&shift)
+	if err == mongo.ErrNoDocuments {
+		return shift, errNoOpenShift
+	}
+	return shift, err
+}
+
+// addCashMovement writes a movement into the cashier's open shift
+func addCashMovement(ctx context.Context, cashierID string, movement models.CashMovement) error {
+	shift, err := findOpenShift(ctx, cashierID)
+	if err != nil {
+		return err
+	}
+	movement.ShiftID = shift.ID
+	movement.CreatedBy = cashierID
+	movement.CreatedAt = time.Now()
+	_, err = config.CashMovementCollection.InsertOne(ctx, movement)
+	return err
+}
+
+// recordCashSale adds a cash payment to the cashier's open shift. Payments taken without an
+// open shift are still valid, they just do not show up in any drawer count.
+func recordCashSale(ctx context.Context, cashierID string, transaction models.Transaction, amount float64) {
+	if amount <= 0 || cashierID == "" {
+		return
+	}
+	err := addCashMovement(ctx, cashierID, models.CashMovement{
+		Type:          CashSale,
+		Amount:        amount,
+		Description:   "Pembayaran nota " + transaction.InvoiceNumber,
+		TransactionID: transaction.ID,
+	})
+	if err != nil && err != errNoOpenShift {
+		log.Printf("Failed to record cash sale for transaction %s: %v", transaction.ID, err)
+	}
+}
+
+// shiftTotals sums the movements of a shift and fills in the expected cash
+func shiftTotals(ctx context.Context, shift *models.Shift) ([]models.CashMovement, error) {
+	cursor, err := config.CashMovementCollection.Find(ctx, bson.M{"shift_id": shift.ID},
+		options.Find().SetSort(bson.M{"created_at": 1}))
+	if err != nil {
+		return nil, err
+	}
+	movements := []models.CashMovement{}
+	if err := cursor.All(ctx, &movements); err != nil {
+		return nil, err
+	}
+
+	shift.CashSales, shift.CashIn, shift.CashOut = 0, 0, 0
+	for _, movement := range movements {
+		switch movement.Type {
+		case CashSale:
+			shift.CashSales += movement.Amount
+		case CashIn:
+			shift.CashIn += movement.Amount
+		case CashOut:
+			shift.CashOut += movement.Amount
+		}
+	}
+	shift.ExpectedCash = shift.OpeningFloat + shift.CashSales + shift.CashIn - shift.CashOut
+	return movements, nil
+}
+
+// OpenShift starts a shift for the logged-in cashier with the cash already in the drawer
+func OpenShift(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		OpeningFloat float64 `json:"opening_float"`
+		Notes        string  `json:"notes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, `{"error": "Invalid input"}`, http.StatusBadRequest)
+		return
+	}
+	if request.OpeningFloat < 0 {
+		http.Error(w, `{"error": "Opening float cannot be negative"}`, http.StatusBadRequest)
+		return
+	}
+
+	shift := models.Shift{
+		CashierID:    r.Header.Get("User-ID"),
+		CashierName:  r.Header.Get("Username"),
+		OutletCode:   config.GetEnv("OUTLET_CODE", ""),
+		Status:       ShiftOpen,
+		OpeningFloat: request.OpeningFloat,
+		OpenedAt:     time.Now(),
+		Notes:        request.Notes,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := config.ShiftCollection.InsertOne(ctx, shift)
+	if mongo.IsDuplicateKeyError(err) {
+		http.Error(w, `{"error": "You already have an open shift"}`, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error": "Failed to open shift"}`, http.StatusInternalServerError)
+		return
+	}
+	shift.ID = result.InsertedID.(primitive.ObjectID).Hex()
+	shift.ExpectedCash = shift.OpeningFloat
+
+	response := map[string]interface{}{
+		"message": "Shift opened successfully",
+		"shift":   shift,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetCurrentShift returns the logged-in cashier's open shift with running totals
+func GetCurrentShift(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	shift, err := findOpenShift(ctx, r.Header.Get("User-ID"))
+	if err == errNoOpenShift {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to fetch shift", http.StatusInternalServerError)
+		return
+	}
+
+	movements, err := shiftTotals(ctx, &shift)
+	if err != nil {
+		http.Error(w, "Failed to read cash movements", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"shift": shift, "movements": movements})
+}
+
+// AddCashMovement records cash put into (cash_in) or taken out of (cash_out) the drawer,
+// e.g. {"type": "cash_out", "amount": 20000, "description": "Beli deterjen"}
+func AddCashMovement(w http.ResponseWriter, r *http.Request) {
+	var movement models.CashMovement
+	if err := json.NewDecoder(r.Body).Decode(&movement); err != nil {
+		http.Error(w, `{"error": "Invalid input"}`, http.StatusBadRequest)
+		return
+	}
+	if movement.Type != CashIn && movement.Type != CashOut {
+		http.Error(w, `{"error": "Type must be cash_in or cash_out"}`, http.StatusBadRequest)
+		return
+	}
+	if movement.Amount <= 0 {
+		http.Error(w, `{"error": "Amount must be greater than zero"}`, http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(movement.Description) == "" {
+		http.Error(w, `{"error": "Description is required"}`, http.StatusBadRequest)
+		return
+	}
+	movement.TransactionID = ""
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := addCashMovement(ctx, r.Header.Get("User-ID"), movement)
+	if err == errNoOpenShift {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error": "Failed to record cash movement"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Cash movement recorded successfully"})
+}
+
+// CloseShift closes the logged-in cashier's shift with the cash counted in the drawer and
+// stores the expected amount and the variance
+func CloseShift(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		CountedCash *float64 `json:"counted_cash"`
+		Notes       string   `json:"notes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if request.CountedCash == nil || *request.CountedCash < 0 {
+		http.Error(w, "Counted cash is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	shift, err := findOpenShift(ctx, r.Header.Get("User-ID"))
+	if err == errNoOpenShift {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to fetch shift", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := shiftTotals(ctx, &shift); err != nil {
+		http.Error(w, "Failed to read cash movements", http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	shift.Status = ShiftClosed
+	shift.ClosedAt = &now
+	shift.CountedCash = *request.CountedCash
+	shift.Variance = math.Round(shift.CountedCash - shift.ExpectedCash)
+	if request.Notes != "" {
+		shift.Notes = strings.TrimSpace(shift.Notes + "\n" + request.Notes)
+	}
+
+	id, _ := primitive.ObjectIDFromHex(shift.ID)
+	result, err := config.ShiftCollection.UpdateOne(ctx, bson.M{"_id": id, "status": ShiftOpen}, bson.M{"$set": bson.M{
+		"status":        shift.Status,
+		"closed_at":     now,
+		"cash_sales":    shift.CashSales,
+		"cash_in":       shift.CashIn,
+		"cash_out":      shift.CashOut,
+		"expected_cash": shift.ExpectedCash,
+		"counted_cash":  shift.CountedCash,
+		"variance":      shift.Variance,
+		"notes":         shift.Notes,
+	}})
+	if err != nil {
+		http.Error(w, "Failed to close shift", http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, "Shift was already closed", http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Shift closed successfully", "shift": shift})
+}
+
+// GetAllShifts lists shifts, newest first, optionally filtered by ?from=&to= and ?cashier_id=
+func GetAllShifts(w http.ResponseWriter, r *http.Request) {
+	filter := bson.M{}
+	dateFilter, err := dateRangeFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if dateFilter != nil {
+		filter["opened_at"] = dateFilter
+	}
+	if cashierID := r.URL.Query().Get("cashier_id"); cashierID != "" {
+		filter["cashier_id"] = cashierID
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := config.ShiftCollection.Find(ctx, filter, options.Find().SetSort(bson.M{"opened_at": -1}))
+	if err != nil {
+		http.Error(w, "Failed to fetch shifts", http.StatusInternalServerError)
+		return
+	}
+	shifts := []models.Shift{}
+	if err := cursor.All(ctx, &shifts); err != nil {
+		http.Error(w, "Failed to read shift data", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(shifts)
+}
+
+// GetShiftReport renders a shift report as JSON (default), PDF (?format=pdf) or
+// ESC/POS (?format=escpos&width=58|80). Open shifts are shown with their running totals.
+func GetShiftReport(w http.ResponseWriter, r *http.Request) {
+	shiftID := r.URL.Query().Get("id")
+	if shiftID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(shiftID)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	paperWidth := receipt.Paper58mm
+	if width := r.URL.Query().Get("width"); width != "" {
+		paperWidth, err = strconv.Atoi(width)
+		if err != nil || (paperWidth != receipt.Paper58mm && paperWidth != receipt.Paper80mm) {
+			http.Error(w, "Invalid width, use 58 or 80", http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var shift models.Shift
+	if err := config.ShiftCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&shift); err != nil {
+		http.Error(w, "Shift not found", http.StatusNotFound)
+		return
+	}
+	// Kasir hanya boleh melihat shift miliknya sendiri
+	if r.Header.Get("Role") != "admin" && shift.CashierID != r.Header.Get("User-ID") {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	movements, err := shiftTotals(ctx, &shift)
+	if err != nil {
+		http.Error(w, "Failed to read cash movements", http.StatusInternalServerError)
+		return
+	}
+
+	report := receipt.ShiftReport{Business: config.Business(), Shift: shift, Movements: movements}
+
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"shift": shift, "movements": movements})
+	case "escpos":
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", `attachment; filename="shift-`+shift.ID+`.bin"`)
+		w.Write(report.ESCPOS(paperWidth))
+	case "pdf":
+		var pdf bytes.Buffer
+		if err := report.WritePDF(&pdf); err != nil {
+			http.Error(w, "Failed to render report", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", `inline; filename="shift-`+shift.ID+`.pdf"`)
+		w.Write(pdf.Bytes())
+	default:
+		http.Error(w, "Invalid format, use json, pdf or escpos", http.StatusBadRequest)
+	}
+}
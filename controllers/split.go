@@ -0,0 +1,397 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"apkclaundry/config"
+	"apkclaundry/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// splitSuffix returns the invoice suffix of the n-th child order: A, B, ... Z, then 27, 28, ...
+func splitSuffix(n int) string {
+	if n >= 1 && n <= 26 {
+		return string(rune('A' + n - 1))
+	}
+	return strconv.Itoa(n)
+}
+
+// splittable reports why an order cannot be split, or "" when it can
+func splittable(transaction models.Transaction) string {
+	switch {
+	case transaction.Status == StatusPickedUp || transaction.Status == StatusCancelled || transaction.Status == StatusAbandoned:
+		return "Order is already " + transaction.Status
+	case transaction.Status == StatusDraft:
+		return "Confirm the draft order first"
+	case transaction.Cancellation != nil && transaction.Cancellation.Status == "pending_approval":
+		return "Order has a pending cancellation request"
+	case transaction.LoadID != "":
+		return "Remove the order from its wash load first"
+	case transaction.PickedUpPieces > 0:
+		return "Order already has partial pickups"
+	}
+	return ""
+}
+
+// splitPart is the share of an order's price, tax and payment that moves to a child order
+type splitPart struct {
+	Total    float64 // Harga total pesanan anak, termasuk pajak
+	Subtotal float64 // Harga layanan seperti yang dicatat di baris pajak: termasuk pajak bila harga inklusif
+	Base     float64
+	Tax      float64
+	Paid     float64
+}
+
+// servicePrice returns the service price of an order including tax, and the tax on it, read from
+// the service tax line. Delivery and storage fees are left out; without a tax line the order is
+// untaxed.
+func servicePrice(transaction models.Transaction) (total, tax float64) {
+	total = transaction.TotalPrice - transaction.DeliveryFee - transaction.StorageFee
+	for _, line := range transaction.TaxLines {
+		if line.Description == transaction.ServiceType {
+			total, tax = line.Amount, line.Tax
+			if !transaction.TaxInclusive {
+				total += line.Tax
+			}
+			break
+		}
+	}
+	return total, tax
+}
+
+// splitPrice computes the part of an order that moves to a child order taking share (between 0
+// and 1) of the service price. Payments stay on the parent until it is fully paid; only what was
+// paid beyond the parent's new total moves to the child.
+func splitPrice(parent models.Transaction, serviceTotal, serviceTax, share float64) splitPart {
+	var part splitPart
+	part.Total = math.Round(serviceTotal * share)
+	part.Tax = math.Round(serviceTax * part.Total / serviceTotal)
+	part.Base = part.Total - part.Tax
+	part.Subtotal = part.Total
+	if !parent.TaxInclusive {
+		part.Subtotal = part.Base
+	}
+	part.Paid = math.Min(part.Total, math.Max(0, parent.AmountPaid-(parent.TotalPrice-part.Total)))
+	return part
+}
+
+// SplitTransaction moves part of an order into a new child order with its own status and balance:
+// {"weight_per_kg": 3, "pieces": 1, "items": ["1 bedcover"], "estimated_ready_at": "..."}.
+// The price is shared by weight (or pieces), unless "total_price" is given. Delivery and storage
+// fees stay on the parent; whatever was paid beyond the parent's new total moves to the child.
+func SplitTransaction(w http.ResponseWriter, r *http.Request) {
+	transactionID := r.URL.Query().Get("id")
+	if transactionID == "" {
+		http.Error(w, `{"error": "ID not provided"}`, http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		WeightPerKg      float64    `json:"weight_per_kg"`
+		Pieces           int        `json:"pieces"`
+		Items            []string   `json:"items"`
+		TotalPrice       float64    `json:"total_price"`
+		EstimatedReadyAt *time.Time `json:"estimated_ready_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, `{"error": "Invalid input"}`, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	parent, err := findTransaction(ctx, transactionID)
+	if err != nil {
+		http.Error(w, `{"error": "Transaction not found"}`, http.StatusNotFound)
+		return
+	}
+	if reason := splittable(parent); reason != "" {
+		http.Error(w, `{"error": "`+reason+`"}`, http.StatusConflict)
+		return
+	}
+
+	// Bagian yang dipindah harus lebih kecil dari pesanan induk, induk tetap menyisakan barang
+	if request.WeightPerKg < 0 || request.Pieces < 0 || request.TotalPrice < 0 ||
+		(parent.WeightPerKg > 0 && request.WeightPerKg >= parent.WeightPerKg) ||
+		(parent.Pieces > 0 && request.Pieces >= parent.Pieces) {
+		http.Error(w, `{"error": "The child order must be smaller than the order it is split from"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Bagian harga layanan saja; ongkos antar-jemput, biaya simpan dan pajaknya tetap di induk
+	serviceTotal, serviceTax := servicePrice(parent)
+	var share float64
+	switch {
+	case request.TotalPrice > 0:
+		share = request.TotalPrice / serviceTotal
+	case request.WeightPerKg > 0 && parent.WeightPerKg > 0:
+		share = request.WeightPerKg / parent.WeightPerKg
+	case request.Pieces > 0 && parent.Pieces > 0:
+		share = float64(request.Pieces) / float64(parent.Pieces)
+	default:
+		http.Error(w, `{"error": "Provide the weight_per_kg, pieces or total_price of the child order"}`, http.StatusBadRequest)
+		return
+	}
+	if serviceTotal <= 0 || share <= 0 || share >= 1 {
+		http.Error(w, `{"error": "The child order must cost less than the order it is split from"}`, http.StatusBadRequest)
+		return
+	}
+
+	part := splitPrice(parent, serviceTotal, serviceTax, share)
+
+	child := parent
+	child.ID = ""
+	child.ParentID = parent.ID
+	child.SplitCount = 0
+	child.WeightPerKg = request.WeightPerKg
+	child.Pieces = request.Pieces
+	child.Items = request.Items
+	child.Subtotal = part.Subtotal
+	child.DeliveryFee = 0
+	child.StorageFee = 0
+	child.StorageFeeDays = 0
+	child.Discounts = nil
+	child.TotalPrice = part.Total
+	child.AmountPaid = part.Paid
+	child.TaxBase = part.Base
+	child.TaxAmount = part.Tax
+	child.TaxLines = nil
+	if part.Tax != 0 {
+		child.TaxLines = []models.TaxLine{{Description: parent.ServiceType, Amount: part.Subtotal, Base: part.Base, Tax: part.Tax}}
+	}
+	child.PointsRedeemed = 0
+	child.PointsEarned = 0
+	child.PackageDebits = nil
+	child.PaymentRefs = nil
+	child.ReceiptPrintCount = 0
+	child.RemindersSent = 0
+	child.Pickups = nil
+	if request.EstimatedReadyAt != nil {
+		child.EstimatedReadyAt = request.EstimatedReadyAt
+	}
+	if parent.InvoiceNumber != "" {
+		child.InvoiceNumber = parent.InvoiceNumber + "-" + splitSuffix(parent.SplitCount+1)
+	}
+
+	// Kurangi baris pajak layanan di induk sebesar bagian yang dipindah
+	taxLines := append([]models.TaxLine(nil), parent.TaxLines...)
+	for i := range taxLines {
+		if taxLines[i].Description == parent.ServiceType {
+			taxLines[i].Amount -= part.Subtotal
+			taxLines[i].Base -= part.Base
+			taxLines[i].Tax -= part.Tax
+			break
+		}
+	}
+
+	// Total harga dan jumlah pecahan menjadi syarat update agar dua pemecahan bersamaan tidak tumpang tindih
+	parentID, _ := primitive.ObjectIDFromHex(parent.ID)
+	filter := bson.M{"_id": parentID, "total_price": parent.TotalPrice, "amount_paid": parent.AmountPaid, "split_count": parent.SplitCount}
+	if parent.SplitCount == 0 {
+		filter["split_count"] = bson.M{"$in": bson.A{0, nil}}
+	}
+	set := bson.M{
+		"weight_per_kg": math.Max(0, parent.WeightPerKg-request.WeightPerKg),
+		"pieces":        max(0, parent.Pieces-request.Pieces),
+		"subtotal":      parent.Subtotal - part.Subtotal,
+		"total_price":   parent.TotalPrice - part.Total,
+		"amount_paid":   parent.AmountPaid - part.Paid,
+		"tax_base":      parent.TaxBase - part.Base,
+		"tax_amount":    parent.TaxAmount - part.Tax,
+		"tax_lines":     taxLines,
+		"split_count":   parent.SplitCount + 1,
+	}
+	result, err := config.TransactionCollection.UpdateOne(ctx, filter, bson.M{"$set": set})
+	if err != nil {
+		http.Error(w, `{"error": "Failed to update transaction"}`, http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, `{"error": "Transaction was changed by someone else, please retry"}`, http.StatusConflict)
+		return
+	}
+
+	inserted, err := config.TransactionCollection.InsertOne(ctx, child)
+	if err != nil {
+		// Kembalikan pesanan induk seperti semula
+		config.TransactionCollection.UpdateOne(ctx, bson.M{"_id": parentID, "split_count": parent.SplitCount + 1}, bson.M{"$set": bson.M{
+			"weight_per_kg": parent.WeightPerKg,
+			"pieces":        parent.Pieces,
+			"subtotal":      parent.Subtotal,
+			"total_price":   parent.TotalPrice,
+			"amount_paid":   parent.AmountPaid,
+			"tax_base":      parent.TaxBase,
+			"tax_amount":    parent.TaxAmount,
+			"tax_lines":     parent.TaxLines,
+			"split_count":   parent.SplitCount,
+		}})
+		http.Error(w, `{"error": "Failed to create child order"}`, http.StatusInternalServerError)
+		return
+	}
+	child.ID = inserted.InsertedID.(primitive.ObjectID).Hex()
+	child.TransactionDateFormatted = formatDate(child.TransactionDate)
+
+	parent, _ = findTransaction(ctx, parent.ID)
+	parent.TransactionDateFormatted = formatDate(parent.TransactionDate)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"parent": parent,
+		"child":  child,
+	})
+}
+
+// GetTransactionFamily returns an order together with the orders split from it; given a child
+// order it returns the whole family of its parent
+func GetTransactionFamily(w http.ResponseWriter, r *http.Request) {
+	transactionID := r.URL.Query().Get("id")
+	if transactionID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	parent, err := findTransaction(ctx, transactionID)
+	if err != nil {
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+		return
+	}
+	for parent.ParentID != "" {
+		if parent, err = findTransaction(ctx, parent.ParentID); err != nil {
+			http.Error(w, "Parent transaction not found", http.StatusNotFound)
+			return
+		}
+	}
+	parent.TransactionDateFormatted = formatDate(parent.TransactionDate)
+
+	// Pecahan dari pecahan ikut dikumpulkan
+	var children []models.Transaction
+	parents := []string{parent.ID}
+	for len(parents) > 0 {
+		cursor, err := config.TransactionCollection.Find(ctx, bson.M{"parent_id": bson.M{"$in": parents}})
+		if err != nil {
+			http.Error(w, "Failed to fetch child orders", http.StatusInternalServerError)
+			return
+		}
+		var level []models.Transaction
+		if err := cursor.All(ctx, &level); err != nil {
+			http.Error(w, "Failed to decode child orders", http.StatusInternalServerError)
+			return
+		}
+		parents = nil
+		for _, child := range level {
+			child.TransactionDateFormatted = formatDate(child.TransactionDate)
+			children = append(children, child)
+			parents = append(parents, child.ID)
+		}
+	}
+
+	// Ringkasan gabungan agar kasir melihat total dan sisa tagihan seluruh pesanan
+	total, paid, due := parent.TotalPrice, parent.AmountPaid, balanceDue(parent)
+	for _, child := range children {
+		total += child.TotalPrice
+		paid += child.AmountPaid
+		due += balanceDue(child)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"parent":      parent,
+		"children":    children,
+		"total_price": total,
+		"amount_paid": paid,
+		"balance_due": due,
+	})
+}
+
+// RecordPartialPickup hands over some pieces of a ready order: {"pieces": 3, "note": "kemeja"}.
+// The order moves to picked_up once every piece has been collected; orders without a piece
+// count are finished by their first pickup.
+func RecordPartialPickup(w http.ResponseWriter, r *http.Request) {
+	transactionID := r.URL.Query().Get("id")
+	if transactionID == "" {
+		http.Error(w, `{"error": "ID not provided"}`, http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		Pieces int    `json:"pieces"`
+		Note   string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Pieces <= 0 {
+		http.Error(w, `{"error": "Invalid input, pieces must be positive"}`, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	transaction, err := findTransaction(ctx, transactionID)
+	if err != nil {
+		http.Error(w, `{"error": "Transaction not found"}`, http.StatusNotFound)
+		return
+	}
+	if transaction.Status != StatusReady {
+		http.Error(w, `{"error": "Only ready orders can be picked up"}`, http.StatusConflict)
+		return
+	}
+	picked := transaction.PickedUpPieces + request.Pieces
+	if transaction.Pieces > 0 && picked > transaction.Pieces {
+		http.Error(w, `{"error": "Only `+strconv.Itoa(transaction.Pieces-transaction.PickedUpPieces)+` pieces are left to pick up"}`, http.StatusBadRequest)
+		return
+	}
+
+	pickup := models.PartialPickup{
+		Pieces:   request.Pieces,
+		Note:     request.Note,
+		ByID:     r.Header.Get("User-ID"),
+		By:       r.Header.Get("Username"),
+		PickedAt: time.Now(),
+	}
+
+	// Jumlah yang sudah diambil menjadi syarat update agar dua kasir tidak mencatat potong yang sama
+	id, _ := primitive.ObjectIDFromHex(transaction.ID)
+	filter := bson.M{"_id": id, "status": StatusReady, "picked_up_pieces": transaction.PickedUpPieces}
+	if transaction.PickedUpPieces == 0 {
+		filter["picked_up_pieces"] = bson.M{"$in": bson.A{0, nil}}
+	}
+	result, err := config.TransactionCollection.UpdateOne(ctx, filter, bson.M{
+		"$set":  bson.M{"picked_up_pieces": picked},
+		"$push": bson.M{"pickups": pickup},
+	})
+	if err != nil {
+		http.Error(w, `{"error": "Failed to record pickup"}`, http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, `{"error": "Transaction was changed by someone else, please retry"}`, http.StatusConflict)
+		return
+	}
+
+	complete := transaction.Pieces == 0 || picked >= transaction.Pieces
+	if complete {
+		if code, msg := changeTransactionStatus(ctx, transaction, StatusPickedUp); code != http.StatusOK {
+			http.Error(w, `{"error": "`+msg+`"}`, code)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"picked_up_pieces": picked,
+		"remaining_pieces": int(math.Max(0, float64(transaction.Pieces-picked))),
+		"complete":         complete,
+		"balance_due":      balanceDue(transaction),
+	})
+}
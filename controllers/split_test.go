@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"math"
+	"testing"
+
+	"apkclaundry/models"
+)
+
+// splitParent builds an order with a service of 100000 and a delivery fee of 10000 before tax,
+// both taxed at 11% (or untaxed when taxed is false)
+func splitParent(taxed, inclusive bool, amountPaid float64) models.Transaction {
+	transaction := models.Transaction{ServiceType: "Cuci Kering", DeliveryFee: 10000, AmountPaid: amountPaid}
+	if !taxed {
+		transaction.TotalPrice = 110000
+		return transaction
+	}
+	service, delivery := 100000.0, 10000.0
+	if inclusive {
+		service, delivery = 111000, 11100
+		transaction.DeliveryFee = delivery
+	}
+	transaction.TaxRate, transaction.TaxInclusive = 11, inclusive
+	for _, line := range []models.TaxLine{{Description: "Cuci Kering", Amount: service}, {Description: "Ongkos antar", Amount: delivery}} {
+		line.Base, line.Tax = splitTax(line.Amount, 11, inclusive)
+		transaction.TaxLines = append(transaction.TaxLines, line)
+		transaction.TaxBase += line.Base
+		transaction.TaxAmount += line.Tax
+		transaction.TotalPrice += line.Base + line.Tax
+	}
+	return transaction
+}
+
+func TestServicePrice(t *testing.T) {
+	tests := []struct {
+		name       string
+		parent     models.Transaction
+		total, tax float64
+	}{
+		{"untaxed", splitParent(false, false, 0), 100000, 0},
+		{"exclusive", splitParent(true, false, 0), 111000, 11000},
+		{"inclusive", splitParent(true, true, 0), 111000, 11000},
+	}
+	for _, test := range tests {
+		total, tax := servicePrice(test.parent)
+		if total != test.total || tax != test.tax {
+			t.Errorf("%s: servicePrice = %.0f, %.0f, want %.0f, %.0f", test.name, total, tax, test.total, test.tax)
+		}
+	}
+}
+
+func TestSplitPrice(t *testing.T) {
+	tests := []struct {
+		name   string
+		parent models.Transaction
+		share  float64
+		want   splitPart
+	}{
+		{"untaxed half", splitParent(false, false, 0), 0.5, splitPart{Total: 50000, Subtotal: 50000, Base: 50000}},
+		{"exclusive third", splitParent(true, false, 0), 1.0 / 3, splitPart{Total: 37000, Subtotal: 33333, Base: 33333, Tax: 3667}},
+		{"inclusive third", splitParent(true, true, 0), 1.0 / 3, splitPart{Total: 37000, Subtotal: 37000, Base: 33333, Tax: 3667}},
+		{"exclusive rounding", splitParent(true, false, 0), 0.123, splitPart{Total: 13653, Subtotal: 12300, Base: 12300, Tax: 1353}},
+		{"fully paid", splitParent(true, false, 122100), 1.0 / 3, splitPart{Total: 37000, Subtotal: 33333, Base: 33333, Tax: 3667, Paid: 37000}},
+		{"paid beyond the parent's share", splitParent(true, false, 100000), 1.0 / 3, splitPart{Total: 37000, Subtotal: 33333, Base: 33333, Tax: 3667, Paid: 14900}},
+		{"paid within the parent's share", splitParent(true, false, 50000), 1.0 / 3, splitPart{Total: 37000, Subtotal: 33333, Base: 33333, Tax: 3667}},
+	}
+	for _, test := range tests {
+		parent := test.parent
+		serviceTotal, serviceTax := servicePrice(parent)
+		part := splitPrice(parent, serviceTotal, serviceTax, test.share)
+		if part != test.want {
+			t.Errorf("%s: splitPrice = %+v, want %+v", test.name, part, test.want)
+			continue
+		}
+
+		if part.Base+part.Tax != part.Total {
+			t.Errorf("%s: child base + tax = %.0f, want total %.0f", test.name, part.Base+part.Tax, part.Total)
+		}
+		// Pajak pesanan anak mengikuti tarif pesanan induk, selisih paling banyak satu rupiah
+		if parent.TaxRate > 0 && math.Abs(part.Base*parent.TaxRate/100-part.Tax) > 1 {
+			t.Errorf("%s: child tax %.0f is not %.0f%% of %.0f", test.name, part.Tax, parent.TaxRate, part.Base)
+		}
+
+		// Sisa di induk ditambah pesanan anak harus sama dengan pesanan semula
+		parentTotal, parentPaid := parent.TotalPrice-part.Total, parent.AmountPaid-part.Paid
+		if parentPaid < 0 || parentPaid > parentTotal {
+			t.Errorf("%s: parent keeps %.0f paid of %.0f", test.name, parentPaid, parentTotal)
+		}
+		parentBase, parentTax := parent.TaxBase-part.Base, parent.TaxAmount-part.Tax
+		if parent.TaxRate > 0 && parentBase+parentTax != parentTotal {
+			t.Errorf("%s: parent base + tax = %.0f, want total %.0f", test.name, parentBase+parentTax, parentTotal)
+		}
+		for _, line := range parent.TaxLines {
+			if line.Description != parent.ServiceType {
+				continue
+			}
+			remaining := line.Amount - part.Subtotal
+			if !parent.TaxInclusive {
+				remaining += line.Tax - part.Tax
+			}
+			if remaining+part.Total != serviceTotal {
+				t.Errorf("%s: service line left on parent %.0f + child %.0f != %.0f", test.name, remaining, part.Total, serviceTotal)
+			}
+		}
+	}
+}
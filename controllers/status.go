@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"apkclaundry/config"
+	"apkclaundry/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Status pesanan laundry, berurutan dari diterima sampai diambil
+const (
+	StatusReceived = "received"
+	StatusWashing  = "washing"
+	StatusDrying   = "drying"
+	StatusIroning  = "ironing"
+	StatusReady    = "ready"
+	StatusPickedUp = "picked_up"
+
+	// StatusCancelled berada di luar alur normal dan hanya bisa dicapai lewat pembatalan
+	StatusCancelled = "cancelled"
+	// StatusAbandoned diberikan oleh job cucian tak diambil sebelum donasi/pembuangan
+	StatusAbandoned = "abandoned"
+	// StatusDraft adalah pesanan dari jadwal berulang yang belum ditimbang dan dikonfirmasi
+	StatusDraft = "draft"
+)
+
+// statusFlow is the order an order moves through; steps may be skipped (e.g. no ironing) but never reversed
+var statusFlow = []string{StatusReceived, StatusWashing, StatusDrying, StatusIroning, StatusReady, StatusPickedUp}
+
+// openStatuses are the statuses of orders still being processed, i.e. in the workload queue
+var openStatuses = []string{StatusReceived, StatusWashing, StatusDrying, StatusIroning}
+
+// statusIndex returns the position of a status in statusFlow, or -1 when unknown.
+// Orders created before statuses existed are treated as received.
+func statusIndex(status string) int {
+	if status == "" {
+		return 0
+	}
+	for i, s := range statusFlow {
+		if s == status {
+			return i
+		}
+	}
+	return -1
+}
+
+// nextStatus returns the status that follows the given one, or "" when the order is finished
+func nextStatus(status string) string {
+	i := statusIndex(status)
+	if i < 0 || i+1 >= len(statusFlow) {
+		return ""
+	}
+	return statusFlow[i+1]
+}
+
+// statusUpdate builds the $set document for moving an order to a status, stamping milestone times
+func statusUpdate(status string, now time.Time) bson.M {
+	set := bson.M{"status": status}
+	switch status {
+	case StatusReady:
+		set["ready_at"] = now
+	case StatusPickedUp:
+		set["picked_up_at"] = now
+	}
+	return set
+}
+
+// changeTransactionStatus moves a transaction forward to a new status. The current status is part
+// of the update filter so that two operators advancing the same order cannot both succeed.
+func changeTransactionStatus(ctx context.Context, transaction models.Transaction, status string) (int, string) {
+	from, to := statusIndex(transaction.Status), statusIndex(status)
+	if to < 0 {
+		return http.StatusBadRequest, "Invalid status"
+	}
+	if from < 0 || to <= from {
+		return http.StatusConflict, "Cannot move order from " + transaction.Status + " to " + status
+	}
+
+	id, _ := primitive.ObjectIDFromHex(transaction.ID)
+	filter := bson.M{"_id": id, "status": transaction.Status}
+	if transaction.Status == "" {
+		filter["status"] = bson.M{"$in": bson.A{"", nil}}
+	}
+
+	result, err := config.TransactionCollection.UpdateOne(ctx, filter, bson.M{"$set": statusUpdate(status, time.Now())})
+	if err != nil {
+		return http.StatusInternalServerError, "Failed to update transaction status"
+	}
+	if result.MatchedCount == 0 {
+		return http.StatusConflict, "Transaction was changed by someone else, please retry"
+	}
+
+	// Bahan habis pakai dipotong saat pesanan mulai dicuci, juga jika tahap cuci dilewati;
+	// pemotongan yang gagal sebagian dilanjutkan pada perubahan status berikutnya
+	washing := statusIndex(StatusWashing)
+	if (from < washing && to >= washing) || (len(transaction.ConsumableItems) > 0 && !transaction.ConsumablesDeducted) {
+		if err := deductConsumables(ctx, transaction); err != nil {
+			log.Printf("Failed to deduct consumables for transaction %s: %v", transaction.ID, err)
+		}
+	}
+	if status == StatusReady {
+		notifyTransaction(transaction, EventReady)
+	}
+	return http.StatusOK, ""
+}
+
+// UpdateTransactionStatus moves an order to a new status, e.g. {"status": "ready"}
+func UpdateTransactionStatus(w http.ResponseWriter, r *http.Request) {
+	transactionID := r.URL.Query().Get("id")
+	if transactionID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(transactionID)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var transaction models.Transaction
+	if err := config.TransactionCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&transaction); err != nil {
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+		return
+	}
+
+	if code, msg := changeTransactionStatus(ctx, transaction, request.Status); code != http.StatusOK {
+		http.Error(w, msg, code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Transaction status updated successfully"})
+}
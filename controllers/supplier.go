@@ -102,44 +102,11 @@ func GetSupplierByID(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(supplier)
 }
 
-// UpdateSupplier updates a supplier's data by their ID
+// UpdateSupplier updates a supplier's data by their ID. The body is a JSON merge patch:
+// only the fields it contains are changed, and each change is kept in the revision history
 func UpdateSupplier(w http.ResponseWriter, r *http.Request) {
-	supplierID := r.URL.Query().Get("id")
-	if supplierID == "" {
-		http.Error(w, "ID not provided", http.StatusBadRequest)
-		return
-	}
-
-	id, err := primitive.ObjectIDFromHex(supplierID)
-	if err != nil {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
-		return
-	}
-
-	var updatedSupplier models.Supplier
-	if err := json.NewDecoder(r.Body).Decode(&updatedSupplier); err != nil {
-		http.Error(w, "Invalid input", http.StatusBadRequest)
-		return
-	}
-
-	update := bson.M{
-		"$set": bson.M{
-			"supplier_name":     updatedSupplier.SupplierName,
-			"phone_number":      updatedSupplier.PhoneNumber,
-			"address":           updatedSupplier.Address,
-			"email":             updatedSupplier.Email,
-			"supplied_products": updatedSupplier.SuppliedProducts,
-		},
-	}
-
-	result, err := config.SupplierCollection.UpdateOne(context.TODO(), bson.M{"_id": id}, update)
-	if err != nil {
-		http.Error(w, "Failed to update supplier", http.StatusInternalServerError)
-		return
-	}
-
-	if result.MatchedCount == 0 {
-		http.Error(w, "Supplier not found", http.StatusNotFound)
+	var supplier models.Supplier
+	if !patchDocument(w, r, config.SupplierCollection, "supplier", &supplier, supplierPatchFields, nil) {
 		return
 	}
 
@@ -0,0 +1,212 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"time"
+
+	"apkclaundry/config"
+	"apkclaundry/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// splitTax returns the tax base (DPP) and the tax of an amount, rounded to whole rupiah.
+// For tax-inclusive prices the tax is taken out of the amount; otherwise it comes on top.
+func splitTax(amount, rate float64, inclusive bool) (float64, float64) {
+	if rate <= 0 {
+		return amount, 0
+	}
+	if inclusive {
+		base := math.Round(amount / (1 + rate/100))
+		return base, amount - base
+	}
+	return amount, math.Round(amount * rate / 100)
+}
+
+// applyTax computes the tax of a new order on its price after discounts, using the service's
+// own rate when it has one, and adds the tax to TotalPrice for tax-exclusive prices
+func applyTax(ctx context.Context, transaction *models.Transaction) error {
+	settings := config.Tax()
+	rate := settings.Rate
+	service, err := findServiceByName(ctx, transaction.ServiceType)
+	if err != nil && err != errServiceNotFound {
+		return err
+	}
+	if err == nil && service.TaxRate != nil {
+		rate = *service.TaxRate
+	}
+
+	transaction.TaxName = settings.Name
+	transaction.TaxRate = rate
+	transaction.TaxInclusive = settings.Inclusive
+	transaction.TaxBase, transaction.TaxAmount = splitTax(transaction.TotalPrice, rate, settings.Inclusive)
+	transaction.TaxLines = nil
+	if rate <= 0 {
+		transaction.TaxName = ""
+		return nil
+	}
+
+	transaction.TaxLines = []models.TaxLine{{
+		Description: transaction.ServiceType,
+		Amount:      transaction.TotalPrice,
+		Base:        transaction.TaxBase,
+		Tax:         transaction.TaxAmount,
+	}}
+	if !settings.Inclusive {
+		transaction.TotalPrice += transaction.TaxAmount
+	}
+	return nil
+}
+
+// taxedFee builds the update that adds a fee (negative to remove it) to an order's field and
+// total, taxed at the rate and mode the order was created with. It returns the update and the
+// amount added to the total.
+func taxedFee(transaction models.Transaction, field, description string, fee float64) (bson.M, float64) {
+	inc := bson.M{field: fee, "total_price": fee}
+	update := bson.M{"$inc": inc}
+	if transaction.TaxRate <= 0 {
+		return update, fee
+	}
+
+	base, tax := splitTax(fee, transaction.TaxRate, transaction.TaxInclusive)
+	charged := fee
+	if !transaction.TaxInclusive {
+		charged += tax
+	}
+	inc["total_price"] = charged
+	inc["tax_base"] = base
+	inc["tax_amount"] = tax
+	update["$push"] = bson.M{"tax_lines": models.TaxLine{Description: description, Amount: fee, Base: base, Tax: tax}}
+	return update, charged
+}
+
+// addTaxedFee adds a fee to an order, together with its tax line
+func addTaxedFee(ctx context.Context, transactionID, field, description string, fee float64) error {
+	transaction, err := findTransaction(ctx, transactionID)
+	if err != nil {
+		return err
+	}
+	update, charged := taxedFee(transaction, field, description, fee)
+	id, _ := primitive.ObjectIDFromHex(transactionID)
+	if _, err = config.TransactionCollection.UpdateOne(ctx, bson.M{"_id": id}, update); err != nil {
+		return err
+	}
+	after := transaction
+	after.TotalPrice += charged
+	syncAccountOutstanding(ctx, transaction, after)
+	billInvoicedFee(ctx, transaction, fee, charged)
+	return nil
+}
+
+// GetTaxReport summarizes taxed sales per month and tax rate for the accountant, for ?year=
+// (default the current year) or ?from=&to=. Cancelled orders are left out.
+func GetTaxReport(w http.ResponseWriter, r *http.Request) {
+	location := config.Location()
+	dateFilter, err := dateRangeFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if dateFilter == nil {
+		year := time.Now().In(location).Year()
+		if value := r.URL.Query().Get("year"); value != "" {
+			parsed, err := time.ParseInLocation("2006", value, location)
+			if err != nil {
+				http.Error(w, "Invalid year", http.StatusBadRequest)
+				return
+			}
+			year = parsed.Year()
+		}
+		start := time.Date(year, 1, 1, 0, 0, 0, 0, location)
+		dateFilter = bson.M{"$gte": start, "$lt": start.AddDate(1, 0, 0)}
+	}
+
+	month := bson.M{"$dateToString": bson.M{"format": "%Y-%m", "date": "$transaction_date", "timezone": time.Now().In(location).Format("-07:00")}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"transaction_date": dateFilter, "status": bson.M{"$ne": StatusCancelled}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    bson.M{"month": month, "rate": bson.M{"$ifNull": bson.A{"$tax_rate", 0}}},
+			"orders": bson.M{"$sum": 1},
+			"sales":  bson.M{"$sum": "$total_price"},
+			// Transaksi lama tanpa pajak: seluruh penjualan adalah DPP
+			"base": bson.M{"$sum": bson.M{"$ifNull": bson.A{"$tax_base", "$total_price"}}},
+			"tax":  bson.M{"$sum": bson.M{"$ifNull": bson.A{"$tax_amount", 0}}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id.month", Value: 1}, {Key: "_id.rate", Value: 1}}}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := config.TransactionCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		http.Error(w, "Failed to build tax report", http.StatusInternalServerError)
+		return
+	}
+	var rows []struct {
+		Key struct {
+			Month string  `bson:"month"`
+			Rate  float64 `bson:"rate"`
+		} `bson:"_id"`
+		Orders int     `bson:"orders"`
+		Sales  float64 `bson:"sales"`
+		Base   float64 `bson:"base"`
+		Tax    float64 `bson:"tax"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		http.Error(w, "Failed to read tax report", http.StatusInternalServerError)
+		return
+	}
+
+	type rateRow struct {
+		Rate   float64 `json:"rate"`
+		Orders int     `json:"orders"`
+		Sales  float64 `json:"sales"`
+		Base   float64 `json:"base"`
+		Tax    float64 `json:"tax"`
+	}
+	type monthRow struct {
+		Month  string    `json:"month"`
+		Orders int       `json:"orders"`
+		Sales  float64   `json:"sales"`
+		Base   float64   `json:"base"`
+		Tax    float64   `json:"tax"`
+		Rates  []rateRow `json:"rates"`
+	}
+	months := []monthRow{}
+	var total rateRow
+	for _, row := range rows {
+		if len(months) == 0 || months[len(months)-1].Month != row.Key.Month {
+			months = append(months, monthRow{Month: row.Key.Month, Rates: []rateRow{}})
+		}
+		current := &months[len(months)-1]
+		current.Orders += row.Orders
+		current.Sales += row.Sales
+		current.Base += row.Base
+		current.Tax += row.Tax
+		current.Rates = append(current.Rates, rateRow{Rate: row.Key.Rate, Orders: row.Orders, Sales: row.Sales, Base: row.Base, Tax: row.Tax})
+		total.Orders += row.Orders
+		total.Sales += row.Sales
+		total.Base += row.Base
+		total.Tax += row.Tax
+	}
+
+	response := map[string]interface{}{
+		"tax_name": config.Tax().Name,
+		"months":   months,
+		"total": map[string]interface{}{
+			"orders": total.Orders,
+			"sales":  total.Sales,
+			"base":   total.Base,
+			"tax":    total.Tax,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"testing"
+
+	"apkclaundry/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestSplitTax(t *testing.T) {
+	tests := []struct {
+		name      string
+		amount    float64
+		rate      float64
+		inclusive bool
+		base, tax float64
+	}{
+		{"exclusive", 100000, 11, false, 100000, 11000},
+		{"inclusive", 111000, 11, true, 100000, 11000},
+		{"rate 0", 50000, 0, false, 50000, 0},
+		{"rate 0 inclusive", 50000, 0, true, 50000, 0},
+		{"negative rate", 50000, -5, false, 50000, 0},
+		{"exclusive rounds up", 12345, 11, false, 12345, 1358},
+		{"exclusive rounds down", 12340, 11, false, 12340, 1357},
+		{"inclusive rounds", 10000, 11, true, 9009, 991},
+		{"inclusive small amount", 21, 5, true, 20, 1},
+		{"fee removed", -10000, 11, false, -10000, -1100},
+		{"zero amount", 0, 11, true, 0, 0},
+	}
+	for _, test := range tests {
+		base, tax := splitTax(test.amount, test.rate, test.inclusive)
+		if base != test.base || tax != test.tax {
+			t.Errorf("%s: splitTax(%.0f, %.0f, %v) = %.0f, %.0f, want %.0f, %.0f",
+				test.name, test.amount, test.rate, test.inclusive, base, tax, test.base, test.tax)
+		}
+		// Harga inklusif harus terbagi habis menjadi DPP dan pajak
+		if test.inclusive && base+tax != test.amount {
+			t.Errorf("%s: base + tax = %.0f, want %.0f", test.name, base+tax, test.amount)
+		}
+	}
+}
+
+func TestTaxedFee(t *testing.T) {
+	tests := []struct {
+		name        string
+		transaction models.Transaction
+		fee         float64
+		charged     float64
+		base, tax   float64
+	}{
+		{"untaxed", models.Transaction{}, 10000, 10000, 0, 0},
+		{"exclusive", models.Transaction{TaxRate: 11}, 10000, 11100, 10000, 1100},
+		{"inclusive", models.Transaction{TaxRate: 11, TaxInclusive: true}, 10000, 10000, 9009, 991},
+		{"removed exclusive", models.Transaction{TaxRate: 11}, -10000, -11100, -10000, -1100},
+	}
+	for _, test := range tests {
+		update, charged := taxedFee(test.transaction, "delivery_fee", "Ongkos antar", test.fee)
+		if charged != test.charged {
+			t.Errorf("%s: charged = %.0f, want %.0f", test.name, charged, test.charged)
+		}
+		inc := update["$inc"].(bson.M)
+		if inc["delivery_fee"] != test.fee || inc["total_price"] != test.charged {
+			t.Errorf("%s: $inc = %v, want delivery_fee %.0f and total_price %.0f", test.name, inc, test.fee, test.charged)
+		}
+
+		if test.transaction.TaxRate <= 0 {
+			if _, ok := update["$push"]; ok || inc["tax_amount"] != nil {
+				t.Errorf("%s: untaxed fee must not add tax: %v", test.name, update)
+			}
+			continue
+		}
+		if inc["tax_base"] != test.base || inc["tax_amount"] != test.tax {
+			t.Errorf("%s: tax_base, tax_amount = %v, %v, want %.0f, %.0f", test.name, inc["tax_base"], inc["tax_amount"], test.base, test.tax)
+		}
+		line := update["$push"].(bson.M)["tax_lines"].(models.TaxLine)
+		want := models.TaxLine{Description: "Ongkos antar", Amount: test.fee, Base: test.base, Tax: test.tax}
+		if line != want {
+			t.Errorf("%s: tax line = %+v, want %+v", test.name, line, want)
+		}
+	}
+}
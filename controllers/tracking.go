@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"apkclaundry/config"
+	"apkclaundry/models"
+	"apkclaundry/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TrackingInfo is the public view of an order; it must never carry customer identity or contact data
+type TrackingInfo struct {
+	InvoiceNumber    string     `json:"invoice_number"`
+	Outlet           string     `json:"outlet"`
+	OutletPhone      string     `json:"outlet_phone,omitempty"`
+	ServiceType      string     `json:"service_type"`
+	Status           string     `json:"status"`
+	EstimatedReadyAt *time.Time `json:"estimated_ready_at,omitempty"`
+	ReadyAt          *time.Time `json:"ready_at,omitempty"`
+	PickedUpAt       *time.Time `json:"picked_up_at,omitempty"`
+	BalanceDue       float64    `json:"balance_due"`
+}
+
+// trackingToken signs a tracking token for a transaction, valid for TRACKING_TOKEN_DAYS (default 30)
+func trackingToken(transaction models.Transaction) (string, error) {
+	days := config.GetEnvInt("TRACKING_TOKEN_DAYS", 30)
+	return utils.GenerateTrackingToken(transaction.ID, time.Now().AddDate(0, 0, days))
+}
+
+// trackingURL returns the customer-facing tracking link printed on receipts, or "" when tracking
+// is not configured
+func trackingURL(transaction models.Transaction) string {
+	baseURL := config.GetEnv("TRACKING_BASE_URL", "")
+	if baseURL == "" {
+		return ""
+	}
+	token, err := trackingToken(transaction)
+	if err != nil {
+		log.Printf("No tracking link for transaction %s: %v", transaction.ID, err)
+		return ""
+	}
+	return strings.TrimRight(baseURL, "/") + "/" + token
+}
+
+// TrackOrder returns the public status of the order referenced by a signed token (GET /track/{token}).
+// It requires no login; the token itself is the authorization.
+func TrackOrder(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.URL.Path, "/track/")
+	if token == "" || strings.Contains(token, "/") {
+		http.Error(w, "Tracking link not found", http.StatusNotFound)
+		return
+	}
+
+	transactionID, err := utils.ValidateTrackingToken(token)
+	if err == utils.ErrNoTrackingSecret {
+		log.Printf("Tracking request rejected: %v", err)
+		http.Error(w, "Order tracking is not available", http.StatusServiceUnavailable)
+		return
+	}
+	if err == utils.ErrExpiredTrackingToken {
+		http.Error(w, "Tracking link has expired", http.StatusGone)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Tracking link not found", http.StatusNotFound)
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(transactionID)
+	if err != nil {
+		http.Error(w, "Tracking link not found", http.StatusNotFound)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var transaction models.Transaction
+	if err := config.TransactionCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&transaction); err != nil {
+		http.Error(w, "Tracking link not found", http.StatusNotFound)
+		return
+	}
+
+	business := config.Business()
+	outlet := business.Name
+	if transaction.OutletCode != "" {
+		outlet += " (" + transaction.OutletCode + ")"
+	}
+	status := transaction.Status
+	if status == "" {
+		status = StatusReceived
+	}
+
+	info := TrackingInfo{
+		InvoiceNumber:    transaction.InvoiceNumber,
+		Outlet:           outlet,
+		OutletPhone:      business.Phone,
+		ServiceType:      transaction.ServiceType,
+		Status:           status,
+		EstimatedReadyAt: transaction.EstimatedReadyAt,
+		ReadyAt:          transaction.ReadyAt,
+		PickedUpAt:       transaction.PickedUpAt,
+		BalanceDue:       math.Max(0, transaction.TotalPrice-transaction.AmountPaid),
+	}
+
+	// Jangan simpan di cache bersama karena isinya berubah sesuai status pesanan
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(info)
+}
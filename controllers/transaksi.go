@@ -3,6 +3,9 @@ package controllers
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"log"
+	"math"
 	"net/http"
 	"time"
 
@@ -18,6 +21,50 @@ func formatDate(date time.Time) string {
 	return date.Format("02/01/2006")
 }
 
+// findTransaction loads a transaction by its hex ID
+func findTransaction(ctx context.Context, transactionID string) (models.Transaction, error) {
+	var transaction models.Transaction
+	id, err := primitive.ObjectIDFromHex(transactionID)
+	if err != nil {
+		return transaction, err
+	}
+	err = config.TransactionCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&transaction)
+	return transaction, err
+}
+
+// dateRangeFilter builds a MongoDB date filter from ?from=yyyy-mm-dd&to=yyyy-mm-dd (both inclusive,
+// in the business time zone). It returns nil when neither parameter is given.
+func dateRangeFilter(r *http.Request) (bson.M, error) {
+	filter := bson.M{}
+	if from := r.URL.Query().Get("from"); from != "" {
+		start, err := time.ParseInLocation("2006-01-02", from, config.Location())
+		if err != nil {
+			return nil, errors.New("Invalid from date, use yyyy-mm-dd")
+		}
+		filter["$gte"] = start
+	}
+	if to := r.URL.Query().Get("to"); to != "" {
+		end, err := time.ParseInLocation("2006-01-02", to, config.Location())
+		if err != nil {
+			return nil, errors.New("Invalid to date, use yyyy-mm-dd")
+		}
+		filter["$lt"] = end.AddDate(0, 0, 1)
+	}
+	if len(filter) == 0 {
+		return nil, nil
+	}
+	return filter, nil
+}
+
+// totalDiscount sums all discount lines of a transaction
+func totalDiscount(transaction models.Transaction) float64 {
+	total := 0.0
+	for _, discount := range transaction.Discounts {
+		total += discount.Amount
+	}
+	return total
+}
+
 // CreateTransaction handles the creation of a new transaction
 func CreateTransaction(w http.ResponseWriter, r *http.Request) {
 	var transaction models.Transaction
@@ -34,14 +81,167 @@ func CreateTransaction(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	// Pesanan baru selalu mulai dari status diterima, dicatat atas nama karyawan yang login
+	transaction.Status = StatusReceived
+	transaction.HandledByID = r.Header.Get("User-ID")
+	transaction.HandledBy = r.Header.Get("Username")
+	transaction.ReadyAt = nil
+	transaction.PickedUpAt = nil
+	// Field berikut diisi server selama pesanan diproses, bukan dari klien
+	transaction.ReceiptPrintCount = 0
+	transaction.DeliveryFee = 0
+	transaction.PaymentRefs = nil
+	transaction.Cancellation = nil
+	transaction.RemindersSent = 0
+	transaction.StorageFee = 0
+	transaction.StorageFeeDays = 0
+	transaction.LoadID = ""
+	transaction.ConsumablesDeducted = false
+	transaction.ConsumableItems = nil
+	transaction.ParentID = ""
+	transaction.SplitCount = 0
+	transaction.Pickups = nil
+	transaction.PickedUpPieces = 0
+	transaction.ScheduleID = ""
+	transaction.ScheduledFor = ""
+	// Akun korporat hanya diisi oleh chargeToAccount setelah limit kreditnya diperiksa
+	transaction.AccountID = ""
+	transaction.CorporateInvoiceID = ""
+
+	// Preferensi pelanggan disalin ke pesanan, kecuali kasir mengisi preferensi khusus untuk pesanan ini
+	if transaction.Preferences == nil && transaction.CustomerID != "" {
+		if customer, err := findCustomer(ctx, transaction.CustomerID); err == nil {
+			transaction.Preferences = customer.Preferences
+		}
+	}
+
+	// Estimasi selesai dihitung dari layanan; jika layanan belum terdaftar, pakai nilai dari klien
+	estimate, err := estimateReadyAt(ctx, transaction, time.Now())
+	switch err {
+	case nil:
+		transaction.EstimatedReadyAt = &estimate
+	case errServiceNotFound:
+	case errExpressNotAvailable:
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusUnprocessableEntity)
+		return
+	default:
+		log.Printf("Failed to estimate ready time: %v", err)
+	}
+
+	// Harga dari klien adalah subtotal; diskon hanya boleh berasal dari promo, tier atau poin
+	transaction.Subtotal = transaction.TotalPrice
+	transaction.Discounts = nil
+	transaction.PointsRedeemed = 0
+	transaction.PointsEarned = 0
+	transaction.LoyaltyAwarded = false
+
+	// rollback membatalkan efek samping jika transaksi gagal disimpan
+	var rollbacks []func()
+	rollback := func() {
+		for i := len(rollbacks) - 1; i >= 0; i-- {
+			rollbacks[i]()
+		}
+	}
+
+	var promo *models.Promotion
+	if transaction.PromoCode != "" {
+		promo, err = applyPromotion(ctx, &transaction, time.Now())
+		if err != nil {
+			if err == errPromotionNotFound {
+				http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+				return
+			}
+			if _, ok := promotionErrors[err]; ok {
+				http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusUnprocessableEntity)
+				return
+			}
+			http.Error(w, `{"error": "Failed to apply promo code"}`, http.StatusInternalServerError)
+			return
+		}
+		customerKey := promotionCustomerKey(transaction)
+		rollbacks = append(rollbacks, func() { releasePromotion(ctx, *promo, customerKey) })
+	}
+
+	releasePoints, err := applyMemberBenefits(ctx, &transaction)
+	if err != nil {
+		rollback()
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusUnprocessableEntity)
+		return
+	}
+	rollbacks = append(rollbacks, releasePoints)
+
+	transaction.TotalPrice = transaction.Subtotal - totalDiscount(transaction)
+	if err := applyTax(ctx, &transaction); err != nil {
+		rollback()
+		http.Error(w, `{"error": "Failed to calculate tax"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Pesanan korporat ditagihkan bulanan ke akun, bukan dibayar saat diambil atau dari paket
+	if transaction.PaymentMethod == PaymentAccount {
+		if transaction.UsePackage != "" {
+			rollback()
+			http.Error(w, `{"error": "Orders charged to an account cannot be paid from a package"}`, http.StatusBadRequest)
+			return
+		}
+		releaseCredit, err := chargeToAccount(ctx, &transaction)
+		if err != nil {
+			rollback()
+			http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusUnprocessableEntity)
+			return
+		}
+		rollbacks = append(rollbacks, releaseCredit)
+	}
+
+	// Uang tunai yang masuk laci kasir; kembalian tidak dihitung
+	cashPaid := 0.0
+	if isCashMethod(transaction.PaymentMethod) {
+		cashPaid = math.Min(transaction.AmountPaid, transaction.TotalPrice)
+	}
+
+	// Bayar dari paket prabayar pelanggan jika diminta
+	transaction.PackageDebits = nil
+	restorePackage, err := debitCustomerPackage(ctx, &transaction, time.Now())
+	if err != nil {
+		rollback()
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusUnprocessableEntity)
+		return
+	}
+	rollbacks = append(rollbacks, restorePackage)
+
+	// Nomor nota selalu dibuat server agar tetap berurutan
+	if transaction.OutletCode == "" {
+		transaction.OutletCode = config.GetEnv("OUTLET_CODE", "")
+	}
+	transaction.InvoiceNumber, err = nextInvoiceNumber(ctx, transaction.OutletCode)
+	if err != nil {
+		rollback()
+		http.Error(w, `{"error": "Failed to generate invoice number"}`, http.StatusInternalServerError)
+		return
+	}
+
 	result, err := config.TransactionCollection.InsertOne(ctx, transaction)
 	if err != nil {
+		rollback()
 		http.Error(w, `{"error": "Failed to create transaction"}`, http.StatusInternalServerError)
 		return
 	}
 
 	transaction.ID = result.InsertedID.(primitive.ObjectID).Hex()
 
+	if promo != nil {
+		if err := recordPromotionUsage(ctx, *promo, transaction); err != nil {
+			log.Printf("Failed to record promotion usage for transaction %s: %v", transaction.ID, err)
+		}
+	}
+	recordPointsRedeemed(ctx, transaction)
+	recordPackageDebits(ctx, transaction)
+	if err := awardLoyaltyPoints(ctx, transaction); err != nil {
+		log.Printf("Failed to award loyalty points for transaction %s: %v", transaction.ID, err)
+	}
+	recordCashSale(ctx, r.Header.Get("User-ID"), transaction, cashPaid)
+	notifyTransaction(transaction, EventReceived)
+
 	response := map[string]interface{}{
 		"message":     "Transaction created successfully",
 		"transaction": transaction,
@@ -109,46 +309,11 @@ func GetTransactionByID(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(transaction)
 }
 
-// UpdateTransaction updates a transaction's data by its ID
+// UpdateTransaction updates a transaction's data by its ID. The body is a JSON merge patch:
+// only the fields it contains are changed, and each change is kept in the revision history
 func UpdateTransaction(w http.ResponseWriter, r *http.Request) {
-	transactionID := r.URL.Query().Get("id")
-	if transactionID == "" {
-		http.Error(w, "ID not provided", http.StatusBadRequest)
-		return
-	}
-
-	id, err := primitive.ObjectIDFromHex(transactionID)
-	if err != nil {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
-		return
-	}
-
-	var updatedTransaction models.Transaction
-	if err := json.NewDecoder(r.Body).Decode(&updatedTransaction); err != nil {
-		http.Error(w, "Invalid input", http.StatusBadRequest)
-		return
-	}
-
-	update := bson.M{
-		"$set": bson.M{
-			"customer_name":   updatedTransaction.CustomerName,
-			"phone_number":    updatedTransaction.PhoneNumber,
-			"service_type":    updatedTransaction.ServiceType,
-			"weight_per_kg":   updatedTransaction.WeightPerKg,
-			"total_price":     updatedTransaction.TotalPrice,
-			"payment_method":  updatedTransaction.PaymentMethod,
-			"transaction_date": updatedTransaction.TransactionDate,
-		},
-	}
-
-	result, err := config.TransactionCollection.UpdateOne(context.TODO(), bson.M{"_id": id}, update)
-	if err != nil {
-		http.Error(w, "Failed to update transaction", http.StatusInternalServerError)
-		return
-	}
-
-	if result.MatchedCount == 0 {
-		http.Error(w, "Transaction not found", http.StatusNotFound)
+	var transaction models.Transaction
+	if !patchDocument(w, r, config.TransactionCollection, "transaction", &transaction, transactionPatchFields, nil) {
 		return
 	}
 
@@ -170,13 +335,19 @@ func DeleteTransaction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := config.TransactionCollection.DeleteOne(context.TODO(), bson.M{"_id": id})
+	// Pesanan harus dibatalkan dulu (dengan alasan dan refund) sebelum boleh dihapus;
+	// efek sampingnya sudah dikembalikan saat pembatalan
+	result, err := config.TransactionCollection.DeleteOne(context.TODO(), bson.M{"_id": id, "status": StatusCancelled})
 	if err != nil {
 		http.Error(w, "Failed to delete transaction", http.StatusInternalServerError)
 		return
 	}
-
 	if result.DeletedCount == 0 {
+		count, _ := config.TransactionCollection.CountDocuments(context.TODO(), bson.M{"_id": id})
+		if count > 0 {
+			http.Error(w, "Only cancelled transactions can be deleted, cancel it first", http.StatusConflict)
+			return
+		}
 		http.Error(w, "Transaction not found", http.StatusNotFound)
 		return
 	}
@@ -0,0 +1,321 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"apkclaundry/config"
+	"apkclaundry/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	DisposalMarked   = "marked"
+	DisposalDonated  = "donated"
+	DisposalDisposed = "disposed"
+	DisposalReturned = "returned"
+)
+
+// daysSince counts whole calendar days in the business time zone between two moments
+func daysSince(from, now time.Time) int {
+	start, _ := todayRange(from)
+	today, _ := todayRange(now)
+	return int(today.Sub(start).Hours()/24 + 0.5)
+}
+
+// remindersDue returns how many reminders should have been sent after waiting the given days
+func remindersDue(policy config.UnclaimedPolicy, days int) int {
+	due := 0
+	for _, threshold := range policy.ReminderDays {
+		if days >= threshold {
+			due++
+		}
+	}
+	return due
+}
+
+// accrueStorageFee adds the storage fee for the days not yet charged to the order's total.
+// Filtering on the days already charged keeps overlapping runs from charging twice.
+func accrueStorageFee(ctx context.Context, transaction *models.Transaction, policy config.UnclaimedPolicy, days int) (bool, error) {
+	feeDays := days - policy.StorageFeeAfter
+	if policy.StorageFeePerDay <= 0 || feeDays <= transaction.StorageFeeDays {
+		return false, nil
+	}
+
+	newDays := feeDays - transaction.StorageFeeDays
+	fee := float64(newDays) * policy.StorageFeePerDay
+	update, charged := taxedFee(*transaction, "storage_fee", fmt.Sprintf("Biaya simpan %d hari", newDays), fee)
+	update["$set"] = bson.M{"storage_fee_days": feeDays}
+
+	id, _ := primitive.ObjectIDFromHex(transaction.ID)
+	result, err := config.TransactionCollection.UpdateOne(ctx,
+		bson.M{"_id": id, "status": StatusReady, "storage_fee_days": bson.M{"$in": bson.A{transaction.StorageFeeDays, nil}}},
+		update,
+	)
+	if err != nil || result.ModifiedCount == 0 {
+		return false, err
+	}
+	before := *transaction
+	transaction.StorageFee += fee
+	transaction.TotalPrice += charged
+	transaction.StorageFeeDays = feeDays
+	syncAccountOutstanding(ctx, before, *transaction)
+	billInvoicedFee(ctx, before, fee, charged)
+	return true, nil
+}
+
+// markForDisposal sets an order aside and writes its audit record
+func markForDisposal(ctx context.Context, transaction models.Transaction, days int, now time.Time) (bool, error) {
+	id, _ := primitive.ObjectIDFromHex(transaction.ID)
+	result, err := config.TransactionCollection.UpdateOne(ctx,
+		bson.M{"_id": id, "status": StatusReady},
+		bson.M{"$set": bson.M{"status": StatusAbandoned}},
+	)
+	if err != nil || result.ModifiedCount == 0 {
+		return false, err
+	}
+
+	record := models.DisposalRecord{
+		TransactionID: transaction.ID,
+		InvoiceNumber: transaction.InvoiceNumber,
+		CustomerName:  transaction.CustomerName,
+		PhoneNumber:   transaction.PhoneNumber,
+		ReadyAt:       *transaction.ReadyAt,
+		DaysUnclaimed: days,
+		RemindersSent: transaction.RemindersSent,
+		BalanceDue:    balanceDue(transaction),
+		Status:        DisposalMarked,
+		MarkedAt:      now,
+	}
+	if _, err := config.DisposalCollection.InsertOne(ctx, record); err != nil && !mongo.IsDuplicateKeyError(err) {
+		return true, err
+	}
+	return true, nil
+}
+
+// RunUnclaimedPolicy processes ready orders that have not been picked up: it sends reminders at
+// the configured days, accrues storage fees and marks orders for donation/disposal once
+// UNCLAIMED_DISPOSE_DAYS has passed. It is called daily by a cron job and is safe to re-run.
+func RunUnclaimedPolicy(w http.ResponseWriter, r *http.Request) {
+	policy := config.Unclaimed()
+	now := time.Now()
+
+	firstDay := policy.DisposeAfterDays
+	if len(policy.ReminderDays) > 0 && policy.ReminderDays[0] < firstDay {
+		firstDay = policy.ReminderDays[0]
+	}
+	if policy.StorageFeePerDay > 0 && policy.StorageFeeAfter+1 < firstDay {
+		firstDay = policy.StorageFeeAfter + 1
+	}
+	today, _ := todayRange(now)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Second)
+	defer cancel()
+
+	cursor, err := config.TransactionCollection.Find(ctx, bson.M{
+		"status":   StatusReady,
+		"ready_at": bson.M{"$lt": today.AddDate(0, 0, 1-firstDay)},
+	})
+	if err != nil {
+		http.Error(w, "Failed to fetch transactions", http.StatusInternalServerError)
+		return
+	}
+	var transactions []models.Transaction
+	if err := cursor.All(ctx, &transactions); err != nil {
+		http.Error(w, "Failed to read transaction data", http.StatusInternalServerError)
+		return
+	}
+
+	summary := map[string]int{"checked": len(transactions), "reminded": 0, "charged": 0, "marked": 0}
+	for _, transaction := range transactions {
+		if ctx.Err() != nil {
+			break
+		}
+		days := daysSince(*transaction.ReadyAt, now)
+
+		if policy.DisposeAfterDays > 0 && days >= policy.DisposeAfterDays {
+			marked, err := markForDisposal(ctx, transaction, days, now)
+			if err != nil {
+				log.Printf("Failed to mark transaction %s for disposal: %v", transaction.ID, err)
+			}
+			if marked {
+				summary["marked"]++
+			}
+			continue
+		}
+
+		charged, err := accrueStorageFee(ctx, &transaction, policy, days)
+		if err != nil {
+			log.Printf("Failed to charge storage fee for transaction %s: %v", transaction.ID, err)
+		}
+		if charged {
+			summary["charged"]++
+		}
+
+		// Klaim pengingat dulu agar job yang berjalan bersamaan tidak mengirim dua kali
+		if due := remindersDue(policy, days); due > transaction.RemindersSent {
+			id, _ := primitive.ObjectIDFromHex(transaction.ID)
+			result, err := config.TransactionCollection.UpdateOne(ctx,
+				bson.M{"_id": id, "reminders_sent": bson.M{"$in": bson.A{transaction.RemindersSent, nil}}},
+				bson.M{"$set": bson.M{"reminders_sent": due}},
+			)
+			if err != nil {
+				log.Printf("Failed to update reminders for transaction %s: %v", transaction.ID, err)
+				continue
+			}
+			if result.ModifiedCount > 0 {
+				transaction.RemindersSent = due
+				notifyTransaction(transaction, EventUnclaimed)
+				summary["reminded"]++
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
+
+// GetUnclaimedTransactions lists ready orders waiting longer than the first reminder, longest first
+func GetUnclaimedTransactions(w http.ResponseWriter, r *http.Request) {
+	policy := config.Unclaimed()
+	minDays := 1
+	if len(policy.ReminderDays) > 0 {
+		minDays = policy.ReminderDays[0]
+	}
+	now := time.Now()
+	today, _ := todayRange(now)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := config.TransactionCollection.Find(ctx,
+		bson.M{"status": StatusReady, "ready_at": bson.M{"$lt": today.AddDate(0, 0, 1-minDays)}},
+		options.Find().SetSort(bson.M{"ready_at": 1}))
+	if err != nil {
+		http.Error(w, "Failed to fetch transactions", http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	type unclaimed struct {
+		models.Transaction
+		DaysWaiting int       `json:"days_waiting"`
+		DisposalAt  time.Time `json:"disposal_at"`
+	}
+	result := []unclaimed{}
+	for cursor.Next(ctx) {
+		var transaction models.Transaction
+		if err := cursor.Decode(&transaction); err != nil {
+			http.Error(w, "Failed to read transaction data", http.StatusInternalServerError)
+			return
+		}
+		transaction.TransactionDateFormatted = formatDate(transaction.TransactionDate)
+		result = append(result, unclaimed{
+			Transaction: transaction,
+			DaysWaiting: daysSince(*transaction.ReadyAt, now),
+			DisposalAt:  transaction.ReadyAt.AddDate(0, 0, policy.DisposeAfterDays),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// GetDisposalRecords lists orders marked for donation/disposal, filtered by ?status=
+func GetDisposalRecords(w http.ResponseWriter, r *http.Request) {
+	filter := bson.M{}
+	if status := r.URL.Query().Get("status"); status != "" {
+		filter["status"] = status
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := config.DisposalCollection.Find(ctx, filter, options.Find().SetSort(bson.M{"marked_at": -1}))
+	if err != nil {
+		http.Error(w, "Failed to fetch disposal records", http.StatusInternalServerError)
+		return
+	}
+	records := []models.DisposalRecord{}
+	if err := cursor.All(ctx, &records); err != nil {
+		http.Error(w, "Failed to read disposal records", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(records)
+}
+
+// CompleteDisposal records what happened to a marked order: {"status": "donated"|"disposed"|"returned", "note": "..."}.
+// "returned" is for a customer who still shows up; the order is then handed over as picked up.
+func CompleteDisposal(w http.ResponseWriter, r *http.Request) {
+	recordID := r.URL.Query().Get("id")
+	if recordID == "" {
+		http.Error(w, "ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(recordID)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		Status string `json:"status"`
+		Note   string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if request.Status != DisposalDonated && request.Status != DisposalDisposed && request.Status != DisposalReturned {
+		http.Error(w, "Status must be donated, disposed or returned", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	var record models.DisposalRecord
+	err = config.DisposalCollection.FindOneAndUpdate(ctx,
+		bson.M{"_id": id, "status": DisposalMarked},
+		bson.M{"$set": bson.M{
+			"status":       request.Status,
+			"note":         request.Note,
+			"completed_by": r.Header.Get("Username"),
+			"completed_at": now,
+		}},
+	).Decode(&record)
+	if err == mongo.ErrNoDocuments {
+		http.Error(w, "Record not found or already completed", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update disposal record", http.StatusInternalServerError)
+		return
+	}
+
+	if request.Status == DisposalReturned {
+		transactionID, _ := primitive.ObjectIDFromHex(record.TransactionID)
+		_, err := config.TransactionCollection.UpdateOne(ctx,
+			bson.M{"_id": transactionID, "status": StatusAbandoned},
+			bson.M{"$set": statusUpdate(StatusPickedUp, now)},
+		)
+		if err != nil {
+			log.Printf("Failed to hand over transaction %s: %v", record.TransactionID, err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Disposal record updated successfully"})
+}
@@ -2,7 +2,11 @@ module apkclaundry
 
 go 1.23.3
 
-require go.mongodb.org/mongo-driver v1.17.1
+require (
+	github.com/boombuler/barcode v1.1.0
+	github.com/go-pdf/fpdf v0.9.0
+	go.mongodb.org/mongo-driver v1.17.1
+)
 
 require (
 	github.com/golang-jwt/jwt/v4 v4.5.1
@@ -13,7 +17,7 @@ require (
 	github.com/xdg-go/scram v1.1.2 // indirect
 	github.com/xdg-go/stringprep v1.0.4 // indirect
 	github.com/youmark/pkcs8 v0.0.0-20240726163527-a2c0da244d78 // indirect
-	golang.org/x/crypto v0.26.0 // indirect
+	golang.org/x/crypto v0.26.0
 	golang.org/x/sync v0.8.0 // indirect
 	golang.org/x/text v0.17.0 // indirect
 )
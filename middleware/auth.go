@@ -2,8 +2,10 @@ package middleware
 
 import (
 	"apkclaundry/utils"
+	"crypto/subtle"
 	"log"
 	"net/http"
+	"os"
 )
 
 // EnableCORS menangani header CORS agar frontend dapat mengakses API
@@ -23,7 +25,7 @@ func EnableCORS(next http.Handler) http.Handler {
             w.Header().Set("Vary", "Origin")
         }
 
-        w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+        w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
         w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
         w.Header().Set("Access-Control-Allow-Credentials", "true")
 
@@ -38,41 +40,78 @@ func EnableCORS(next http.Handler) http.Handler {
 }
 
 
+// authenticate memvalidasi header Authorization dan mengembalikan klaim JWT
+func authenticate(w http.ResponseWriter, r *http.Request) (*utils.JWTClaims, bool) {
+    token := r.Header.Get("Authorization")
+    if token == "" {
+        log.Println("Authorization header missing")
+        http.Error(w, "Authorization header missing", http.StatusUnauthorized)
+        return nil, false
+    }
+
+    if len(token) < 7 || token[:7] != "Bearer " {
+        log.Println("Invalid token format")
+        http.Error(w, "Invalid token format", http.StatusUnauthorized)
+        return nil, false
+    }
+
+    token = token[7:]
+    claims, err := utils.ValidateJWT(token)
+    if err != nil {
+        log.Printf("Invalid token: %v", err)
+        http.Error(w, "Invalid token", http.StatusUnauthorized)
+        return nil, false
+    }
+
+    r.Header.Set("User-ID", claims.ID)
+    r.Header.Set("Username", claims.Username)
+    r.Header.Set("Role", claims.Role)
+
+    return claims, true
+}
+
 // AuthMiddleware validates JWT tokens
 func AuthMiddleware(next http.Handler) http.Handler {
     return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-        token := r.Header.Get("Authorization")
-        if token == "" {
-            log.Println("Authorization header missing")
-            http.Error(w, "Authorization header missing", http.StatusUnauthorized)
+        claims, ok := authenticate(w, r)
+        if !ok {
             return
         }
 
-        if len(token) < 7 || token[:7] != "Bearer " {
-            log.Println("Invalid token format")
-            http.Error(w, "Invalid token format", http.StatusUnauthorized)
+        if claims.Role != "admin" {
+            log.Printf("Forbidden access for role: %s", claims.Role)
+            http.Error(w, "Forbidden: Only admins can access this endpoint", http.StatusForbidden)
             return
         }
 
-        token = token[7:]
-        claims, err := utils.ValidateJWT(token)
-        if err != nil {
-            log.Printf("Invalid token: %v", err)
-            http.Error(w, "Invalid token", http.StatusUnauthorized)
+        next.ServeHTTP(w, r)
+    })
+}
+
+// StaffMiddleware validates JWT tokens for endpoints open to every logged-in employee,
+// e.g. couriers; handlers scope the data with the User-ID header
+func StaffMiddleware(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if _, ok := authenticate(w, r); !ok {
             return
         }
 
-        if claims.Role != "admin" {
-            log.Printf("Forbidden access for role: %s", claims.Role)
-            http.Error(w, "Forbidden: Only admins can access this endpoint", http.StatusForbidden)
+        next.ServeHTTP(w, r)
+    })
+}
+
+
+// CronMiddleware protects endpoints called by scheduled jobs. Vercel Cron sends
+// "Authorization: Bearer <CRON_SECRET>"; admins can also trigger the job with their JWT.
+func CronMiddleware(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        secret := os.Getenv("CRON_SECRET")
+        if secret != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+secret)) == 1 {
+            next.ServeHTTP(w, r)
             return
         }
 
-        r.Header.Set("User-ID", claims.ID)
-        r.Header.Set("Username", claims.Username)
-        r.Header.Set("Role", claims.Role)
-
-        next.ServeHTTP(w, r)
+        AuthMiddleware(next).ServeHTTP(w, r)
     })
 }
 
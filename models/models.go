@@ -13,6 +13,20 @@ type Customer struct {
 	Phone     string    `json:"phone" bson:"phone"`
 	Address   string    `json:"address" bson:"address"`
 	Email     string	`json:"email" bson:"email"`
+	LoyaltyPoints int     `json:"loyalty_points" bson:"loyalty_points"`
+	Tier          string  `json:"tier" bson:"tier,omitempty"` // "", "silver" atau "gold"
+	LifetimeSpend float64 `json:"lifetime_spend" bson:"lifetime_spend"`
+	Preferences   *CustomerPreferences `json:"preferences,omitempty" bson:"preferences,omitempty"`
+	AccountID     string  `json:"account_id,omitempty" bson:"account_id,omitempty"` // Akun korporat yang menanggung tagihan pelanggan ini
+}
+
+// CustomerPreferences are standing instructions that are copied onto each new order of a customer
+type CustomerPreferences struct {
+	NoSoftener     bool   `json:"no_softener" bson:"no_softener"`
+	Hypoallergenic bool   `json:"hypoallergenic" bson:"hypoallergenic"` // Deterjen khusus kulit sensitif
+	Fragrance      string `json:"fragrance,omitempty" bson:"fragrance,omitempty"`
+	Finish         string `json:"finish,omitempty" bson:"finish,omitempty"` // "fold" atau "hang"
+	Notes          string `json:"notes,omitempty" bson:"notes,omitempty"`
 }
 
 // User represents an employee or system user
@@ -80,7 +94,8 @@ type ItemTransaction struct {
 	ItemID        string    `json:"item_id" bson:"item_id"`
 	ItemName   	  string  `json:"item_name" bson:"item_name"`
 	Date          time.Time `json:"date" bson:"date"`
-	TransactionType string  `json:"transaction_type" bson:"transaction_type"` // "Pemakaian" or "Pembelian"
+	TransactionType string  `json:"transaction_type" bson:"transaction_type"` // "Pemakaian", "Pembelian", "Pembatalan" or "Penyesuaian"
+	TransactionID string    `json:"transaction_id,omitempty" bson:"transaction_id,omitempty"` // Pesanan laundry yang memakai stok ini
 	Quantity      int       `json:"quantity" bson:"quantity"`
 	StockAfter    int       `json:"stock_after" bson:"stock_after"`
 }
@@ -92,6 +107,7 @@ type Item struct {
 	ID          string    `json:"id" bson:"_id,omitempty"`
 	ItemName    string    `json:"item_name" bson:"item_name"`
 	Quantity    int       `json:"quantity" bson:"quantity"`
+	Unit        string    `json:"unit,omitempty" bson:"unit,omitempty"` // Satuan stok, mis. "gram" atau "ml"
 	Price       float64   `json:"price" bson:"price"`
 }
 
@@ -99,22 +115,586 @@ type Item struct {
 
 type Transaction struct {
 	ID                      string    `json:"id" bson:"_id,omitempty"`
+	InvoiceNumber           string    `json:"invoice_number" bson:"invoice_number,omitempty"` // Nomor nota, mis. LDY-20261017-0001
+	ParentID                string    `json:"parent_id,omitempty" bson:"parent_id,omitempty"` // Pesanan induk jika pesanan ini hasil pecahan, nota bersufiks -A, -B, ...
+	SplitCount              int       `json:"split_count,omitempty" bson:"split_count,omitempty"` // Jumlah pesanan anak yang sudah dipecah
+	OutletCode              string    `json:"outlet_code" bson:"outlet_code,omitempty"`
+	CustomerID              string    `json:"customer_id" bson:"customer_id,omitempty"`
 	CustomerName            string    `json:"customer_name" bson:"customer_name"`
+	HandledByID             string    `json:"handled_by_id,omitempty" bson:"handled_by_id,omitempty"` // Karyawan yang menerima pesanan
+	HandledBy               string    `json:"handled_by,omitempty" bson:"handled_by,omitempty"`
 	PhoneNumber             string    `json:"phone_number" bson:"phone_number"`
 	ServiceType             string    `json:"service_type" bson:"service_type"`
 	WeightPerKg             float64   `json:"weight_per_kg" bson:"weight_per_kg"`
+	Pieces                  int       `json:"pieces" bson:"pieces,omitempty"` // Jumlah potong pakaian, untuk label per potong
+	Items                   []string  `json:"items,omitempty" bson:"items,omitempty"` // Rincian barang, mis. "1 bedcover"
+	PickedUpPieces          int       `json:"picked_up_pieces,omitempty" bson:"picked_up_pieces,omitempty"`
+	Pickups                 []PartialPickup `json:"pickups,omitempty" bson:"pickups,omitempty"` // Pengambilan sebagian
+	Preferences             *CustomerPreferences `json:"preferences,omitempty" bson:"preferences,omitempty"` // Salinan preferensi pelanggan saat pesanan dibuat
+	SpecialInstructions     string    `json:"special_instructions,omitempty" bson:"special_instructions,omitempty"` // Instruksi khusus untuk pesanan ini saja
+	Subtotal                float64   `json:"subtotal" bson:"subtotal"` // Harga sebelum diskon
+	DeliveryFee             float64   `json:"delivery_fee" bson:"delivery_fee"` // Ongkos antar-jemput, sudah termasuk di TotalPrice
+	Discounts               []TransactionDiscount `json:"discounts,omitempty" bson:"discounts,omitempty"`
+	PromoCode               string    `json:"promo_code,omitempty" bson:"-"` // Hanya untuk input saat membuat transaksi
 	TotalPrice              float64   `json:"total_price" bson:"total_price"`
 	PaymentMethod           string    `json:"payment_method" bson:"payment_method"`
+	AmountPaid              float64   `json:"amount_paid" bson:"amount_paid"`
+	Express                 bool      `json:"express" bson:"express"`
+	Status                  string    `json:"status" bson:"status,omitempty"` // Lihat Status* di controllers
+	EstimatedReadyAt        *time.Time `json:"estimated_ready_at,omitempty" bson:"estimated_ready_at,omitempty"`
+	ReadyAt                 *time.Time `json:"ready_at,omitempty" bson:"ready_at,omitempty"`
+	PickedUpAt              *time.Time `json:"picked_up_at,omitempty" bson:"picked_up_at,omitempty"`
+	ReceiptPrintCount       int       `json:"receipt_print_count" bson:"receipt_print_count"` // Berapa kali nota sudah dicetak
+	RedeemPoints            int       `json:"redeem_points,omitempty" bson:"-"` // Hanya untuk input: poin yang ingin ditukar
+	PointsRedeemed          int       `json:"points_redeemed" bson:"points_redeemed"`
+	PointsEarned            int       `json:"points_earned" bson:"points_earned"`
+	LoyaltyAwarded          bool      `json:"loyalty_awarded" bson:"loyalty_awarded"`
+	UsePackage              string    `json:"use_package,omitempty" bson:"-"` // Hanya untuk input: "kilo" atau "deposit"
+	PackageDebits           []PackageDebit `json:"package_debits,omitempty" bson:"package_debits,omitempty"`
+	PaymentRefs             []string  `json:"-" bson:"payment_refs,omitempty"` // Referensi pembayaran yang sudah dijumlahkan ke AmountPaid
+	Cancellation            *TransactionCancellation `json:"cancellation,omitempty" bson:"cancellation,omitempty"`
+	TaxName                 string    `json:"tax_name,omitempty" bson:"tax_name,omitempty"`
+	TaxRate                 float64   `json:"tax_rate" bson:"tax_rate"` // Tarif pajak (%) saat pesanan dibuat
+	TaxInclusive            bool      `json:"tax_inclusive" bson:"tax_inclusive"`
+	TaxBase                 float64   `json:"tax_base" bson:"tax_base"`     // Dasar pengenaan pajak (DPP)
+	TaxAmount               float64   `json:"tax_amount" bson:"tax_amount"` // Sudah termasuk di TotalPrice
+	TaxLines                []TaxLine `json:"tax_lines,omitempty" bson:"tax_lines,omitempty"`
+	RemindersSent           int       `json:"reminders_sent,omitempty" bson:"reminders_sent,omitempty"` // Pengingat cucian belum diambil
+	StorageFee              float64   `json:"storage_fee,omitempty" bson:"storage_fee,omitempty"`       // Biaya simpan, sudah termasuk di TotalPrice
+	StorageFeeDays          int       `json:"storage_fee_days,omitempty" bson:"storage_fee_days,omitempty"`
+	LoadID                  string    `json:"load_id,omitempty" bson:"load_id,omitempty"` // Muatan cuci yang sedang diproses
+	ConsumablesDeducted     bool      `json:"consumables_deducted,omitempty" bson:"consumables_deducted,omitempty"` // Bahan sesuai resep sudah dipotong dari stok
+	ConsumableItems         []string  `json:"-" bson:"consumable_items,omitempty"` // Item resep yang sudah dipotong, agar pemotongan yang gagal bisa dilanjutkan
+	ScheduleID              string    `json:"schedule_id,omitempty" bson:"schedule_id,omitempty"` // Jadwal jemput berulang yang membuat pesanan ini
+	ScheduledFor            string    `json:"scheduled_for,omitempty" bson:"scheduled_for,omitempty"` // Tanggal jadwal, yyyy-mm-dd
+	AccountID               string    `json:"account_id,omitempty" bson:"account_id,omitempty"` // Akun korporat jika pesanan ditagihkan bulanan
+	CorporateInvoiceID      string    `json:"corporate_invoice_id,omitempty" bson:"corporate_invoice_id,omitempty"` // Tagihan bulanan yang memuat pesanan ini
 	TransactionDate         time.Time `json:"-" bson:"transaction_date"` // Tidak di-export ke JSON
 	TransactionDateFormatted string    `json:"transaction_date" bson:"-"` // Hanya untuk respons JSON
 }
 
 
+// PartialPickup records pieces of a ready order handed over before the rest
+type PartialPickup struct {
+	Pieces   int       `json:"pieces" bson:"pieces"`
+	Note     string    `json:"note,omitempty" bson:"note,omitempty"`
+	ByID     string    `json:"by_id" bson:"by_id"`
+	By       string    `json:"by" bson:"by"`
+	PickedAt time.Time `json:"picked_at" bson:"picked_at"`
+}
+
+// TaxLine is the tax of one charged line of an order (the service, a delivery fee, a storage fee)
+type TaxLine struct {
+	Description string  `json:"description" bson:"description"`
+	Amount      float64 `json:"amount" bson:"amount"` // Harga baris sebelum pajak eksklusif
+	Base        float64 `json:"base" bson:"base"`
+	Tax         float64 `json:"tax" bson:"tax"`
+}
+
+// TransactionCancellation records why an order was cancelled, who approved it and what was refunded
+type TransactionCancellation struct {
+	ReasonCode    string     `json:"reason_code" bson:"reason_code"`
+	Note          string     `json:"note" bson:"note"`
+	RefundAmount  float64    `json:"refund_amount" bson:"refund_amount"`
+	RefundMethod  string     `json:"refund_method,omitempty" bson:"refund_method,omitempty"`
+	Status        string     `json:"status" bson:"status"` // "pending_approval", "approved" atau "rejected"
+	RequestedByID string     `json:"requested_by_id" bson:"requested_by_id"`
+	RequestedBy   string     `json:"requested_by" bson:"requested_by"`
+	RequestedAt   time.Time  `json:"requested_at" bson:"requested_at"`
+	ReviewedBy    string     `json:"reviewed_by,omitempty" bson:"reviewed_by,omitempty"`
+	ReviewedAt    *time.Time `json:"reviewed_at,omitempty" bson:"reviewed_at,omitempty"`
+	ReviewNote    string     `json:"review_note,omitempty" bson:"review_note,omitempty"`
+	CompletedAt   *time.Time `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+}
+
 // Payment represents a payment transaction
 type Payment struct {
-	ID          string    `json:"id" bson:"_id,omitempty"`
-	CustomerID  string    `json:"customer_id" bson:"customer_id"`
-	Amount      float64   `json:"amount" bson:"amount"`
-	PaymentType string    `json:"payment_type" bson:"payment_type"` // e.g., "cash", "card"
-	Date        time.Time `json:"date" bson:"date"`
+	ID            string    `json:"id" bson:"_id,omitempty"`
+	TransactionID string    `json:"transaction_id" bson:"transaction_id"`
+	CustomerID    string    `json:"customer_id" bson:"customer_id"`
+	Amount        float64   `json:"amount" bson:"amount"`
+	PaymentType   string    `json:"payment_type" bson:"payment_type"` // e.g., "cash", "card", "qris", "va"
+	Provider      string    `json:"provider,omitempty" bson:"provider,omitempty"`
+	Reference     string    `json:"reference" bson:"reference"` // Unik per pembayaran agar tidak tercatat dua kali
+	RefundDue     bool      `json:"refund_due,omitempty" bson:"refund_due,omitempty"` // Kelebihan bayar yang tidak dikreditkan ke pesanan dan harus dikembalikan
+	Date          time.Time `json:"date" bson:"date"`
+}
+
+// PaymentCharge is a QRIS or virtual-account bill created at the payment gateway for an order's balance
+type PaymentCharge struct {
+	ID            string     `json:"id" bson:"_id,omitempty"`
+	TransactionID string     `json:"transaction_id" bson:"transaction_id"`
+	OrderID       string     `json:"order_id" bson:"order_id"` // ID unik yang dikirim ke gateway
+	Provider      string     `json:"provider" bson:"provider"`
+	ProviderRef   string     `json:"provider_ref" bson:"provider_ref"`
+	Method        string     `json:"method" bson:"method"` // "qris" atau "va"
+	Bank          string     `json:"bank,omitempty" bson:"bank,omitempty"`
+	Amount        float64    `json:"amount" bson:"amount"`
+	QRString      string     `json:"qr_string,omitempty" bson:"qr_string,omitempty"`
+	VANumber      string     `json:"va_number,omitempty" bson:"va_number,omitempty"`
+	Status        string     `json:"status" bson:"status"` // "pending", "paid", "expired" atau "failed"
+	ExpiresAt     *time.Time `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at" bson:"created_at"`
+	PaidAt        *time.Time `json:"paid_at,omitempty" bson:"paid_at,omitempty"`
+}
+
+// Counter is an atomically incremented sequence, e.g. for invoice numbers per outlet per day
+type Counter struct {
+	ID  string `json:"id" bson:"_id"`
+	Seq int64  `json:"seq" bson:"seq"`
+}
+
+// TransactionDiscount is a discount line applied to a transaction
+type TransactionDiscount struct {
+	PromotionID string  `json:"promotion_id,omitempty" bson:"promotion_id,omitempty"`
+	Code        string  `json:"code,omitempty" bson:"code,omitempty"`
+	Description string  `json:"description" bson:"description"`
+	Amount      float64 `json:"amount" bson:"amount"`
+}
+
+// Promotion represents a discount campaign redeemable with a code
+type Promotion struct {
+	ID               string     `json:"id" bson:"_id,omitempty"`
+	Code             string     `json:"code" bson:"code"`
+	Name             string     `json:"name" bson:"name"`
+	DiscountType     string     `json:"discount_type" bson:"discount_type"`   // "percentage" or "fixed"
+	DiscountValue    float64    `json:"discount_value" bson:"discount_value"` // Persen (0-100) atau rupiah
+	MaxDiscount      float64    `json:"max_discount" bson:"max_discount"`     // Batas diskon persentase, 0 = tanpa batas
+	MinSpend         float64    `json:"min_spend" bson:"min_spend"`
+	EligibleServices []string   `json:"eligible_services" bson:"eligible_services"` // Kosong = semua layanan
+	ValidFrom        *time.Time `json:"valid_from,omitempty" bson:"valid_from,omitempty"`
+	ValidUntil       *time.Time `json:"valid_until,omitempty" bson:"valid_until,omitempty"`
+	UsageLimit       int        `json:"usage_limit" bson:"usage_limit"`               // 0 = tanpa batas
+	PerCustomerLimit int        `json:"per_customer_limit" bson:"per_customer_limit"` // 0 = tanpa batas
+	UsageCount       int        `json:"usage_count" bson:"usage_count"`
+	Active           bool       `json:"active" bson:"active"`
+}
+
+// PromotionUsage records one redemption of a promotion
+type PromotionUsage struct {
+	ID             string    `json:"id" bson:"_id,omitempty"`
+	PromotionID    string    `json:"promotion_id" bson:"promotion_id"`
+	Code           string    `json:"code" bson:"code"`
+	TransactionID  string    `json:"transaction_id" bson:"transaction_id"`
+	CustomerKey    string    `json:"customer_key" bson:"customer_key"` // ID pelanggan, atau nomor telepon jika tidak ada
+	DiscountAmount float64   `json:"discount_amount" bson:"discount_amount"`
+	Date           time.Time `json:"date" bson:"date"`
+}
+
+// LoyaltyEntry is one movement in a customer's loyalty points ledger
+type LoyaltyEntry struct {
+	ID            string    `json:"id" bson:"_id,omitempty"`
+	CustomerID    string    `json:"customer_id" bson:"customer_id"`
+	TransactionID string    `json:"transaction_id,omitempty" bson:"transaction_id,omitempty"`
+	Type          string    `json:"type" bson:"type"`     // "earn", "redeem", "reverse"
+	Points        int       `json:"points" bson:"points"` // Positif = bertambah, negatif = berkurang
+	Description   string    `json:"description" bson:"description"`
+	Date          time.Time `json:"date" bson:"date"`
+}
+
+// Package is a prepaid product sold to customers, e.g. "Paket 50 kg" or a deposit top-up
+type Package struct {
+	ID           string  `json:"id" bson:"_id,omitempty"`
+	Name         string  `json:"name" bson:"name"`
+	Type         string  `json:"type" bson:"type"`           // "kilo" atau "deposit"
+	Kilograms    float64 `json:"kilograms" bson:"kilograms"` // Kuota kg untuk paket kilo
+	Credit       float64 `json:"credit" bson:"credit"`       // Saldo rupiah untuk paket deposit (boleh lebih dari harga sebagai bonus)
+	Price        float64 `json:"price" bson:"price"`
+	ValidityDays int     `json:"validity_days" bson:"validity_days"` // 0 = tidak kedaluwarsa
+	Active       bool    `json:"active" bson:"active"`
+}
+
+// CustomerPackage is a package bought by a customer together with its remaining balance
+type CustomerPackage struct {
+	ID                 string     `json:"id" bson:"_id,omitempty"`
+	CustomerID         string     `json:"customer_id" bson:"customer_id"`
+	PackageID          string     `json:"package_id" bson:"package_id"`
+	PackageName        string     `json:"package_name" bson:"package_name"`
+	Type               string     `json:"type" bson:"type"`
+	KilogramsTotal     float64    `json:"kilograms_total" bson:"kilograms_total"`
+	KilogramsRemaining float64    `json:"kilograms_remaining" bson:"kilograms_remaining"`
+	CreditTotal        float64    `json:"credit_total" bson:"credit_total"`
+	CreditRemaining    float64    `json:"credit_remaining" bson:"credit_remaining"`
+	PricePaid          float64    `json:"price_paid" bson:"price_paid"`
+	PaymentMethod      string     `json:"payment_method" bson:"payment_method"`
+	PurchasedAt        time.Time  `json:"purchased_at" bson:"purchased_at"`
+	ExpiresAt          *time.Time `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+}
+
+// PackageDebit records how much of a customer package was used by a transaction
+type PackageDebit struct {
+	CustomerPackageID string  `json:"customer_package_id" bson:"customer_package_id"`
+	PackageName       string  `json:"package_name" bson:"package_name"`
+	Kilograms         float64 `json:"kilograms" bson:"kilograms"`
+	Credit            float64 `json:"credit" bson:"credit"`
+	Amount            float64 `json:"amount" bson:"amount"` // Nilai rupiah yang ditutup oleh paket
+}
+
+// WalletEntry is one movement in a customer's prepaid balance statement
+type WalletEntry struct {
+	ID                string    `json:"id" bson:"_id,omitempty"`
+	CustomerID        string    `json:"customer_id" bson:"customer_id"`
+	CustomerPackageID string    `json:"customer_package_id" bson:"customer_package_id"`
+	TransactionID     string    `json:"transaction_id,omitempty" bson:"transaction_id,omitempty"`
+	Type              string    `json:"type" bson:"type"`           // "purchase", "debit", "refund"
+	Kilograms         float64   `json:"kilograms" bson:"kilograms"` // Positif = bertambah, negatif = berkurang
+	Credit            float64   `json:"credit" bson:"credit"`
+	Description       string    `json:"description" bson:"description"`
+	Date              time.Time `json:"date" bson:"date"`
+}
+
+// DeliveryZone is an area with a fixed pickup/delivery fee
+type DeliveryZone struct {
+	ID   string  `json:"id" bson:"_id,omitempty"`
+	Name string  `json:"name" bson:"name"`
+	Fee  float64 `json:"fee" bson:"fee"`
+}
+
+// DeliveryJob is a pickup or delivery (antar-jemput) trip for a transaction
+type DeliveryJob struct {
+	ID            string     `json:"id" bson:"_id,omitempty"`
+	TransactionID string     `json:"transaction_id" bson:"transaction_id"`
+	InvoiceNumber string     `json:"invoice_number" bson:"invoice_number"`
+	Type          string     `json:"type" bson:"type"` // "pickup" atau "delivery"
+	CustomerName  string     `json:"customer_name" bson:"customer_name"`
+	PhoneNumber   string     `json:"phone_number" bson:"phone_number"`
+	Address       string     `json:"address" bson:"address"`
+	ZoneID        string     `json:"zone_id" bson:"zone_id"`
+	ZoneName      string     `json:"zone_name" bson:"zone_name"`
+	Fee           float64    `json:"fee" bson:"fee"`
+	WindowStart   time.Time  `json:"window_start" bson:"window_start"`
+	WindowEnd     time.Time  `json:"window_end" bson:"window_end"`
+	CourierID     string     `json:"courier_id" bson:"courier_id"`
+	CourierName   string     `json:"courier_name" bson:"courier_name"`
+	Status        string     `json:"status" bson:"status"` // "scheduled", "en_route", "done", "failed"
+	Notes         string     `json:"notes" bson:"notes"`
+	FailureReason string     `json:"failure_reason,omitempty" bson:"failure_reason,omitempty"`
+	CreatedAt     time.Time  `json:"created_at" bson:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at" bson:"updated_at"`
+	CompletedAt   *time.Time `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+}
+
+// Service is a laundry service offered by the outlet, matched to Transaction.ServiceType by name
+
+type Service struct {
+	ID                     string       `json:"id" bson:"_id,omitempty"`
+	Name                   string       `json:"name" bson:"name"`
+	PricePerKg             float64      `json:"price_per_kg" bson:"price_per_kg"`
+	TurnaroundHours        float64      `json:"turnaround_hours" bson:"turnaround_hours"`                 // Jam kerja normal sampai selesai
+	ExpressTurnaroundHours float64      `json:"express_turnaround_hours" bson:"express_turnaround_hours"` // 0 = tidak ada layanan express
+	WashTemperature        int          `json:"wash_temperature" bson:"wash_temperature"`                 // Suhu cuci (°C); 0 = tidak ditentukan
+	TaxRate                *float64     `json:"tax_rate,omitempty" bson:"tax_rate,omitempty"`             // Tarif pajak khusus (%); kosong = tarif default, 0 = bebas pajak
+	Recipe                 []RecipeLine `json:"recipe,omitempty" bson:"recipe,omitempty"`                 // Bahan habis pakai per kg cucian
+}
+
+// RecipeLine is how much of a consumable a service uses per kilogram of laundry
+type RecipeLine struct {
+	ItemID        string  `json:"item_id" bson:"item_id"`
+	ItemName      string  `json:"item_name" bson:"item_name"`
+	QuantityPerKg float64 `json:"quantity_per_kg" bson:"quantity_per_kg"` // Dalam satuan stok barang, mis. 15 (gram)
+}
+
+// Holiday is a date on which the outlet is closed
+type Holiday struct {
+	ID   string    `json:"id" bson:"_id,omitempty"`
+	Date time.Time `json:"date" bson:"date"`
+	Name string    `json:"name" bson:"name"`
+}
+
+// Notification is an outbox entry: a rendered customer message waiting to be sent or already sent.
+// It is written before sending so that nothing is lost if the process dies mid-send.
+type Notification struct {
+	ID            string                `json:"id" bson:"_id,omitempty"`
+	TransactionID string                `json:"transaction_id" bson:"transaction_id"`
+	InvoiceNumber string                `json:"invoice_number" bson:"invoice_number"`
+	Event         string                `json:"event" bson:"event"`     // "received", "ready", "delivered" atau "unclaimed"
+	Channel       string                `json:"channel" bson:"channel"` // "whatsapp", "sms", "email" atau "log"
+	Recipient     string                `json:"recipient" bson:"recipient"`
+	Subject       string                `json:"subject" bson:"subject"`
+	Body          string                `json:"body" bson:"body"`
+	Status        string                `json:"status" bson:"status"` // "pending", "sending", "sent" atau "failed"
+	Attempts      int                   `json:"attempts" bson:"attempts"`
+	NextAttemptAt time.Time             `json:"next_attempt_at" bson:"next_attempt_at"`
+	LockedUntil   *time.Time            `json:"-" bson:"locked_until,omitempty"`
+	LastError     string                `json:"last_error,omitempty" bson:"last_error,omitempty"`
+	History       []NotificationAttempt `json:"history" bson:"history"`
+	CreatedAt     time.Time             `json:"created_at" bson:"created_at"`
+	SentAt        *time.Time            `json:"sent_at,omitempty" bson:"sent_at,omitempty"`
+}
+
+// NotificationAttempt is one send attempt in a notification's delivery log
+type NotificationAttempt struct {
+	At    time.Time `json:"at" bson:"at"`
+	Error string    `json:"error,omitempty" bson:"error,omitempty"`
+}
+
+// NotificationTemplate overrides the default message for an event. Subject and Body are
+// Go text/template strings, e.g. "Cucian {{.InvoiceNumber}} sudah siap diambil".
+type NotificationTemplate struct {
+	ID        string    `json:"id" bson:"_id,omitempty"`
+	Event     string    `json:"event" bson:"event"`
+	Subject   string    `json:"subject" bson:"subject"`
+	Body      string    `json:"body" bson:"body"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// Shift is one cashier's session at the cash drawer, from opening float to counted closing cash
+type Shift struct {
+	ID           string     `json:"id" bson:"_id,omitempty"`
+	CashierID    string     `json:"cashier_id" bson:"cashier_id"`
+	CashierName  string     `json:"cashier_name" bson:"cashier_name"`
+	OutletCode   string     `json:"outlet_code,omitempty" bson:"outlet_code,omitempty"`
+	Status       string     `json:"status" bson:"status"`               // "open" atau "closed"
+	OpeningFloat float64    `json:"opening_float" bson:"opening_float"` // Modal awal di laci
+	OpenedAt     time.Time  `json:"opened_at" bson:"opened_at"`
+	ClosedAt     *time.Time `json:"closed_at,omitempty" bson:"closed_at,omitempty"`
+	CashSales    float64    `json:"cash_sales" bson:"cash_sales"`
+	CashIn       float64    `json:"cash_in" bson:"cash_in"`
+	CashOut      float64    `json:"cash_out" bson:"cash_out"`
+	ExpectedCash float64    `json:"expected_cash" bson:"expected_cash"`
+	CountedCash  float64    `json:"counted_cash" bson:"counted_cash"`
+	Variance     float64    `json:"variance" bson:"variance"` // Dihitung - seharusnya; negatif berarti kurang
+	Notes        string     `json:"notes" bson:"notes"`
+}
+
+// CashMovement is money entering or leaving the drawer during a shift
+type CashMovement struct {
+	ID            string    `json:"id" bson:"_id,omitempty"`
+	ShiftID       string    `json:"shift_id" bson:"shift_id"`
+	Type          string    `json:"type" bson:"type"` // "sale", "cash_in" atau "cash_out"
+	Amount        float64   `json:"amount" bson:"amount"`
+	Description   string    `json:"description" bson:"description"`
+	TransactionID string    `json:"transaction_id,omitempty" bson:"transaction_id,omitempty"`
+	CreatedBy     string    `json:"created_by" bson:"created_by"`
+	CreatedAt     time.Time `json:"created_at" bson:"created_at"`
+}
+
+// Claim is a customer complaint about a damaged or lost garment
+type Claim struct {
+	ID                 string       `json:"id" bson:"_id,omitempty"`
+	TransactionID      string       `json:"transaction_id" bson:"transaction_id"`
+	InvoiceNumber      string       `json:"invoice_number" bson:"invoice_number"`
+	CustomerID         string       `json:"customer_id,omitempty" bson:"customer_id,omitempty"`
+	CustomerName       string       `json:"customer_name" bson:"customer_name"`
+	Type               string       `json:"type" bson:"type"`                                   // "damage" atau "loss"
+	GarmentTag         string       `json:"garment_tag,omitempty" bson:"garment_tag,omitempty"` // Opsional: label pakaian tertentu
+	GarmentDescription string       `json:"garment_description,omitempty" bson:"garment_description,omitempty"`
+	Description        string       `json:"description" bson:"description"`
+	Photos             []string     `json:"photos" bson:"photos"`
+	ClaimedAmount      float64      `json:"claimed_amount" bson:"claimed_amount"`
+	ApprovedAmount     float64      `json:"approved_amount" bson:"approved_amount"`
+	Status             string       `json:"status" bson:"status"` // "open", "investigating", "approved", "rejected" atau "paid"
+	HandledByID        string       `json:"handled_by_id,omitempty" bson:"handled_by_id,omitempty"`
+	HandledBy          string       `json:"handled_by,omitempty" bson:"handled_by,omitempty"` // Karyawan yang menangani pesanan
+	ReportedBy         string       `json:"reported_by" bson:"reported_by"`
+	PaymentMethod      string       `json:"payment_method,omitempty" bson:"payment_method,omitempty"`
+	PaidAt             *time.Time   `json:"paid_at,omitempty" bson:"paid_at,omitempty"`
+	History            []ClaimEvent `json:"history" bson:"history"`
+	CreatedAt          time.Time    `json:"created_at" bson:"created_at"`
+	UpdatedAt          time.Time    `json:"updated_at" bson:"updated_at"`
+}
+
+// ClaimEvent is one status change of a claim
+type ClaimEvent struct {
+	Status string    `json:"status" bson:"status"`
+	By     string    `json:"by" bson:"by"`
+	Note   string    `json:"note,omitempty" bson:"note,omitempty"`
+	At     time.Time `json:"at" bson:"at"`
+}
+
+// DisposalRecord is the audit trail of an unclaimed order marked for donation or disposal
+type DisposalRecord struct {
+	ID            string     `json:"id" bson:"_id,omitempty"`
+	TransactionID string     `json:"transaction_id" bson:"transaction_id"`
+	InvoiceNumber string     `json:"invoice_number" bson:"invoice_number"`
+	CustomerName  string     `json:"customer_name" bson:"customer_name"`
+	PhoneNumber   string     `json:"phone_number" bson:"phone_number"`
+	ReadyAt       time.Time  `json:"ready_at" bson:"ready_at"`
+	DaysUnclaimed int        `json:"days_unclaimed" bson:"days_unclaimed"`
+	RemindersSent int        `json:"reminders_sent" bson:"reminders_sent"`
+	BalanceDue    float64    `json:"balance_due" bson:"balance_due"`
+	Status        string     `json:"status" bson:"status"` // "marked", "donated", "disposed" atau "returned"
+	MarkedAt      time.Time  `json:"marked_at" bson:"marked_at"`
+	CompletedBy   string     `json:"completed_by,omitempty" bson:"completed_by,omitempty"`
+	CompletedAt   *time.Time `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+	Note          string     `json:"note,omitempty" bson:"note,omitempty"`
+}
+
+// Attachment is a photo uploaded for a transaction or a claim; the file itself lives in blob storage
+type Attachment struct {
+	ID           string    `json:"id" bson:"_id,omitempty"`
+	OwnerType    string    `json:"owner_type" bson:"owner_type"` // "transaction" atau "claim"
+	OwnerID      string    `json:"owner_id" bson:"owner_id"`
+	Key          string    `json:"-" bson:"key"`
+	ThumbnailKey string    `json:"-" bson:"thumbnail_key"`
+	FileName     string    `json:"file_name" bson:"file_name"`
+	ContentType  string    `json:"content_type" bson:"content_type"`
+	Size         int       `json:"size" bson:"size"`
+	Width        int       `json:"width" bson:"width"`
+	Height       int       `json:"height" bson:"height"`
+	Note         string    `json:"note,omitempty" bson:"note,omitempty"` // Mis. "noda di kerah"
+	UploadedBy   string    `json:"uploaded_by" bson:"uploaded_by"`
+	CreatedAt    time.Time `json:"created_at" bson:"created_at"`
+	URL          string    `json:"url,omitempty" bson:"-"`
+	ThumbnailURL string    `json:"thumbnail_url,omitempty" bson:"-"`
+}
+
+// Machine is a washer or dryer on the production floor
+type Machine struct {
+	ID             string    `json:"id" bson:"_id,omitempty"`
+	Code           string    `json:"code" bson:"code"` // Kode pada mesin, mis. "W1" atau "D3"
+	Name           string    `json:"name" bson:"name"`
+	Type           string    `json:"type" bson:"type"` // "washer" atau "dryer"
+	CapacityKg     float64   `json:"capacity_kg" bson:"capacity_kg"`
+	Status         string    `json:"status" bson:"status"` // "available", "running" atau "maintenance"
+	CurrentCycleID string    `json:"current_cycle_id,omitempty" bson:"current_cycle_id,omitempty"`
+	CreatedAt      time.Time `json:"created_at" bson:"created_at"`
+}
+
+// MachineCycle is one run of a machine with the orders in its load
+type MachineCycle struct {
+	ID             string     `json:"id" bson:"_id,omitempty"`
+	MachineID      string     `json:"machine_id" bson:"machine_id"`
+	MachineCode    string     `json:"machine_code" bson:"machine_code"`
+	MachineType    string     `json:"machine_type" bson:"machine_type"`
+	TransactionIDs []string   `json:"transaction_ids" bson:"transaction_ids"`
+	InvoiceNumbers []string   `json:"invoice_numbers" bson:"invoice_numbers"`
+	LoadKg         float64    `json:"load_kg" bson:"load_kg"`
+	Program        string     `json:"program,omitempty" bson:"program,omitempty"` // Mis. "cold 30°C"
+	Status         string     `json:"status" bson:"status"`                       // "running" atau "finished"
+	StartedBy      string     `json:"started_by" bson:"started_by"`
+	StartedAt      time.Time  `json:"started_at" bson:"started_at"`
+	FinishedBy     string     `json:"finished_by,omitempty" bson:"finished_by,omitempty"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty" bson:"finished_at,omitempty"`
+}
+
+// MaintenanceLog records a period in which a machine was out of service
+type MaintenanceLog struct {
+	ID        string     `json:"id" bson:"_id,omitempty"`
+	MachineID string     `json:"machine_id" bson:"machine_id"`
+	Reason    string     `json:"reason" bson:"reason"`
+	Notes     string     `json:"notes,omitempty" bson:"notes,omitempty"` // Catatan perbaikan saat selesai
+	Cost      float64    `json:"cost" bson:"cost"`
+	StartedBy string     `json:"started_by" bson:"started_by"`
+	StartedAt time.Time  `json:"started_at" bson:"started_at"`
+	EndedBy   string     `json:"ended_by,omitempty" bson:"ended_by,omitempty"`
+	EndedAt   *time.Time `json:"ended_at,omitempty" bson:"ended_at,omitempty"`
+}
+
+// Load groups compatible orders that are washed, dried and ironed together
+type Load struct {
+	ID             string     `json:"id" bson:"_id,omitempty"`
+	Code           string     `json:"code" bson:"code"` // Mis. L20261019-03, ditulis di keranjang
+	ServiceType    string     `json:"service_type" bson:"service_type"`
+	Temperature    int        `json:"temperature" bson:"temperature"`
+	ColorGroup     string     `json:"color_group" bson:"color_group"` // Mis. "white", "light", "dark"
+	Fabric         string     `json:"fabric,omitempty" bson:"fabric,omitempty"`
+	TransactionIDs []string   `json:"transaction_ids" bson:"transaction_ids"`
+	InvoiceNumbers []string   `json:"invoice_numbers" bson:"invoice_numbers"`
+	TotalKg        float64    `json:"total_kg" bson:"total_kg"`
+	Stage          string     `json:"stage" bson:"stage"`   // Status pesanan yang sedang dijalani muatan
+	Status         string     `json:"status" bson:"status"` // "open" atau "closed"
+	MachineID      string     `json:"machine_id,omitempty" bson:"machine_id,omitempty"`
+	CycleID        string     `json:"cycle_id,omitempty" bson:"cycle_id,omitempty"`
+	CreatedBy      string     `json:"created_by" bson:"created_by"`
+	CreatedAt      time.Time  `json:"created_at" bson:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" bson:"updated_at"`
+	ClosedAt       *time.Time `json:"closed_at,omitempty" bson:"closed_at,omitempty"`
+}
+
+// Revision records one change made to a document through an update endpoint
+type Revision struct {
+	ID         string        `json:"id" bson:"_id,omitempty"`
+	Collection string        `json:"collection" bson:"collection"` // Mis. "transaction", "customer"
+	DocumentID string        `json:"document_id" bson:"document_id"`
+	Number     int           `json:"number" bson:"number"` // Revisi ke-n dari dokumen ini
+	Changes    []FieldChange `json:"changes" bson:"changes"`
+	EditedByID string        `json:"edited_by_id" bson:"edited_by_id"`
+	EditedBy   string        `json:"edited_by" bson:"edited_by"`
+	EditedAt   time.Time     `json:"edited_at" bson:"edited_at"`
+}
+
+// FieldChange is the before and after value of one field in a revision
+type FieldChange struct {
+	Field string      `json:"field" bson:"field"`
+	Old   interface{} `json:"old" bson:"old"`
+	New   interface{} `json:"new" bson:"new"`
+}
+
+// RecurringSchedule is a standing pickup order of a business customer, e.g. hotel linen every
+// Monday and Thursday. The scheduler turns each occurrence into a pickup job and a draft order.
+
+type RecurringSchedule struct {
+	ID                  string     `json:"id" bson:"_id,omitempty"`
+	CustomerID          string     `json:"customer_id" bson:"customer_id"`
+	CustomerName        string     `json:"customer_name" bson:"customer_name"`
+	PhoneNumber         string     `json:"phone_number" bson:"phone_number"`
+	Address             string     `json:"address" bson:"address"`
+	ZoneID              string     `json:"zone_id" bson:"zone_id"`
+	Weekdays            []int      `json:"weekdays" bson:"weekdays"`         // 0 = Minggu, 1 = Senin, ...
+	WindowStart         string     `json:"window_start" bson:"window_start"` // Jam jemput "HH:MM"
+	WindowEnd           string     `json:"window_end" bson:"window_end"`
+	CourierID           string     `json:"courier_id,omitempty" bson:"courier_id,omitempty"`
+	ServiceType         string     `json:"service_type" bson:"service_type"`
+	Items               []string   `json:"items,omitempty" bson:"items,omitempty"`
+	Express             bool       `json:"express" bson:"express"`
+	SpecialInstructions string     `json:"special_instructions,omitempty" bson:"special_instructions,omitempty"`
+	StartDate           string     `json:"start_date" bson:"start_date"` // yyyy-mm-dd
+	EndDate             string     `json:"end_date,omitempty" bson:"end_date,omitempty"`
+	SkipDates           []string   `json:"skip_dates,omitempty" bson:"skip_dates,omitempty"`     // Tanggal libur pelanggan, yyyy-mm-dd
+	Status              string     `json:"status" bson:"status"`                                 // "active" atau "paused"
+	PausedUntil         string     `json:"paused_until,omitempty" bson:"paused_until,omitempty"` // Kosong = dijeda sampai dilanjutkan
+	CreatedBy           string     `json:"created_by" bson:"created_by"`
+	CreatedAt           time.Time  `json:"created_at" bson:"created_at"`
+	LastRunAt           *time.Time `json:"last_run_at,omitempty" bson:"last_run_at,omitempty"`
+}
+
+// CorporateAccount is a business client that is billed monthly instead of paying per order
+
+
+type CorporateAccount struct {
+	ID              string    `json:"id" bson:"_id,omitempty"`
+	Code            string    `json:"code" bson:"code"` // Kode singkat untuk nomor tagihan, mis. "HTLMAWAR"
+	Name            string    `json:"name" bson:"name"`
+	ContactName     string    `json:"contact_name" bson:"contact_name"`
+	Phone           string    `json:"phone" bson:"phone"`
+	Email           string    `json:"email" bson:"email"`
+	BillingAddress  string    `json:"billing_address" bson:"billing_address"`
+	TaxID           string    `json:"tax_id,omitempty" bson:"tax_id,omitempty"`   // NPWP
+	CreditLimit     float64   `json:"credit_limit" bson:"credit_limit"`           // 0 = tanpa batas
+	Outstanding     float64   `json:"outstanding" bson:"outstanding"`             // Sisa tagihan pesanan yang dibebankan, dipakai untuk cek limit
+	PaymentTermDays int       `json:"payment_term_days" bson:"payment_term_days"` // Jatuh tempo, mis. 30 hari setelah tagihan
+	Status          string    `json:"status" bson:"status"`                       // "active" atau "suspended"
+	CreatedAt       time.Time `json:"created_at" bson:"created_at"`
+}
+
+// CorporateInvoice consolidates the orders of a corporate account in one month
+
+type CorporateInvoice struct {
+	ID          string                 `json:"id" bson:"_id,omitempty"`
+	Number      string                 `json:"number" bson:"number"` // Mis. INV-HTLMAWAR-202610
+	AccountID   string                 `json:"account_id" bson:"account_id"`
+	AccountName string                 `json:"account_name" bson:"account_name"`
+	Period      string                 `json:"period" bson:"period"` // yyyy-mm
+	Lines       []CorporateInvoiceLine `json:"lines" bson:"lines"`
+	TaxBase     float64                `json:"tax_base" bson:"tax_base"`
+	TaxAmount   float64                `json:"tax_amount" bson:"tax_amount"`
+	Total       float64                `json:"total" bson:"total"`
+	AmountPaid  float64                `json:"amount_paid" bson:"amount_paid"`
+	Status      string                 `json:"status" bson:"status"` // "open" atau "paid"
+	IssuedAt    time.Time              `json:"issued_at" bson:"issued_at"`
+	DueDate     time.Time              `json:"due_date" bson:"due_date"`
+	PaidAt      *time.Time             `json:"paid_at,omitempty" bson:"paid_at,omitempty"`
+	PaymentRefs []string               `json:"-" bson:"payment_refs,omitempty"`
+}
+
+// CorporateInvoiceLine is one order on a consolidated invoice
+type CorporateInvoiceLine struct {
+	TransactionID   string    `json:"transaction_id" bson:"transaction_id"`
+	InvoiceNumber   string    `json:"invoice_number" bson:"invoice_number"`
+	TransactionDate time.Time `json:"transaction_date" bson:"transaction_date"`
+	CustomerName    string    `json:"customer_name" bson:"customer_name"`
+	ServiceType     string    `json:"service_type" bson:"service_type"`
+	WeightPerKg     float64   `json:"weight_per_kg" bson:"weight_per_kg"`
+	Amount          float64   `json:"amount" bson:"amount"` // Sisa tagihan pesanan saat tagihan dibuat
 }
@@ -0,0 +1,72 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"apkclaundry/config"
+)
+
+// Email sends plain-text messages over SMTP
+type Email struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// NewEmail configures SMTP from SMTP_HOST, SMTP_PORT, SMTP_USERNAME, SMTP_PASSWORD and SMTP_FROM
+func NewEmail() *Email {
+	return &Email{
+		Host:     config.GetEnv("SMTP_HOST", ""),
+		Port:     config.GetEnv("SMTP_PORT", "587"),
+		Username: config.GetEnv("SMTP_USERNAME", ""),
+		Password: config.GetEnv("SMTP_PASSWORD", ""),
+		From:     config.GetEnv("SMTP_FROM", ""),
+	}
+}
+
+func (p *Email) Channel() string { return ChannelEmail }
+
+func (p *Email) Send(ctx context.Context, message Message) error {
+	if p.Host == "" || p.From == "" {
+		return errors.New("SMTP_HOST and SMTP_FROM must be set")
+	}
+	if strings.ContainsAny(message.To, "\r\n") {
+		return errors.New("invalid recipient address")
+	}
+
+	headers := []string{
+		"From: " + p.From,
+		"To: " + message.To,
+		"Subject: " + mime.QEncoding.Encode("utf-8", message.Subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=utf-8",
+	}
+	body := strings.Join(headers, "\r\n") + "\r\n\r\n" + message.Body
+
+	var auth smtp.Auth
+	if p.Username != "" {
+		auth = smtp.PlainAuth("", p.Username, p.Password, p.Host)
+	}
+
+	// smtp.SendMail tidak menerima context, jadi jalankan terpisah dan hormati batas waktunya
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(net.JoinHostPort(p.Host, p.Port), auth, p.From, []string{message.To}, []byte(body))
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("sending email: %w", ctx.Err())
+	}
+}
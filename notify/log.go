@@ -0,0 +1,46 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"apkclaundry/config"
+)
+
+// Log is the development provider: messages are appended to NOTIFY_LOG_FILE,
+// or written to the application log when no file is configured
+type Log struct {
+	Path string
+}
+
+var logFileMu sync.Mutex
+
+// NewLog configures the provider from NOTIFY_LOG_FILE
+func NewLog() *Log {
+	return &Log{Path: config.GetEnv("NOTIFY_LOG_FILE", "")}
+}
+
+func (p *Log) Channel() string { return ChannelLog }
+
+func (p *Log) Send(ctx context.Context, message Message) error {
+	entry := fmt.Sprintf("[%s] to=%s subject=%q\n%s\n\n", time.Now().Format(time.RFC3339), message.To, message.Subject, message.Body)
+	if p.Path == "" {
+		log.Print("Notification " + entry)
+		return nil
+	}
+
+	logFileMu.Lock()
+	defer logFileMu.Unlock()
+
+	file, err := os.OpenFile(p.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.WriteString(entry)
+	return err
+}
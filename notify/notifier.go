@@ -0,0 +1,85 @@
+// Package notify sends customer notifications through a configurable provider
+package notify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"apkclaundry/config"
+)
+
+// Channel names, also stored on outbox entries
+const (
+	ChannelWhatsApp = "whatsapp"
+	ChannelSMS      = "sms"
+	ChannelEmail    = "email"
+	ChannelLog      = "log"
+)
+
+// Message is one notification to one recipient. To is a phone number or an email address
+// depending on the channel; Subject is only used by email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notifier delivers messages over one channel
+type Notifier interface {
+	Channel() string
+	Send(ctx context.Context, message Message) error
+}
+
+// FromSettings returns the notifier selected by NOTIFY_PROVIDER (whatsapp, sms, email or log).
+// The log provider is the default so development setups never message real customers.
+func FromSettings() (Notifier, error) {
+	switch provider := config.GetEnv("NOTIFY_PROVIDER", ChannelLog); provider {
+	case ChannelWhatsApp:
+		return NewWhatsApp(), nil
+	case ChannelSMS:
+		return NewSMS(), nil
+	case ChannelEmail:
+		return NewEmail(), nil
+	case ChannelLog:
+		return NewLog(), nil
+	default:
+		return nil, fmt.Errorf("unknown notification provider %q", provider)
+	}
+}
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// send executes a provider API request and turns non-2xx responses into errors
+func send(request *http.Request) error {
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("provider returned %s: %s", response.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// NormalizePhone converts a local number such as 0812-3456-789 to international format without
+// the plus sign (628123456789), which is what WhatsApp and SMS gateways expect
+func NormalizePhone(phone string) string {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, phone)
+
+	if strings.HasPrefix(digits, "0") {
+		return config.GetEnv("NOTIFY_COUNTRY_CODE", "62") + digits[1:]
+	}
+	return digits
+}
@@ -0,0 +1,53 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"apkclaundry/config"
+)
+
+// SMS sends messages through an HTTP SMS gateway that accepts a JSON body of
+// {"to", "from", "message"} authenticated with a bearer API key
+type SMS struct {
+	APIURL string
+	APIKey string
+	Sender string
+}
+
+// NewSMS configures the gateway from SMS_API_URL, SMS_API_KEY and SMS_SENDER
+func NewSMS() *SMS {
+	return &SMS{
+		APIURL: config.GetEnv("SMS_API_URL", ""),
+		APIKey: config.GetEnv("SMS_API_KEY", ""),
+		Sender: config.GetEnv("SMS_SENDER", ""),
+	}
+}
+
+func (p *SMS) Channel() string { return ChannelSMS }
+
+func (p *SMS) Send(ctx context.Context, message Message) error {
+	if p.APIURL == "" || p.APIKey == "" {
+		return errors.New("SMS_API_URL and SMS_API_KEY must be set")
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"to":      NormalizePhone(message.To),
+		"from":    p.Sender,
+		"message": message.Body,
+	})
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, p.APIURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Authorization", "Bearer "+p.APIKey)
+	return send(request)
+}
@@ -0,0 +1,46 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"apkclaundry/config"
+)
+
+// WhatsApp sends messages through an HTTP WhatsApp gateway that accepts a form with
+// target and message fields and a token in the Authorization header (e.g. Fonnte)
+type WhatsApp struct {
+	APIURL string
+	Token  string
+}
+
+// NewWhatsApp configures the gateway from WHATSAPP_API_URL and WHATSAPP_API_TOKEN
+func NewWhatsApp() *WhatsApp {
+	return &WhatsApp{
+		APIURL: config.GetEnv("WHATSAPP_API_URL", "https://api.fonnte.com/send"),
+		Token:  config.GetEnv("WHATSAPP_API_TOKEN", ""),
+	}
+}
+
+func (p *WhatsApp) Channel() string { return ChannelWhatsApp }
+
+func (p *WhatsApp) Send(ctx context.Context, message Message) error {
+	if p.Token == "" {
+		return errors.New("WHATSAPP_API_TOKEN is not set")
+	}
+
+	form := url.Values{}
+	form.Set("target", NormalizePhone(message.To))
+	form.Set("message", message.Body)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, p.APIURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", p.Token)
+	return send(request)
+}
@@ -0,0 +1,177 @@
+package payment
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"apkclaundry/config"
+)
+
+// fakeGateway imitates the parts of the Midtrans Core API used by this app: POST /v2/charge
+// creates a charge, POST /v2/{order_id}/expire closes it, and notify builds the signed
+// notification the gateway would send.
+type fakeGateway struct {
+	serverKey string
+
+	mu       sync.Mutex
+	requests []map[string]interface{}
+	charges  map[string]fakeCharge
+	expired  map[string]bool
+}
+
+type fakeCharge struct {
+	TransactionID string
+	OrderID       string
+	PaymentType   string
+	Bank          string
+	Amount        float64
+}
+
+// newFakeGateway starts a fake gateway that accepts serverKey and returns a Midtrans client for it
+func newFakeGateway(t *testing.T, serverKey string) (*fakeGateway, *Midtrans) {
+	t.Helper()
+	gateway := &fakeGateway{serverKey: serverKey, charges: map[string]fakeCharge{}, expired: map[string]bool{}}
+	server := httptest.NewServer(gateway)
+	t.Cleanup(server.Close)
+	return gateway, &Midtrans{APIURL: server.URL, ServerKey: serverKey, client: server.Client()}
+}
+
+func (f *fakeGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	reply := func(body map[string]interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(body)
+	}
+	if r.Method != http.MethodPost || !strings.HasPrefix(r.URL.Path, "/v2/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Seperti Midtrans, error dibalas dengan HTTP 200 dan status_code di body
+	if key, _, ok := r.BasicAuth(); !ok || key != f.serverKey {
+		reply(map[string]interface{}{"status_code": "401", "status_message": "Access denied due to unauthorized transaction"})
+		return
+	}
+	if orderID, found := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/v2/"), "/expire"); found {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if _, ok := f.charges[orderID]; !ok {
+			reply(map[string]interface{}{"status_code": "404", "status_message": "Transaction doesn't exist."})
+			return
+		}
+		statusCode := "407"
+		if !f.expired[orderID] {
+			statusCode = "200"
+			f.expired[orderID] = true
+		}
+		reply(map[string]interface{}{"status_code": statusCode, "order_id": orderID, "transaction_status": "expire"})
+		return
+	}
+	if r.URL.Path != "/v2/charge" {
+		http.NotFound(w, r)
+		return
+	}
+	var request map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		reply(map[string]interface{}{"status_code": "400", "status_message": err.Error()})
+		return
+	}
+	details, _ := request["transaction_details"].(map[string]interface{})
+	orderID, _ := details["order_id"].(string)
+	amount, _ := details["gross_amount"].(float64)
+	paymentType, _ := request["payment_type"].(string)
+	bankTransfer, _ := request["bank_transfer"].(map[string]interface{})
+	bank, _ := bankTransfer["bank"].(string)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, request)
+	if _, taken := f.charges[orderID]; taken {
+		reply(map[string]interface{}{"status_code": "406", "status_message": "Order ID has been used"})
+		return
+	}
+	charge := fakeCharge{
+		TransactionID: "trx-" + strconv.Itoa(len(f.charges)+1),
+		OrderID:       orderID,
+		PaymentType:   paymentType,
+		Bank:          bank,
+		Amount:        amount,
+	}
+	f.charges[orderID] = charge
+
+	response := map[string]interface{}{
+		"status_code":        "201",
+		"status_message":     "Success, transaction is created",
+		"transaction_id":     charge.TransactionID,
+		"order_id":           charge.OrderID,
+		"gross_amount":       formatAmount(charge.Amount),
+		"transaction_status": "pending",
+		"expiry_time":        time.Now().In(config.Location()).Add(30 * time.Minute).Format("2006-01-02 15:04:05"),
+	}
+	if paymentType == "qris" {
+		response["qr_string"] = "00020101021226FAKEQRIS" + charge.TransactionID
+	} else {
+		response["va_numbers"] = []map[string]string{{"bank": bank, "va_number": "88081234567890"}}
+	}
+	reply(response)
+}
+
+// notify returns the webhook request the gateway sends when a charge changes status
+func (f *fakeGateway) notify(t *testing.T, orderID, status string) *http.Request {
+	t.Helper()
+	f.mu.Lock()
+	charge, ok := f.charges[orderID]
+	f.mu.Unlock()
+	if !ok {
+		t.Fatalf("unknown order_id %q", orderID)
+	}
+	return signedNotification(t, f.serverKey, charge.TransactionID, orderID, status, charge.Amount)
+}
+
+// signedNotification builds a notification request signed with serverKey
+func signedNotification(t *testing.T, serverKey, transactionID, orderID, status string, amount float64) *http.Request {
+	t.Helper()
+	statusCode, grossAmount := "200", formatAmount(amount)
+	if status == "expire" || status == "cancel" || status == "deny" {
+		statusCode = "202"
+	}
+	signer := &Midtrans{ServerKey: serverKey}
+	payload, err := json.Marshal(map[string]string{
+		"transaction_id":     transactionID,
+		"order_id":           orderID,
+		"status_code":        statusCode,
+		"gross_amount":       grossAmount,
+		"transaction_status": status,
+		"settlement_time":    time.Now().In(config.Location()).Format("2006-01-02 15:04:05"),
+		"signature_key":      signer.signature(orderID, statusCode, grossAmount),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return httptest.NewRequest(http.MethodPost, "/payment-webhook", bytes.NewReader(payload))
+}
+
+// tamper returns a copy of a notification request with one field replaced, keeping the old signature
+func tamper(t *testing.T, request *http.Request, field, value string) *http.Request {
+	t.Helper()
+	var body map[string]string
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	body[field] = value
+	payload, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return httptest.NewRequest(http.MethodPost, "/payment-webhook", bytes.NewReader(payload))
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
@@ -0,0 +1,200 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"apkclaundry/config"
+)
+
+// Midtrans talks to the Midtrans Core API. PAYMENT_API_URL defaults to the sandbox.
+type Midtrans struct {
+	APIURL    string
+	ServerKey string
+	client    *http.Client
+}
+
+// NewMidtrans configures the provider from PAYMENT_API_URL and PAYMENT_SERVER_KEY
+func NewMidtrans() *Midtrans {
+	return &Midtrans{
+		APIURL:    strings.TrimRight(config.GetEnv("PAYMENT_API_URL", "https://api.sandbox.midtrans.com"), "/"),
+		ServerKey: config.GetEnv("PAYMENT_SERVER_KEY", ""),
+		client:    &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (m *Midtrans) Name() string { return "midtrans" }
+
+// midtransResponse covers the fields of a charge response and of a notification
+type midtransResponse struct {
+	StatusCode        string `json:"status_code"`
+	StatusMessage     string `json:"status_message"`
+	TransactionID     string `json:"transaction_id"`
+	OrderID           string `json:"order_id"`
+	GrossAmount       string `json:"gross_amount"`
+	TransactionStatus string `json:"transaction_status"`
+	FraudStatus       string `json:"fraud_status"`
+	SignatureKey      string `json:"signature_key"`
+	QRString          string `json:"qr_string"`
+	VANumbers         []struct {
+		Bank     string `json:"bank"`
+		VANumber string `json:"va_number"`
+	} `json:"va_numbers"`
+	PermataVANumber string `json:"permata_va_number"`
+	ExpiryTime      string `json:"expiry_time"`
+	SettlementTime  string `json:"settlement_time"`
+}
+
+func (m *Midtrans) CreateCharge(ctx context.Context, request ChargeRequest) (Charge, error) {
+	if m.ServerKey == "" {
+		return Charge{}, errors.New("PAYMENT_SERVER_KEY is not set")
+	}
+
+	body := map[string]interface{}{
+		"transaction_details": map[string]interface{}{
+			"order_id":     request.OrderID,
+			"gross_amount": int64(request.Amount),
+		},
+	}
+	switch request.Method {
+	case MethodQRIS:
+		body["payment_type"] = "qris"
+	case MethodVA:
+		if request.Bank == "" {
+			return Charge{}, errors.New("bank is required for virtual account payments")
+		}
+		body["payment_type"] = "bank_transfer"
+		body["bank_transfer"] = map[string]string{"bank": request.Bank}
+	default:
+		return Charge{}, fmt.Errorf("unsupported payment method %q", request.Method)
+	}
+	if request.Expiry > 0 {
+		body["custom_expiry"] = map[string]interface{}{"expiry_duration": int(request.Expiry.Minutes()), "unit": "minute"}
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return Charge{}, err
+	}
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, m.APIURL+"/v2/charge", bytes.NewReader(payload))
+	if err != nil {
+		return Charge{}, err
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+	httpRequest.Header.Set("Accept", "application/json")
+	httpRequest.SetBasicAuth(m.ServerKey, "")
+
+	httpResponse, err := m.client.Do(httpRequest)
+	if err != nil {
+		return Charge{}, err
+	}
+	defer httpResponse.Body.Close()
+
+	var response midtransResponse
+	if err := json.NewDecoder(httpResponse.Body).Decode(&response); err != nil {
+		return Charge{}, fmt.Errorf("reading charge response: %w", err)
+	}
+	// Midtrans bisa membalas HTTP 200 dengan status_code error di body
+	if response.StatusCode != "201" && response.StatusCode != "200" {
+		return Charge{}, fmt.Errorf("charge rejected (%s): %s", response.StatusCode, response.StatusMessage)
+	}
+
+	charge := Charge{ProviderRef: response.TransactionID, QRString: response.QRString}
+	if len(response.VANumbers) > 0 {
+		charge.VANumber = response.VANumbers[0].VANumber
+	} else if response.PermataVANumber != "" {
+		charge.VANumber = response.PermataVANumber
+	}
+	if expiry, err := time.ParseInLocation("2006-01-02 15:04:05", response.ExpiryTime, config.Location()); err == nil {
+		charge.ExpiresAt = &expiry
+	}
+	return charge, nil
+}
+
+func (m *Midtrans) ExpireCharge(ctx context.Context, orderID string) error {
+	if m.ServerKey == "" {
+		return errors.New("PAYMENT_SERVER_KEY is not set")
+	}
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, m.APIURL+"/v2/"+url.PathEscape(orderID)+"/expire", nil)
+	if err != nil {
+		return err
+	}
+	httpRequest.Header.Set("Accept", "application/json")
+	httpRequest.SetBasicAuth(m.ServerKey, "")
+
+	httpResponse, err := m.client.Do(httpRequest)
+	if err != nil {
+		return err
+	}
+	defer httpResponse.Body.Close()
+
+	var response midtransResponse
+	if err := json.NewDecoder(httpResponse.Body).Decode(&response); err != nil {
+		return fmt.Errorf("reading expire response: %w", err)
+	}
+	// 407 berarti tagihan sudah kedaluwarsa
+	if response.StatusCode != "200" && response.StatusCode != "407" {
+		return fmt.Errorf("expire rejected (%s): %s", response.StatusCode, response.StatusMessage)
+	}
+	return nil
+}
+
+// signature computes SHA512(order_id + status_code + gross_amount + server_key)
+func (m *Midtrans) signature(orderID, statusCode, grossAmount string) string {
+	sum := sha512.Sum512([]byte(orderID + statusCode + grossAmount + m.ServerKey))
+	return hex.EncodeToString(sum[:])
+}
+
+func (m *Midtrans) ParseWebhook(r *http.Request) (Notification, error) {
+	var body midtransResponse
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return Notification{}, fmt.Errorf("invalid notification body: %w", err)
+	}
+
+	expected := m.signature(body.OrderID, body.StatusCode, body.GrossAmount)
+	if m.ServerKey == "" || subtle.ConstantTimeCompare([]byte(expected), []byte(body.SignatureKey)) != 1 {
+		return Notification{}, ErrInvalidSignature
+	}
+
+	amount, err := strconv.ParseFloat(body.GrossAmount, 64)
+	if err != nil {
+		return Notification{}, fmt.Errorf("invalid gross_amount %q", body.GrossAmount)
+	}
+
+	notification := Notification{
+		OrderID:     body.OrderID,
+		ProviderRef: body.TransactionID,
+		Amount:      amount,
+		PaidAt:      time.Now(),
+	}
+	switch body.TransactionStatus {
+	case "settlement":
+		notification.Status = StatusPaid
+	case "capture":
+		notification.Status = StatusPending
+		if body.FraudStatus == "accept" {
+			notification.Status = StatusPaid
+		}
+	case "expire":
+		notification.Status = StatusExpired
+	case "cancel", "deny", "failure":
+		notification.Status = StatusFailed
+	default:
+		notification.Status = StatusPending
+	}
+	if settled, err := time.ParseInLocation("2006-01-02 15:04:05", body.SettlementTime, config.Location()); err == nil {
+		notification.PaidAt = settled
+	}
+	return notification, nil
+}
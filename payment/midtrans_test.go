@@ -0,0 +1,150 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCreateChargeQRIS(t *testing.T) {
+	gateway, provider := newFakeGateway(t, "test-key")
+
+	charge, err := provider.CreateCharge(context.Background(), ChargeRequest{OrderID: "INV-1-P1", Method: MethodQRIS, Amount: 25000})
+	if err != nil {
+		t.Fatalf("CreateCharge: %v", err)
+	}
+	if charge.ProviderRef == "" || charge.QRString == "" {
+		t.Errorf("charge = %+v, want provider ref and QR string", charge)
+	}
+	if charge.VANumber != "" {
+		t.Errorf("VANumber = %q, want empty for QRIS", charge.VANumber)
+	}
+	if charge.ExpiresAt == nil {
+		t.Error("ExpiresAt not parsed from expiry_time")
+	}
+	if got := gateway.requests[0]["payment_type"]; got != "qris" {
+		t.Errorf("payment_type = %v, want qris", got)
+	}
+}
+
+func TestCreateChargeVirtualAccount(t *testing.T) {
+	gateway, provider := newFakeGateway(t, "test-key")
+
+	charge, err := provider.CreateCharge(context.Background(), ChargeRequest{OrderID: "INV-1-P1", Method: MethodVA, Bank: "bca", Amount: 25000})
+	if err != nil {
+		t.Fatalf("CreateCharge: %v", err)
+	}
+	if charge.VANumber == "" {
+		t.Errorf("charge = %+v, want a VA number", charge)
+	}
+	if got := gateway.charges["INV-1-P1"].Bank; got != "bca" {
+		t.Errorf("bank = %q, want bca", got)
+	}
+}
+
+func TestCreateChargeErrors(t *testing.T) {
+	_, provider := newFakeGateway(t, "test-key")
+	ctx := context.Background()
+
+	if _, err := provider.CreateCharge(ctx, ChargeRequest{OrderID: "INV-1-P1", Method: MethodVA, Amount: 25000}); err == nil {
+		t.Error("VA charge without bank: want error")
+	}
+	if _, err := provider.CreateCharge(ctx, ChargeRequest{OrderID: "INV-1-P1", Method: "cash", Amount: 25000}); err == nil {
+		t.Error("unsupported method: want error")
+	}
+
+	// Gateway membalas error di body dengan HTTP 200
+	if _, err := provider.CreateCharge(ctx, ChargeRequest{OrderID: "INV-1-P1", Method: MethodQRIS, Amount: 25000}); err != nil {
+		t.Fatalf("first charge: %v", err)
+	}
+	if _, err := provider.CreateCharge(ctx, ChargeRequest{OrderID: "INV-1-P1", Method: MethodQRIS, Amount: 25000}); err == nil {
+		t.Error("reused order_id: want error")
+	}
+
+	wrongKey := *provider
+	wrongKey.ServerKey = "other-key"
+	if _, err := wrongKey.CreateCharge(ctx, ChargeRequest{OrderID: "INV-1-P2", Method: MethodQRIS, Amount: 25000}); err == nil {
+		t.Error("wrong server key: want error")
+	}
+	wrongKey.ServerKey = ""
+	if _, err := wrongKey.CreateCharge(ctx, ChargeRequest{OrderID: "INV-1-P2", Method: MethodQRIS, Amount: 25000}); err == nil {
+		t.Error("missing server key: want error")
+	}
+}
+
+func TestParseWebhook(t *testing.T) {
+	gateway, provider := newFakeGateway(t, "test-key")
+	if _, err := provider.CreateCharge(context.Background(), ChargeRequest{OrderID: "INV-1-P1", Method: MethodQRIS, Amount: 25000}); err != nil {
+		t.Fatalf("CreateCharge: %v", err)
+	}
+
+	tests := []struct {
+		status string
+		want   string
+	}{
+		{"settlement", StatusPaid},
+		{"pending", StatusPending},
+		{"expire", StatusExpired},
+		{"deny", StatusFailed},
+	}
+	for _, test := range tests {
+		notification, err := provider.ParseWebhook(gateway.notify(t, "INV-1-P1", test.status))
+		if err != nil {
+			t.Fatalf("%s: ParseWebhook: %v", test.status, err)
+		}
+		if notification.Status != test.want {
+			t.Errorf("%s: status = %q, want %q", test.status, notification.Status, test.want)
+		}
+		if notification.OrderID != "INV-1-P1" || notification.Amount != 25000 || notification.ProviderRef == "" {
+			t.Errorf("%s: notification = %+v", test.status, notification)
+		}
+	}
+}
+
+func TestParseWebhookRejectsBadSignature(t *testing.T) {
+	tests := []struct {
+		name      string
+		signedKey string
+		serverKey string
+	}{
+		{"signed with another key", "other-key", "test-key"},
+		{"server key not configured", "", ""},
+	}
+	for _, test := range tests {
+		provider := &Midtrans{ServerKey: test.serverKey}
+		request := signedNotification(t, test.signedKey, "trx-1", "INV-1-P1", "settlement", 25000)
+		if _, err := provider.ParseWebhook(request); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: err = %v, want ErrInvalidSignature", test.name, err)
+		}
+	}
+
+	// Jumlah diubah setelah ditandatangani
+	provider := &Midtrans{ServerKey: "test-key"}
+	request := signedNotification(t, "test-key", "trx-1", "INV-1-P1", "settlement", 25000)
+	body := tamper(t, request, "gross_amount", "250000.00")
+	if _, err := provider.ParseWebhook(body); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("tampered amount: err = %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestExpireCharge(t *testing.T) {
+	gateway, provider := newFakeGateway(t, "test-key")
+	ctx := context.Background()
+	if _, err := provider.CreateCharge(ctx, ChargeRequest{OrderID: "INV-1-P1", Method: MethodQRIS, Amount: 25000}); err != nil {
+		t.Fatalf("CreateCharge: %v", err)
+	}
+
+	if err := provider.ExpireCharge(ctx, "INV-1-P1"); err != nil {
+		t.Fatalf("ExpireCharge: %v", err)
+	}
+	if !gateway.expired["INV-1-P1"] {
+		t.Error("charge was not expired at the gateway")
+	}
+	// Kedaluwarsa dua kali tetap berhasil
+	if err := provider.ExpireCharge(ctx, "INV-1-P1"); err != nil {
+		t.Errorf("second ExpireCharge: %v", err)
+	}
+	if err := provider.ExpireCharge(ctx, "INV-1-P9"); err == nil {
+		t.Error("unknown order_id: want error")
+	}
+}
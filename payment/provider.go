@@ -0,0 +1,76 @@
+// Package payment creates QRIS and virtual-account charges at a payment gateway and
+// verifies the gateway's payment notifications
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"apkclaundry/config"
+)
+
+// Payment methods offered to customers
+const (
+	MethodQRIS = "qris"
+	MethodVA   = "va"
+)
+
+// Charge statuses
+const (
+	StatusPending = "pending"
+	StatusPaid    = "paid"
+	StatusExpired = "expired"
+	StatusFailed  = "failed"
+)
+
+// ErrInvalidSignature is returned when a webhook does not carry a valid provider signature
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// ChargeRequest asks the provider to bill an amount
+type ChargeRequest struct {
+	OrderID string // Unik per tagihan di sisi provider
+	Method  string // MethodQRIS atau MethodVA
+	Bank    string // Wajib untuk MethodVA, mis. "bca", "bni", "bri"
+	Amount  float64
+	Expiry  time.Duration
+}
+
+// Charge is the provider's answer: what the customer needs to pay
+type Charge struct {
+	ProviderRef string
+	QRString    string
+	VANumber    string
+	ExpiresAt   *time.Time
+}
+
+// Notification is a verified payment status update received on the webhook
+type Notification struct {
+	OrderID     string
+	ProviderRef string
+	Status      string
+	Amount      float64
+	PaidAt      time.Time
+}
+
+// Provider is a payment gateway
+type Provider interface {
+	Name() string
+	CreateCharge(ctx context.Context, request ChargeRequest) (Charge, error)
+	// ExpireCharge closes a pending charge so it can no longer be paid
+	ExpireCharge(ctx context.Context, orderID string) error
+	// ParseWebhook verifies the signature of a notification request and decodes it
+	ParseWebhook(r *http.Request) (Notification, error)
+}
+
+// FromSettings returns the provider selected by PAYMENT_PROVIDER (default midtrans)
+func FromSettings() (Provider, error) {
+	switch provider := config.GetEnv("PAYMENT_PROVIDER", "midtrans"); provider {
+	case "midtrans":
+		return NewMidtrans(), nil
+	default:
+		return nil, fmt.Errorf("unknown payment provider %q", provider)
+	}
+}
@@ -0,0 +1,209 @@
+package receipt
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Lebar kertas thermal yang didukung (dalam mm)
+const (
+	Paper58mm = 58
+	Paper80mm = 80
+)
+
+// ESC/POS command sequences
+var (
+	escInit        = []byte{0x1B, 0x40}
+	escAlignLeft   = []byte{0x1B, 0x61, 0x00}
+	escAlignCenter = []byte{0x1B, 0x61, 0x01}
+	escBoldOn      = []byte{0x1B, 0x45, 0x01}
+	escBoldOff     = []byte{0x1B, 0x45, 0x00}
+	escDoubleSize  = []byte{0x1D, 0x21, 0x11}
+	escNormalSize  = []byte{0x1D, 0x21, 0x00}
+	escPartialCut  = []byte{0x1D, 0x56, 0x42, 0x00}
+)
+
+// ColumnsFor returns the number of Font A characters per line for a paper width
+func ColumnsFor(paperWidth int) int {
+	if paperWidth == Paper80mm {
+		return 48
+	}
+	return 32
+}
+
+// ESCPOS renders the receipt as a raw ESC/POS byte stream for a 58mm or 80mm thermal printer
+func (r Receipt) ESCPOS(paperWidth int) []byte {
+	columns := ColumnsFor(paperWidth)
+	var buf bytes.Buffer
+
+	buf.Write(escInit)
+
+	// Header usaha
+	buf.Write(escAlignCenter)
+	buf.Write(escBoldOn)
+	buf.Write(escDoubleSize)
+	writeLine(&buf, r.Business.Name)
+	buf.Write(escNormalSize)
+	buf.Write(escBoldOff)
+	for _, line := range wrap(r.Business.Address, columns) {
+		writeLine(&buf, line)
+	}
+	if r.Business.Phone != "" {
+		writeLine(&buf, "Telp. "+r.Business.Phone)
+	}
+	if r.IsReprint() {
+		buf.Write(escBoldOn)
+		writeLine(&buf, fmt.Sprintf("CETAK ULANG #%d", r.PrintCount-1))
+		buf.Write(escBoldOff)
+	}
+
+	buf.Write(escAlignLeft)
+	writeLine(&buf, strings.Repeat("-", columns))
+	writeLine(&buf, pair("No. Nota", r.Number, columns))
+	writeLine(&buf, pair("Tanggal", formatDateTime(r.Date), columns))
+	writeLine(&buf, pair("Pelanggan", r.CustomerName, columns))
+	if r.PhoneNumber != "" {
+		writeLine(&buf, pair("Telepon", r.PhoneNumber, columns))
+	}
+	writeLine(&buf, strings.Repeat("-", columns))
+
+	// Daftar item
+	for _, line := range r.Lines {
+		for _, text := range wrap(line.Description, columns) {
+			writeLine(&buf, text)
+		}
+		detail := fmt.Sprintf("  %s %s x %s", formatQuantity(line.Quantity), line.Unit, FormatRupiah(line.UnitPrice))
+		writeLine(&buf, pair(detail, FormatRupiah(line.Amount), columns))
+	}
+	writeLine(&buf, strings.Repeat("-", columns))
+
+	// Instruksi penanganan
+	if len(r.Instructions) > 0 {
+		buf.Write(escBoldOn)
+		writeLine(&buf, "Catatan:")
+		buf.Write(escBoldOff)
+		for _, instruction := range r.Instructions {
+			for _, text := range wrap("- "+instruction, columns) {
+				writeLine(&buf, text)
+			}
+		}
+		writeLine(&buf, strings.Repeat("-", columns))
+	}
+
+	// Ringkasan pembayaran
+	if r.TaxAmount != 0 && !r.TaxInclusive {
+		writeLine(&buf, pair("DPP", FormatRupiah(r.TaxBase), columns))
+		writeLine(&buf, pair(r.TaxLabel(), FormatRupiah(r.TaxAmount), columns))
+	}
+	buf.Write(escBoldOn)
+	writeLine(&buf, pair("TOTAL", FormatRupiah(r.Total), columns))
+	buf.Write(escBoldOff)
+	if r.TaxAmount != 0 && r.TaxInclusive {
+		writeLine(&buf, pair("Termasuk "+r.TaxLabel(), FormatRupiah(r.TaxAmount), columns))
+	}
+	paidLabel := "Dibayar"
+	if r.PaymentMethod != "" {
+		paidLabel = fmt.Sprintf("Dibayar (%s)", r.PaymentMethod)
+	}
+	writeLine(&buf, pair(paidLabel, FormatRupiah(r.Paid), columns))
+	writeLine(&buf, pair("Sisa", FormatRupiah(r.Balance), columns))
+	if r.EstimatedReadyAt != nil {
+		writeLine(&buf, strings.Repeat("-", columns))
+		buf.Write(escBoldOn)
+		writeLine(&buf, pair("Est. selesai", formatDateTime(*r.EstimatedReadyAt), columns))
+		buf.Write(escBoldOff)
+	}
+
+	// QR untuk pelacakan, dicetak langsung oleh printer
+	buf.Write(escAlignCenter)
+	if r.TrackingURL != "" {
+		buf.WriteByte('\n')
+		writeQR(&buf, r.TrackingURL)
+		writeLine(&buf, "Scan untuk cek status cucian")
+	}
+	if r.Business.Footer != "" {
+		buf.WriteByte('\n')
+		for _, line := range wrap(r.Business.Footer, columns) {
+			writeLine(&buf, line)
+		}
+	}
+
+	// Feed beberapa baris lalu potong kertas
+	buf.Write([]byte{0x1B, 0x64, 0x04})
+	buf.Write(escPartialCut)
+
+	return buf.Bytes()
+}
+
+// writeQR appends the GS ( k sequence that stores and prints a QR code (model 2)
+func writeQR(buf *bytes.Buffer, content string) {
+	data := []byte(asciiOnly(content))
+	storeLength := len(data) + 3
+
+	buf.Write([]byte{0x1D, 0x28, 0x6B, 0x04, 0x00, 0x31, 0x41, 0x32, 0x00}) // model 2
+	buf.Write([]byte{0x1D, 0x28, 0x6B, 0x03, 0x00, 0x31, 0x43, 0x06})       // module size
+	buf.Write([]byte{0x1D, 0x28, 0x6B, 0x03, 0x00, 0x31, 0x45, 0x31})       // error correction M
+	buf.Write([]byte{0x1D, 0x28, 0x6B, byte(storeLength % 256), byte(storeLength / 256), 0x31, 0x50, 0x30})
+	buf.Write(data)
+	buf.Write([]byte{0x1D, 0x28, 0x6B, 0x03, 0x00, 0x31, 0x51, 0x30}) // print
+}
+
+func writeLine(buf *bytes.Buffer, text string) {
+	buf.WriteString(asciiOnly(text))
+	buf.WriteByte('\n')
+}
+
+// pair lays out a label on the left and a value on the right of one line
+func pair(label, value string, columns int) string {
+	label, value = asciiOnly(label), asciiOnly(value)
+	space := columns - len(label) - len(value)
+	if space < 1 {
+		maxLabel := columns - len(value) - 1
+		if maxLabel < 0 {
+			return value[:columns]
+		}
+		label = label[:maxLabel]
+		space = 1
+	}
+	return label + strings.Repeat(" ", space) + value
+}
+
+// wrap breaks text into lines no longer than columns, splitting on spaces
+func wrap(text string, columns int) []string {
+	var lines []string
+	current := ""
+	for _, word := range strings.Fields(asciiOnly(text)) {
+		for len(word) > columns {
+			if current != "" {
+				lines = append(lines, current)
+				current = ""
+			}
+			lines = append(lines, word[:columns])
+			word = word[columns:]
+		}
+		switch {
+		case current == "":
+			current = word
+		case len(current)+1+len(word) <= columns:
+			current += " " + word
+		default:
+			lines = append(lines, current)
+			current = word
+		}
+	}
+	if current != "" {
+		lines = append(lines, current)
+	}
+	return lines
+}
+
+// asciiOnly drops characters outside printable ASCII, which most thermal printers cannot print as-is
+func asciiOnly(text string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\n' || (r >= 0x20 && r < 0x7F) {
+			return r
+		}
+		return -1
+	}, text)
+}
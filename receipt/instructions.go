@@ -0,0 +1,37 @@
+package receipt
+
+import (
+	"strings"
+
+	"apkclaundry/models"
+)
+
+// Instructions lists the handling instructions of an order in short Indonesian phrases, as they
+// are printed on receipts and labels and shown to operators
+func Instructions(transaction models.Transaction) []string {
+	var lines []string
+	if preferences := transaction.Preferences; preferences != nil {
+		if preferences.NoSoftener {
+			lines = append(lines, "Tanpa pelembut")
+		}
+		if preferences.Hypoallergenic {
+			lines = append(lines, "Deterjen hipoalergenik")
+		}
+		if preferences.Fragrance != "" {
+			lines = append(lines, "Parfum: "+preferences.Fragrance)
+		}
+		switch preferences.Finish {
+		case "fold":
+			lines = append(lines, "Dilipat")
+		case "hang":
+			lines = append(lines, "Digantung")
+		}
+		if notes := strings.TrimSpace(preferences.Notes); notes != "" {
+			lines = append(lines, notes)
+		}
+	}
+	if instructions := strings.TrimSpace(transaction.SpecialInstructions); instructions != "" {
+		lines = append(lines, instructions)
+	}
+	return lines
+}
@@ -0,0 +1,138 @@
+package receipt
+
+import (
+	"fmt"
+	"io"
+
+	"apkclaundry/config"
+	"apkclaundry/models"
+
+	"github.com/go-pdf/fpdf"
+)
+
+const invoiceMargin = 15.0
+
+// CorporateInvoice is the printable monthly invoice of a corporate account
+type CorporateInvoice struct {
+	Business config.BusinessProfile
+	Account  models.CorporateAccount
+	Invoice  models.CorporateInvoice
+}
+
+// WritePDF renders the invoice as an A4 PDF with one row per order
+func (c CorporateInvoice) WritePDF(w io.Writer) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(invoiceMargin, invoiceMargin, invoiceMargin)
+	pdf.SetAutoPageBreak(true, invoiceMargin)
+	pdf.AddPage()
+
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pageWidth, _ := pdf.GetPageSize()
+	contentWidth := pageWidth - 2*invoiceMargin
+	location := config.Location()
+
+	// Header usaha dan judul
+	pdf.SetFont("Helvetica", "B", 14)
+	pdf.CellFormat(contentWidth*0.6, 7, tr(c.Business.Name), "", 0, "L", false, 0, "")
+	pdf.CellFormat(contentWidth*0.4, 7, "TAGIHAN", "", 1, "R", false, 0, "")
+	pdf.SetFont("Helvetica", "", 9)
+	if c.Business.Address != "" {
+		pdf.CellFormat(contentWidth, 4.5, tr(c.Business.Address), "", 1, "L", false, 0, "")
+	}
+	if c.Business.Phone != "" {
+		pdf.CellFormat(contentWidth, 4.5, tr("Telp. "+c.Business.Phone), "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(4)
+
+	// Penerima tagihan di kiri, data tagihan di kanan
+	top := pdf.GetY()
+	pdf.SetFont("Helvetica", "B", 9)
+	pdf.CellFormat(contentWidth*0.55, 5, "Kepada:", "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.CellFormat(contentWidth*0.55, 5, tr(c.Account.Name), "", 1, "L", false, 0, "")
+	if c.Account.ContactName != "" {
+		pdf.CellFormat(contentWidth*0.55, 5, tr("u.p. "+c.Account.ContactName), "", 1, "L", false, 0, "")
+	}
+	if c.Account.BillingAddress != "" {
+		pdf.MultiCell(contentWidth*0.55, 5, tr(c.Account.BillingAddress), "", "L", false)
+	}
+	if c.Account.TaxID != "" {
+		pdf.CellFormat(contentWidth*0.55, 5, tr("NPWP "+c.Account.TaxID), "", 1, "L", false, 0, "")
+	}
+	bottom := pdf.GetY()
+
+	pdf.SetY(top)
+	for _, row := range [][2]string{
+		{"No. Tagihan", c.Invoice.Number},
+		{"Periode", c.Invoice.Period},
+		{"Tanggal", c.Invoice.IssuedAt.In(location).Format("02/01/2006")},
+		{"Jatuh tempo", c.Invoice.DueDate.In(location).Format("02/01/2006")},
+	} {
+		pdf.SetX(invoiceMargin + contentWidth*0.55)
+		pdf.CellFormat(contentWidth*0.2, 5, row[0], "", 0, "L", false, 0, "")
+		pdf.CellFormat(contentWidth*0.25, 5, row[1], "", 1, "R", false, 0, "")
+	}
+	if pdf.GetY() < bottom {
+		pdf.SetY(bottom)
+	}
+	pdf.Ln(5)
+
+	// Tabel pesanan
+	widths := []float64{contentWidth * 0.14, contentWidth * 0.22, contentWidth * 0.24, contentWidth * 0.2, contentWidth * 0.2}
+	pdf.SetFont("Helvetica", "B", 8)
+	pdf.SetFillColor(230, 230, 230)
+	for i, heading := range []string{"Tanggal", "No. Nota", "Pemesan", "Layanan", "Jumlah"} {
+		align := "L"
+		if i == len(widths)-1 {
+			align = "R"
+		}
+		pdf.CellFormat(widths[i], 6, heading, "B", 0, align, true, 0, "")
+	}
+	pdf.Ln(-1)
+	pdf.SetFont("Helvetica", "", 8)
+	for _, line := range c.Invoice.Lines {
+		service := line.ServiceType
+		if line.WeightPerKg > 0 {
+			service = fmt.Sprintf("%s %s kg", line.ServiceType, formatQuantity(line.WeightPerKg))
+		}
+		pdf.CellFormat(widths[0], 5, line.TransactionDate.In(location).Format("02/01/2006"), "", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[1], 5, line.InvoiceNumber, "", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[2], 5, tr(line.CustomerName), "", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[3], 5, tr(service), "", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[4], 5, FormatRupiah(line.Amount), "", 1, "R", false, 0, "")
+	}
+	pdf.Ln(1)
+	y := pdf.GetY()
+	pdf.SetDrawColor(120, 120, 120)
+	pdf.Line(invoiceMargin, y, invoiceMargin+contentWidth, y)
+	pdf.Ln(2)
+
+	// Ringkasan
+	labelX := invoiceMargin + contentWidth*0.55
+	summary := func(label, value string) {
+		pdf.SetX(labelX)
+		pdf.CellFormat(contentWidth*0.25, 5, tr(label), "", 0, "L", false, 0, "")
+		pdf.CellFormat(contentWidth*0.2, 5, value, "", 1, "R", false, 0, "")
+	}
+	pdf.SetFont("Helvetica", "", 9)
+	if c.Invoice.TaxAmount != 0 {
+		summary("DPP", FormatRupiah(c.Invoice.TaxBase))
+		summary("Pajak", FormatRupiah(c.Invoice.TaxAmount))
+	}
+	pdf.SetFont("Helvetica", "B", 10)
+	summary("Total", FormatRupiah(c.Invoice.Total))
+	pdf.SetFont("Helvetica", "", 9)
+	if c.Invoice.AmountPaid > 0 {
+		summary("Dibayar", FormatRupiah(c.Invoice.AmountPaid))
+		pdf.SetFont("Helvetica", "B", 9)
+		summary("Sisa", FormatRupiah(c.Invoice.Total-c.Invoice.AmountPaid))
+	}
+
+	if c.Business.Footer != "" {
+		pdf.Ln(8)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.MultiCell(contentWidth, 4, tr(c.Business.Footer), "", "C", false)
+	}
+
+	return pdf.Output(w)
+}
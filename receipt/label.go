@@ -0,0 +1,187 @@
+package receipt
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"apkclaundry/models"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/qr"
+	"github.com/go-pdf/fpdf"
+)
+
+// Label symbologies
+const (
+	SymbologyCode128 = "code128"
+	SymbologyQR      = "qr"
+)
+
+// Label is a sticker for an order bag or a single garment. Code is what the scanner reads:
+// the invoice number for the order, or invoice/piece (e.g. LDY-20261019-0001/03) for a garment.
+type Label struct {
+	Code         string
+	CustomerName string
+	ServiceType  string
+	Piece        int // 0 = label pesanan
+	Pieces       int
+	ReadyAt      *time.Time
+	Express      bool
+	Instructions []string
+}
+
+// PieceCode returns the label code of one garment of an order
+func PieceCode(invoiceNumber string, piece int) string {
+	return fmt.Sprintf("%s/%02d", invoiceNumber, piece)
+}
+
+// ParseLabelCode splits a scanned code into the invoice number and the piece (0 for an order label)
+func ParseLabelCode(code string) (string, int) {
+	code = strings.TrimSpace(code)
+	if i := strings.LastIndex(code, "/"); i > 0 {
+		if piece, err := strconv.Atoi(code[i+1:]); err == nil && piece > 0 {
+			return code[:i], piece
+		}
+	}
+	return code, 0
+}
+
+// LabelsForTransaction returns the labels of up to limit garments starting at piece from (1-based),
+// preceded by the order label when from is 1 or less. Large orders are printed in several batches.
+func LabelsForTransaction(transaction models.Transaction, from, limit int) []Label {
+	number := transaction.InvoiceNumber
+	if number == "" {
+		number = transaction.ID
+	}
+	label := Label{
+		Code:         number,
+		CustomerName: transaction.CustomerName,
+		ServiceType:  transaction.ServiceType,
+		Pieces:       transaction.Pieces,
+		ReadyAt:      transaction.EstimatedReadyAt,
+		Express:      transaction.Express,
+		Instructions: Instructions(transaction),
+	}
+
+	var labels []Label
+	if from <= 1 {
+		labels = append(labels, label)
+		from = 1
+	}
+	for piece := from; piece <= transaction.Pieces && piece < from+limit; piece++ {
+		label.Code = PieceCode(number, piece)
+		label.Piece = piece
+		labels = append(labels, label)
+	}
+	return labels
+}
+
+// Barcode encodes the label code in the given symbology
+func (l Label) Barcode(symbology string) (barcode.Barcode, error) {
+	switch symbology {
+	case SymbologyQR:
+		code, err := qr.Encode(l.Code, qr.M, qr.Auto)
+		if err != nil {
+			return nil, err
+		}
+		return barcode.Scale(code, 256, 256)
+	case SymbologyCode128:
+		code, err := code128.Encode(l.Code)
+		if err != nil {
+			return nil, err
+		}
+		return barcode.Scale(code, code.Bounds().Dx()*3, 96)
+	default:
+		return nil, fmt.Errorf("unknown symbology %q", symbology)
+	}
+}
+
+// WritePNG writes the barcode of the label as a PNG image
+func (l Label) WritePNG(w io.Writer, symbology string) error {
+	code, err := l.Barcode(symbology)
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, code)
+}
+
+// WriteLabelsPDF renders one label per page of widthMM × heightMM, for thermal label printers
+func WriteLabelsPDF(w io.Writer, labels []Label, symbology string, widthMM, heightMM float64) error {
+	const margin = 2.0
+	pdf := fpdf.NewCustom(&fpdf.InitType{UnitStr: "mm", Size: fpdf.SizeType{Wd: widthMM, Ht: heightMM}})
+	pdf.SetMargins(margin, margin, margin)
+	pdf.SetAutoPageBreak(false, 0)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	contentWidth := widthMM - 2*margin
+
+	for i, label := range labels {
+		code, err := label.Barcode(symbology)
+		if err != nil {
+			return err
+		}
+		var image bytes.Buffer
+		if err := png.Encode(&image, code); err != nil {
+			return err
+		}
+		name := fmt.Sprintf("label-%d", i)
+		options := fpdf.ImageOptions{ImageType: "PNG"}
+		pdf.RegisterImageOptionsReader(name, options, &image)
+
+		pdf.AddPage()
+		lines := label.textLines()
+		if symbology == SymbologyQR {
+			// QR di kiri, keterangan di kanan
+			size := heightMM - 2*margin
+			pdf.ImageOptions(name, margin, margin, size, size, false, options, 0, "")
+			textX, textWidth := margin+size+1.5, contentWidth-size-1.5
+			pdf.SetXY(textX, margin)
+			pdf.SetFont("Helvetica", "B", 8)
+			pdf.MultiCell(textWidth, 3.5, label.Code, "", "L", false)
+			pdf.SetFont("Helvetica", "", 7)
+			for _, line := range lines {
+				pdf.SetX(textX)
+				pdf.MultiCell(textWidth, 3.2, tr(line), "", "L", false)
+			}
+			continue
+		}
+
+		// Code128: keterangan di atas, barcode melebar, kode di bawahnya
+		pdf.SetFont("Helvetica", "", 6.5)
+		pdf.CellFormat(contentWidth, 3, tr(strings.Join(lines, " | ")), "", 1, "L", false, 0, "")
+		barHeight := heightMM - 2*margin - 7
+		pdf.ImageOptions(name, margin, pdf.GetY()+0.5, contentWidth, barHeight, false, options, 0, "")
+		pdf.SetXY(margin, heightMM-margin-3.5)
+		pdf.SetFont("Helvetica", "B", 8)
+		pdf.CellFormat(contentWidth, 3.5, label.Code, "", 1, "C", false, 0, "")
+	}
+
+	return pdf.Output(w)
+}
+
+// textLines are the human-readable details printed next to the barcode
+func (l Label) textLines() []string {
+	lines := []string{l.CustomerName}
+	service := l.ServiceType
+	if l.Express {
+		service += " EXPRESS"
+	}
+	lines = append(lines, strings.TrimSpace(service))
+	if l.Piece > 0 {
+		lines = append(lines, fmt.Sprintf("Potong %d/%d", l.Piece, l.Pieces))
+	} else if l.Pieces > 0 {
+		lines = append(lines, fmt.Sprintf("%d potong", l.Pieces))
+	}
+	if l.ReadyAt != nil {
+		lines = append(lines, "Selesai "+formatDateTime(*l.ReadyAt))
+	}
+	if len(l.Instructions) > 0 {
+		lines = append(lines, "! "+strings.Join(l.Instructions, ", "))
+	}
+	return lines
+}
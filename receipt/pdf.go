@@ -0,0 +1,155 @@
+package receipt
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"io"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/qr"
+	"github.com/go-pdf/fpdf"
+)
+
+const (
+	pdfMargin  = 6.0
+	pdfQRSize  = 28.0
+	pdfLineGap = 4.5
+)
+
+// WritePDF renders the receipt as a single A6 page PDF
+func (r Receipt) WritePDF(w io.Writer) error {
+	pdf := fpdf.New("P", "mm", "A6", "")
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(true, pdfMargin)
+	pdf.AddPage()
+
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pageWidth, _ := pdf.GetPageSize()
+	contentWidth := pageWidth - 2*pdfMargin
+
+	// Header usaha
+	pdf.SetFont("Helvetica", "B", 12)
+	pdf.CellFormat(contentWidth, 6, tr(r.Business.Name), "", 1, "C", false, 0, "")
+	pdf.SetFont("Helvetica", "", 7)
+	if r.Business.Address != "" {
+		pdf.MultiCell(contentWidth, 3.5, tr(r.Business.Address), "", "C", false)
+	}
+	if r.Business.Phone != "" {
+		pdf.CellFormat(contentWidth, 3.5, tr("Telp. "+r.Business.Phone), "", 1, "C", false, 0, "")
+	}
+	if r.IsReprint() {
+		pdf.SetFont("Helvetica", "B", 7)
+		pdf.CellFormat(contentWidth, 3.5, fmt.Sprintf("CETAK ULANG #%d", r.PrintCount-1), "", 1, "C", false, 0, "")
+	}
+	pdfRule(pdf, contentWidth)
+
+	// Informasi transaksi
+	pdf.SetFont("Helvetica", "", 8)
+	pdfPair(pdf, contentWidth, "No. Nota", r.Number)
+	pdfPair(pdf, contentWidth, "Tanggal", formatDateTime(r.Date))
+	pdfPair(pdf, contentWidth, "Pelanggan", tr(r.CustomerName))
+	if r.PhoneNumber != "" {
+		pdfPair(pdf, contentWidth, "Telepon", r.PhoneNumber)
+	}
+	pdfRule(pdf, contentWidth)
+
+	// Daftar item
+	for _, line := range r.Lines {
+		pdf.SetFont("Helvetica", "B", 8)
+		pdf.CellFormat(contentWidth, pdfLineGap, tr(line.Description), "", 1, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 8)
+		detail := fmt.Sprintf("%s %s x %s", formatQuantity(line.Quantity), line.Unit, FormatRupiah(line.UnitPrice))
+		pdf.CellFormat(contentWidth*0.6, pdfLineGap, tr(detail), "", 0, "L", false, 0, "")
+		pdf.CellFormat(contentWidth*0.4, pdfLineGap, FormatRupiah(line.Amount), "", 1, "R", false, 0, "")
+	}
+	pdfRule(pdf, contentWidth)
+
+	// Instruksi penanganan
+	if len(r.Instructions) > 0 {
+		pdf.SetFont("Helvetica", "B", 8)
+		pdf.CellFormat(contentWidth, pdfLineGap, "Catatan:", "", 1, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 8)
+		for _, instruction := range r.Instructions {
+			pdf.MultiCell(contentWidth, pdfLineGap, tr("- "+instruction), "", "L", false)
+		}
+		pdfRule(pdf, contentWidth)
+	}
+
+	// Ringkasan pembayaran
+	pdf.SetFont("Helvetica", "", 8)
+	if r.TaxAmount != 0 && !r.TaxInclusive {
+		pdfPair(pdf, contentWidth, "DPP", FormatRupiah(r.TaxBase))
+		pdfPair(pdf, contentWidth, tr(r.TaxLabel()), FormatRupiah(r.TaxAmount))
+	}
+	pdf.SetFont("Helvetica", "B", 9)
+	pdfPair(pdf, contentWidth, "Total", FormatRupiah(r.Total))
+	pdf.SetFont("Helvetica", "", 8)
+	if r.TaxAmount != 0 && r.TaxInclusive {
+		pdfPair(pdf, contentWidth, tr("Termasuk "+r.TaxLabel()), FormatRupiah(r.TaxAmount))
+	}
+	paidLabel := "Dibayar"
+	if r.PaymentMethod != "" {
+		paidLabel = fmt.Sprintf("Dibayar (%s)", r.PaymentMethod)
+	}
+	pdfPair(pdf, contentWidth, tr(paidLabel), FormatRupiah(r.Paid))
+	pdfPair(pdf, contentWidth, "Sisa", FormatRupiah(r.Balance))
+	if r.EstimatedReadyAt != nil {
+		pdfRule(pdf, contentWidth)
+		pdf.SetFont("Helvetica", "B", 8)
+		pdfPair(pdf, contentWidth, "Estimasi selesai", formatDateTime(*r.EstimatedReadyAt))
+	}
+
+	// QR untuk pelacakan
+	if r.TrackingURL != "" {
+		qrImage, err := qrPNG(r.TrackingURL)
+		if err != nil {
+			return err
+		}
+		pdf.Ln(2)
+		options := fpdf.ImageOptions{ImageType: "PNG"}
+		pdf.RegisterImageOptionsReader("tracking-qr", options, qrImage)
+		pdf.ImageOptions("tracking-qr", (pageWidth-pdfQRSize)/2, pdf.GetY(), pdfQRSize, pdfQRSize, true, options, 0, "")
+		pdf.SetFont("Helvetica", "", 6)
+		pdf.CellFormat(contentWidth, 3, "Scan untuk cek status cucian", "", 1, "C", false, 0, "")
+	}
+
+	if r.Business.Footer != "" {
+		pdf.Ln(1)
+		pdf.SetFont("Helvetica", "I", 7)
+		pdf.MultiCell(contentWidth, 3.5, tr(r.Business.Footer), "", "C", false)
+	}
+
+	return pdf.Output(w)
+}
+
+func pdfPair(pdf *fpdf.Fpdf, width float64, label, value string) {
+	pdf.CellFormat(width*0.45, pdfLineGap, label, "", 0, "L", false, 0, "")
+	pdf.CellFormat(width*0.55, pdfLineGap, value, "", 1, "R", false, 0, "")
+}
+
+func pdfRule(pdf *fpdf.Fpdf, width float64) {
+	pdf.Ln(1)
+	y := pdf.GetY()
+	pdf.SetDrawColor(120, 120, 120)
+	pdf.Line(pdfMargin, y, pdfMargin+width, y)
+	pdf.Ln(1.5)
+}
+
+// qrPNG encodes content as a QR code PNG image
+func qrPNG(content string) (*bytes.Buffer, error) {
+	code, err := qr.Encode(content, qr.M, qr.Auto)
+	if err != nil {
+		return nil, err
+	}
+	code, err = barcode.Scale(code, 256, 256)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, code); err != nil {
+		return nil, err
+	}
+	return &buf, nil
+}
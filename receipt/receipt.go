@@ -0,0 +1,166 @@
+package receipt
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"apkclaundry/config"
+	"apkclaundry/models"
+)
+
+// Line is a single row in the item section of a receipt
+type Line struct {
+	Description string
+	Quantity    float64
+	Unit        string
+	UnitPrice   float64
+	Amount      float64
+}
+
+// Receipt holds everything needed to render a customer nota, independent of output format
+type Receipt struct {
+	Business         config.BusinessProfile
+	Number           string
+	Date             time.Time
+	CustomerName     string
+	PhoneNumber      string
+	Lines            []Line
+	Instructions     []string
+	Total            float64
+	Paid             float64
+	Balance          float64
+	PaymentMethod    string
+	TaxName          string
+	TaxRate          float64
+	TaxInclusive     bool
+	TaxBase          float64
+	TaxAmount        float64
+	EstimatedReadyAt *time.Time
+	TrackingURL      string
+	PrintCount       int
+}
+
+// FromTransaction builds a receipt for a laundry transaction
+func FromTransaction(transaction models.Transaction, business config.BusinessProfile, trackingURL string) Receipt {
+	// Transaksi lama belum memiliki subtotal
+	subtotal := transaction.Subtotal
+	if subtotal == 0 {
+		subtotal = transaction.TotalPrice
+	}
+
+	unitPrice := 0.0
+	if transaction.WeightPerKg > 0 {
+		unitPrice = subtotal / transaction.WeightPerKg
+	}
+
+	number := transaction.InvoiceNumber
+	if number == "" {
+		number = transaction.ID
+	}
+
+	lines := []Line{{
+		Description: transaction.ServiceType,
+		Quantity:    transaction.WeightPerKg,
+		Unit:        "kg",
+		UnitPrice:   unitPrice,
+		Amount:      subtotal,
+	}}
+	for _, discount := range transaction.Discounts {
+		lines = append(lines, Line{
+			Description: discount.Description,
+			Quantity:    1,
+			Unit:        "x",
+			UnitPrice:   -discount.Amount,
+			Amount:      -discount.Amount,
+		})
+	}
+
+	if transaction.DeliveryFee != 0 {
+		lines = append(lines, Line{
+			Description: "Ongkos antar-jemput",
+			Quantity:    1,
+			Unit:        "x",
+			UnitPrice:   transaction.DeliveryFee,
+			Amount:      transaction.DeliveryFee,
+		})
+	}
+	if transaction.StorageFee != 0 {
+		lines = append(lines, Line{
+			Description: "Biaya simpan",
+			Quantity:    float64(transaction.StorageFeeDays),
+			Unit:        "hari",
+			UnitPrice:   transaction.StorageFee / math.Max(1, float64(transaction.StorageFeeDays)),
+			Amount:      transaction.StorageFee,
+		})
+	}
+
+	balance := transaction.TotalPrice - transaction.AmountPaid
+	if balance < 0 {
+		balance = 0
+	}
+
+	return Receipt{
+		Business:         business,
+		Number:           number,
+		Date:             transaction.TransactionDate,
+		CustomerName:     transaction.CustomerName,
+		PhoneNumber:      transaction.PhoneNumber,
+		Lines:            lines,
+		Instructions:     Instructions(transaction),
+		Total:            transaction.TotalPrice,
+		Paid:             transaction.AmountPaid,
+		Balance:          balance,
+		PaymentMethod:    transaction.PaymentMethod,
+		TaxName:          transaction.TaxName,
+		TaxRate:          transaction.TaxRate,
+		TaxInclusive:     transaction.TaxInclusive,
+		TaxBase:          transaction.TaxBase,
+		TaxAmount:        transaction.TaxAmount,
+		EstimatedReadyAt: transaction.EstimatedReadyAt,
+		TrackingURL:      trackingURL,
+		PrintCount:       transaction.ReceiptPrintCount,
+	}
+}
+
+// IsReprint reports whether this receipt has been printed before
+func (r Receipt) IsReprint() bool {
+	return r.PrintCount > 1
+}
+
+// TaxLabel returns the tax line caption, e.g. "PPN 11%"
+func (r Receipt) TaxLabel() string {
+	return r.TaxName + " " + formatQuantity(r.TaxRate) + "%"
+}
+
+// FormatRupiah formats an amount as Indonesian rupiah, e.g. 12500 -> "Rp 12.500"
+func FormatRupiah(amount float64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	digits := fmt.Sprintf("%d", int64(math.Round(amount)))
+	var grouped strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			grouped.WriteByte('.')
+		}
+		grouped.WriteRune(digit)
+	}
+
+	return sign + "Rp " + grouped.String()
+}
+
+// formatQuantity prints a quantity without trailing zeros, e.g. 2.50 -> "2.5"
+func formatQuantity(quantity float64) string {
+	return strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.2f", quantity), "0"), ".")
+}
+
+// formatDateTime mengubah time.Time menjadi string dengan format dd/mm/yyyy hh:mm di zona waktu usaha;
+// waktu dari MongoDB terbaca sebagai UTC
+func formatDateTime(date time.Time) string {
+	return date.In(config.Location()).Format("02/01/2006 15:04")
+}
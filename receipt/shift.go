@@ -0,0 +1,180 @@
+package receipt
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"apkclaundry/config"
+	"apkclaundry/models"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// ShiftReport is the end-of-shift cash summary printed for the cashier and the owner
+type ShiftReport struct {
+	Business  config.BusinessProfile
+	Shift     models.Shift
+	Movements []models.CashMovement
+}
+
+// shiftRow is one label/amount line of the report
+type shiftRow struct {
+	Label  string
+	Amount float64
+	Bold   bool
+}
+
+func (s ShiftReport) summary() []shiftRow {
+	return []shiftRow{
+		{Label: "Modal awal", Amount: s.Shift.OpeningFloat},
+		{Label: "Penjualan tunai", Amount: s.Shift.CashSales},
+		{Label: "Kas masuk", Amount: s.Shift.CashIn},
+		{Label: "Kas keluar", Amount: -s.Shift.CashOut},
+		{Label: "Seharusnya", Amount: s.Shift.ExpectedCash, Bold: true},
+		{Label: "Dihitung", Amount: s.Shift.CountedCash, Bold: true},
+		{Label: "Selisih", Amount: s.Shift.Variance, Bold: true},
+	}
+}
+
+// periods returns the opening and closing time lines; an open shift is printed as a preview
+func (s ShiftReport) periods() [][2]string {
+	location := config.Location()
+	closed := "(belum ditutup)"
+	if s.Shift.ClosedAt != nil {
+		closed = formatDateTime(s.Shift.ClosedAt.In(location))
+	}
+	return [][2]string{
+		{"Kasir", s.Shift.CashierName},
+		{"Dibuka", formatDateTime(s.Shift.OpenedAt.In(location))},
+		{"Ditutup", closed},
+	}
+}
+
+// manualMovements are the cash-in and cash-out entries; sales are only summarized
+func (s ShiftReport) manualMovements() []models.CashMovement {
+	var movements []models.CashMovement
+	for _, movement := range s.Movements {
+		if movement.Type != "sale" {
+			movements = append(movements, movement)
+		}
+	}
+	return movements
+}
+
+func (s ShiftReport) salesCount() int {
+	return len(s.Movements) - len(s.manualMovements())
+}
+
+func movementAmount(movement models.CashMovement) float64 {
+	if movement.Type == "cash_out" {
+		return -movement.Amount
+	}
+	return movement.Amount
+}
+
+// ESCPOS renders the report for a 58mm or 80mm thermal printer
+func (s ShiftReport) ESCPOS(paperWidth int) []byte {
+	columns := ColumnsFor(paperWidth)
+	var buf bytes.Buffer
+
+	buf.Write(escInit)
+	buf.Write(escAlignCenter)
+	buf.Write(escBoldOn)
+	writeLine(&buf, s.Business.Name)
+	writeLine(&buf, "LAPORAN SHIFT")
+	buf.Write(escBoldOff)
+
+	buf.Write(escAlignLeft)
+	writeLine(&buf, strings.Repeat("-", columns))
+	for _, period := range s.periods() {
+		writeLine(&buf, pair(period[0], period[1], columns))
+	}
+	writeLine(&buf, pair("Transaksi tunai", fmt.Sprintf("%d", s.salesCount()), columns))
+	writeLine(&buf, strings.Repeat("-", columns))
+
+	for _, row := range s.summary() {
+		if row.Bold {
+			buf.Write(escBoldOn)
+		}
+		writeLine(&buf, pair(row.Label, FormatRupiah(row.Amount), columns))
+		buf.Write(escBoldOff)
+	}
+
+	if movements := s.manualMovements(); len(movements) > 0 {
+		writeLine(&buf, strings.Repeat("-", columns))
+		writeLine(&buf, "Kas masuk/keluar:")
+		for _, movement := range movements {
+			label := movement.CreatedAt.In(config.Location()).Format("15:04") + " " + movement.Description
+			writeLine(&buf, pair(label, FormatRupiah(movementAmount(movement)), columns))
+		}
+	}
+	if s.Shift.Notes != "" {
+		writeLine(&buf, strings.Repeat("-", columns))
+		for _, line := range wrap(s.Shift.Notes, columns) {
+			writeLine(&buf, line)
+		}
+	}
+
+	buf.Write([]byte{0x1B, 0x64, 0x04})
+	buf.Write(escPartialCut)
+	return buf.Bytes()
+}
+
+// WritePDF renders the report as an A6 PDF
+func (s ShiftReport) WritePDF(w io.Writer) error {
+	pdf := fpdf.New("P", "mm", "A6", "")
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(true, pdfMargin)
+	pdf.AddPage()
+
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pageWidth, _ := pdf.GetPageSize()
+	contentWidth := pageWidth - 2*pdfMargin
+
+	pdf.SetFont("Helvetica", "B", 12)
+	pdf.CellFormat(contentWidth, 6, tr(s.Business.Name), "", 1, "C", false, 0, "")
+	pdf.SetFont("Helvetica", "B", 9)
+	pdf.CellFormat(contentWidth, 5, "LAPORAN SHIFT", "", 1, "C", false, 0, "")
+	pdfRule(pdf, contentWidth)
+
+	pdf.SetFont("Helvetica", "", 8)
+	for _, period := range s.periods() {
+		pdfPair(pdf, contentWidth, period[0], tr(period[1]))
+	}
+	pdfPair(pdf, contentWidth, "Transaksi tunai", fmt.Sprintf("%d", s.salesCount()))
+	pdfRule(pdf, contentWidth)
+
+	for _, row := range s.summary() {
+		style := ""
+		if row.Bold {
+			style = "B"
+		}
+		pdf.SetFont("Helvetica", style, 8)
+		pdfPair(pdf, contentWidth, row.Label, FormatRupiah(row.Amount))
+	}
+
+	if movements := s.manualMovements(); len(movements) > 0 {
+		pdfRule(pdf, contentWidth)
+		pdf.SetFont("Helvetica", "B", 8)
+		pdf.CellFormat(contentWidth, pdfLineGap, "Kas masuk/keluar", "", 1, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 8)
+		for _, movement := range movements {
+			label := movement.CreatedAt.In(config.Location()).Format("15:04") + " " + movement.Description
+			pdfPair(pdf, contentWidth, tr(label), FormatRupiah(movementAmount(movement)))
+		}
+	}
+	if s.Shift.Notes != "" {
+		pdfRule(pdf, contentWidth)
+		pdf.SetFont("Helvetica", "I", 7)
+		pdf.MultiCell(contentWidth, 3.5, tr(s.Shift.Notes), "", "L", false)
+	}
+
+	pdf.Ln(2)
+	pdf.SetFont("Helvetica", "", 6)
+	pdf.CellFormat(contentWidth, 3, "Dicetak "+formatDateTime(time.Now().In(config.Location())), "", 1, "R", false, 0, "")
+
+	return pdf.Output(w)
+}
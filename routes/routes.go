@@ -28,6 +28,36 @@ func InitRoutes() *http.ServeMux {
 		}
 	})
 
+	// Rute publik untuk lacak pesanan dengan token bertanda tangan
+	router.HandleFunc("/track/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.TrackOrder(w, r)
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// Rute publik untuk notifikasi pembayaran dari payment gateway (diverifikasi dengan signature)
+	router.HandleFunc("/payment-webhook", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			controllers.PaymentWebhook(w, r)
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// Rute publik untuk membuka foto lampiran lewat URL bertanda tangan
+	router.HandleFunc("/attachment-file", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.ServeAttachmentFile(w, r)
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
 	// Rute dengan AuthMiddleware
 	securedRouter := http.NewServeMux()
 
@@ -91,8 +121,8 @@ func InitRoutes() *http.ServeMux {
 		switch r.Method {
 		case http.MethodGet:
 			controllers.GetCustomerByID(w, r) // Mengambil data user berdasarkan ID
-		case http.MethodPut:
-			controllers.UpdateCustomer(w, r) // Mengupdate data user berdasarkan ID
+		case http.MethodPut, http.MethodPatch:
+			controllers.UpdateCustomer(w, r) // Mengupdate sebagian data (merge patch) berdasarkan ID
 		case http.MethodDelete:
 			controllers.DeleteCustomer(w, r) // Menghapus data user berdasarkan ID
 		default:
@@ -118,6 +148,58 @@ func InitRoutes() *http.ServeMux {
 		}
 	})))
 
+	securedRouter.Handle("/customer-loyalty", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetCustomerLoyalty(w, r) // Saldo poin, tier dan riwayat poin pelanggan
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/customer-balance", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetCustomerBalance(w, r) // Saldo paket prabayar dan mutasinya
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Rute untuk paket prabayar
+	securedRouter.Handle("/package", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetAllPackages(w, r) // Mengambil semua paket
+		case http.MethodPost:
+			controllers.CreatePackage(w, r) // Membuat paket baru
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/package-id", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetPackageByID(w, r) // Mengambil paket berdasarkan ID
+		case http.MethodPut:
+			controllers.UpdatePackage(w, r) // Mengupdate paket berdasarkan ID
+		case http.MethodDelete:
+			controllers.DeletePackage(w, r) // Menghapus paket berdasarkan ID
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/package-purchase", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			controllers.PurchasePackage(w, r) // Pelanggan membeli paket
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
 	securedRouter.Handle("/supplier/transaction", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodPost:
@@ -144,8 +226,8 @@ func InitRoutes() *http.ServeMux {
 		switch r.Method {
 		case http.MethodGet:
 			controllers.GetSupplierByID(w, r) // Mengambil data user berdasarkan ID
-		case http.MethodPut:
-			controllers.UpdateSupplier(w, r) // Mengupdate data user berdasarkan ID
+		case http.MethodPut, http.MethodPatch:
+			controllers.UpdateSupplier(w, r) // Mengupdate sebagian data (merge patch) berdasarkan ID
 		case http.MethodDelete:
 			controllers.DeleteSupplier(w, r) // Menghapus data user berdasarkan ID
 		default:
@@ -170,8 +252,8 @@ func InitRoutes() *http.ServeMux {
 		switch r.Method {
 		case http.MethodGet:
 			controllers.GetItemByID(w, r) // Mengambil data user berdasarkan ID
-		case http.MethodPut:
-			controllers.UpdateItem(w, r) // Mengupdate data user berdasarkan ID
+		case http.MethodPut, http.MethodPatch:
+			controllers.UpdateItem(w, r) // Mengupdate sebagian data (merge patch) berdasarkan ID
 		case http.MethodDelete:
 			controllers.DeleteItem(w, r) // Menghapus data user berdasarkan ID
 		default:
@@ -206,8 +288,8 @@ func InitRoutes() *http.ServeMux {
 		switch r.Method {
 		case http.MethodGet:
 			controllers.GetTransactionByID(w, r) // Mengambil data user berdasarkan ID
-		case http.MethodPut:
-			controllers.UpdateTransaction(w, r) // Mengupdate data user berdasarkan ID
+		case http.MethodPut, http.MethodPatch:
+			controllers.UpdateTransaction(w, r) // Mengupdate sebagian data (merge patch) berdasarkan ID
 		case http.MethodDelete:
 			controllers.DeleteTransaction(w, r) // Menghapus data user berdasarkan ID
 		default:
@@ -215,6 +297,799 @@ func InitRoutes() *http.ServeMux {
 		}
 	})))
 
+	// Rute untuk pencarian transaksi berdasarkan nomor nota
+	securedRouter.Handle("/transaction-invoice", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.SearchTransactionsByInvoice(w, r) // Cari transaksi berdasarkan nomor nota
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/transaction-status", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			controllers.UpdateTransactionStatus(w, r) // Mengubah status pesanan
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/transaction-overdue", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetOverdueTransactions(w, r) // Pesanan yang melewati estimasi selesai
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Rute untuk layanan laundry
+	securedRouter.Handle("/service", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetAllServices(w, r) // Mengambil semua layanan
+		case http.MethodPost:
+			controllers.CreateService(w, r) // Membuat layanan baru
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/service-id", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetServiceByID(w, r) // Mengambil layanan berdasarkan ID
+		case http.MethodPut:
+			controllers.UpdateService(w, r) // Mengupdate layanan berdasarkan ID
+		case http.MethodDelete:
+			controllers.DeleteService(w, r) // Menghapus layanan berdasarkan ID
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Rute untuk hari libur
+	securedRouter.Handle("/holiday", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetAllHolidays(w, r) // Mengambil semua hari libur
+		case http.MethodPost:
+			controllers.CreateHoliday(w, r) // Menambah hari libur
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/holiday-id", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodDelete:
+			controllers.DeleteHoliday(w, r) // Menghapus hari libur
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Rute untuk cetak nota transaksi
+	securedRouter.Handle("/transaction-receipt", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetTransactionReceipt(w, r) // Nota PDF A6 atau ESC/POS thermal
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Rute untuk promo
+	securedRouter.Handle("/promotion", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetAllPromotions(w, r) // Mengambil semua promo
+		case http.MethodPost:
+			controllers.CreatePromotion(w, r) // Membuat promo baru
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Rute untuk promo berdasarkan ID
+	securedRouter.Handle("/promotion-id", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetPromotionByID(w, r) // Mengambil promo berdasarkan ID
+		case http.MethodPut:
+			controllers.UpdatePromotion(w, r) // Mengupdate promo berdasarkan ID
+		case http.MethodDelete:
+			controllers.DeletePromotion(w, r) // Menghapus promo berdasarkan ID
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/promotion-check", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.CheckPromotion(w, r) // Cek diskon dari kode promo tanpa memakainya
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/promotion-report", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetPromotionReport(w, r) // Laporan pemakaian promo
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Rute untuk zona antar-jemput
+	securedRouter.Handle("/delivery-zone", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetAllDeliveryZones(w, r) // Mengambil semua zona
+		case http.MethodPost:
+			controllers.CreateDeliveryZone(w, r) // Membuat zona baru
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/delivery-zone-id", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			controllers.UpdateDeliveryZone(w, r) // Mengupdate zona berdasarkan ID
+		case http.MethodDelete:
+			controllers.DeleteDeliveryZone(w, r) // Menghapus zona berdasarkan ID
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Rute untuk antar-jemput
+	securedRouter.Handle("/delivery-job", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetAllDeliveryJobs(w, r) // Mengambil jadwal antar-jemput
+		case http.MethodPost:
+			controllers.CreateDeliveryJob(w, r) // Menjadwalkan antar-jemput
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/delivery-job-id", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetDeliveryJobByID(w, r) // Mengambil jadwal berdasarkan ID
+		case http.MethodPut:
+			controllers.UpdateDeliveryJob(w, r) // Jadwal ulang atau ganti kurir
+		case http.MethodDelete:
+			controllers.DeleteDeliveryJob(w, r) // Menghapus jadwal
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/delivery-job-status", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			controllers.UpdateDeliveryJobStatus(w, r) // Mengubah status antar-jemput
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Rute untuk kurir (semua karyawan yang login, data dibatasi ke kurir tersebut)
+	securedRouter.Handle("/courier/jobs", middleware.StaffMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetCourierJobs(w, r) // Jadwal kurir hari ini
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/courier/job-status", middleware.StaffMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			controllers.UpdateCourierJobStatus(w, r) // Kurir mengubah status jadwalnya
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Rute untuk pembayaran QRIS dan virtual account
+	securedRouter.Handle("/transaction-charge", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetTransactionPayments(w, r) // Tagihan dan pembayaran transaksi
+		case http.MethodPost:
+			controllers.CreatePaymentCharge(w, r) // Membuat tagihan QRIS/VA untuk sisa tagihan
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/transaction-payment", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			controllers.RecordTransactionPayment(w, r) // Mencatat pembayaran di kasir
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Rute untuk shift kasir
+	securedRouter.Handle("/shift-open", middleware.StaffMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			controllers.OpenShift(w, r) // Membuka shift dengan modal awal
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/shift-current", middleware.StaffMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetCurrentShift(w, r) // Shift yang sedang berjalan
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/shift-cash", middleware.StaffMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			controllers.AddCashMovement(w, r) // Kas masuk/keluar
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/shift-close", middleware.StaffMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			controllers.CloseShift(w, r) // Menutup shift dengan uang yang dihitung
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/shift", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetAllShifts(w, r) // Daftar shift
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/shift-report", middleware.StaffMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetShiftReport(w, r) // Laporan shift (json, pdf atau escpos)
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Rute untuk pembatalan pesanan
+	securedRouter.Handle("/transaction-cancel", middleware.StaffMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			controllers.CancelTransaction(w, r) // Membatalkan pesanan dengan alasan dan refund
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/transaction-cancel-review", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetPendingCancellations(w, r) // Pembatalan yang menunggu persetujuan
+		case http.MethodPost:
+			controllers.ReviewCancellation(w, r) // Menyetujui atau menolak pembatalan
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Rute untuk klaim kerusakan/kehilangan
+	securedRouter.Handle("/claim", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetAllClaims(w, r) // Mengambil semua klaim
+		case http.MethodPost:
+			controllers.CreateClaim(w, r) // Mencatat klaim baru
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/claim-id", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetClaimByID(w, r) // Mengambil klaim berdasarkan ID
+		case http.MethodPut:
+			controllers.UpdateClaim(w, r) // Mengupdate detail klaim
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/claim-status", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			controllers.UpdateClaimStatus(w, r) // Mengubah status klaim
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/claim-report", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetClaimReport(w, r) // Laporan klaim per bulan dan per karyawan
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Rute untuk foto lampiran pesanan dan klaim
+	securedRouter.Handle("/attachment", middleware.StaffMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetAttachments(w, r) // Daftar foto dengan URL bertanda tangan
+		case http.MethodPost:
+			controllers.UploadAttachment(w, r) // Unggah foto noda/kerusakan saat penerimaan
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/attachment-id", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodDelete:
+			controllers.DeleteAttachment(w, r) // Menghapus foto beserta filenya
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Rute untuk mesin cuci dan pengering
+	securedRouter.Handle("/machine", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetAllMachines(w, r) // Mengambil semua mesin
+		case http.MethodPost:
+			controllers.CreateMachine(w, r) // Mendaftarkan mesin baru
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/machine-id", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetMachineByID(w, r) // Mengambil mesin beserta siklus yang berjalan
+		case http.MethodPut:
+			controllers.UpdateMachine(w, r) // Memperbarui kode, nama dan kapasitas mesin
+		case http.MethodDelete:
+			controllers.DeleteMachine(w, r) // Menghapus mesin yang tidak berjalan
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/machine-status", middleware.StaffMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetAllMachines(w, r) // Status mesin untuk operator
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/machine-start", middleware.StaffMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			controllers.StartMachineCycle(w, r) // Memulai siklus dengan pesanan di dalamnya
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/machine-finish", middleware.StaffMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			controllers.FinishMachineCycle(w, r) // Menyelesaikan siklus mesin
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/machine-cycle", middleware.StaffMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetMachineCycles(w, r) // Riwayat siklus mesin
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/machine-maintenance", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetMaintenanceLogs(w, r) // Riwayat perawatan mesin
+		case http.MethodPost:
+			controllers.StartMaintenance(w, r) // Mesin keluar dari layanan untuk perawatan
+		case http.MethodPut:
+			controllers.EndMaintenance(w, r) // Mesin kembali beroperasi
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/machine-report", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetMachineUtilization(w, r) // Laporan utilisasi per mesin
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Rute untuk muatan cuci dan papan produksi
+	securedRouter.Handle("/load", middleware.StaffMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetAllLoads(w, r) // Mengambil muatan yang masih diproses
+		case http.MethodPost:
+			controllers.CreateLoad(w, r) // Menggabungkan pesanan menjadi satu muatan
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/load-id", middleware.StaffMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetLoadByID(w, r) // Mengambil muatan berdasarkan ID
+		case http.MethodDelete:
+			controllers.DeleteLoad(w, r) // Membubarkan muatan yang belum dicuci
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/load-advance", middleware.StaffMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			controllers.AdvanceLoad(w, r) // Memajukan seluruh muatan ke tahap berikutnya
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/production-board", middleware.StaffMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetProductionBoard(w, r) // Ringkasan pesanan dan kg per tahap
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Rute untuk label barcode/QR dan pemindai
+	securedRouter.Handle("/transaction-labels", middleware.StaffMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetTransactionLabels(w, r) // Label pesanan dan per potong (PDF/PNG)
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/scan", middleware.StaffMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodPost:
+			controllers.ScanLabel(w, r) // Cari pesanan dari kode yang dipindai, opsional sekaligus majukan status
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Rute untuk laporan pajak (PPN)
+	securedRouter.Handle("/tax-report", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetTaxReport(w, r) // Ringkasan DPP dan pajak per bulan
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Rute untuk resep bahan habis pakai dan stock opname
+	securedRouter.Handle("/service-recipe", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			controllers.UpdateServiceRecipe(w, r) // Mengatur pemakaian bahan per kg untuk layanan
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/stock-count", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			controllers.CountStock(w, r) // Mencatat hasil hitung fisik stok
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/consumption-report", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetConsumptionReport(w, r) // Pemakaian bahan sesuai resep dibanding aktual
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/transaction-split", middleware.StaffMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetTransactionFamily(w, r) // Pesanan induk beserta pecahannya
+		case http.MethodPost:
+			controllers.SplitTransaction(w, r) // Memecah sebagian pesanan menjadi pesanan anak
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/transaction-pickup", middleware.StaffMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			controllers.RecordPartialPickup(w, r) // Mencatat pengambilan sebagian potong
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/revisions", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetRevisions(w, r) // Riwayat perubahan sebuah dokumen
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/schedule", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			controllers.CreateSchedule(w, r) // Membuat jadwal jemput berulang
+		case http.MethodGet:
+			controllers.GetAllSchedules(w, r) // Mengambil semua jadwal berulang
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/schedule-id", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetScheduleByID(w, r) // Mengambil jadwal berdasarkan ID
+		case http.MethodPut, http.MethodPatch:
+			controllers.UpdateSchedule(w, r) // Mengupdate jadwal (merge patch)
+		case http.MethodDelete:
+			controllers.DeleteSchedule(w, r) // Menghapus jadwal
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/schedule-pause", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			controllers.PauseSchedule(w, r) // Menjeda jadwal
+		case http.MethodDelete:
+			controllers.ResumeSchedule(w, r) // Melanjutkan jadwal yang dijeda
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/schedule-skip", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			controllers.SkipScheduleDate(w, r) // Melewati satu tanggal jadwal
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/schedule-calendar", middleware.StaffMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetScheduleCalendar(w, r) // Kalender jemput berulang
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/schedule-confirm", middleware.StaffMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			controllers.ConfirmScheduledOrder(w, r) // Konfirmasi draft setelah cucian ditimbang
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/schedule-run", middleware.CronMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodPost:
+			controllers.RunSchedules(w, r) // Membuat draft pesanan dan job jemput dari jadwal (cron)
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/account", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			controllers.CreateAccount(w, r) // Membuat akun korporat
+		case http.MethodGet:
+			controllers.GetAllAccounts(w, r) // Mengambil semua akun korporat
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/account-id", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetAccountByID(w, r) // Akun korporat beserta saldo terutang
+		case http.MethodPut, http.MethodPatch:
+			controllers.UpdateAccount(w, r) // Mengupdate akun korporat (merge patch)
+		case http.MethodDelete:
+			controllers.DeleteAccount(w, r) // Menghapus akun korporat yang sudah lunas
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/account-invoice", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			controllers.IssueCorporateInvoice(w, r) // Membuat tagihan bulanan konsolidasi
+		case http.MethodGet:
+			controllers.GetCorporateInvoices(w, r) // Mengambil daftar tagihan korporat
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/account-invoice-id", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetCorporateInvoiceByID(w, r) // Tagihan korporat (JSON atau PDF)
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/account-invoice-payment", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			controllers.RecordInvoicePayment(w, r) // Mencatat pembayaran tagihan korporat
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/account-invoice-run", middleware.CronMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodPost:
+			controllers.RunCorporateInvoices(w, r) // Membuat tagihan bulan lalu untuk semua akun (cron)
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/ar-aging", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetARAging(w, r) // Umur piutang akun korporat
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Rute untuk cucian yang belum diambil
+	securedRouter.Handle("/unclaimed-run", middleware.CronMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodPost:
+			controllers.RunUnclaimedPolicy(w, r) // Pengingat, biaya simpan dan penandaan donasi (cron)
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/unclaimed", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetUnclaimedTransactions(w, r) // Pesanan siap yang belum diambil
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/disposal", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetDisposalRecords(w, r) // Catatan donasi/pembuangan
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/disposal-id", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			controllers.CompleteDisposal(w, r) // Mencatat hasil donasi/pembuangan
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Rute untuk notifikasi pelanggan
+	securedRouter.Handle("/notification-dispatch", middleware.CronMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodPost:
+			controllers.DispatchNotifications(w, r) // Mengirim notifikasi yang tertunda (cron)
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/notification-retry", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			controllers.RetryNotification(w, r) // Mengirim ulang notifikasi yang gagal
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/notification-template", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetNotificationTemplates(w, r) // Mengambil template pesan
+		case http.MethodPut:
+			controllers.UpdateNotificationTemplate(w, r) // Mengubah template pesan per peristiwa
+		case http.MethodDelete:
+			controllers.ResetNotificationTemplate(w, r) // Kembali ke template bawaan
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	securedRouter.Handle("/transaction-notifications", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			controllers.GetTransactionNotifications(w, r) // Log pengiriman notifikasi per transaksi
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
 	// Rute untuk transaksi item
 	securedRouter.Handle("/item-transaction", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"apkclaundry/config"
+)
+
+// Local stores files on the filesystem under STORAGE_LOCAL_DIR. Files are served by the
+// application itself at /attachment-file, so STORAGE_PUBLIC_URL must point at this API.
+// Serverless deployments do not keep the filesystem between requests; use s3 there.
+type Local struct {
+	dir     string
+	baseURL string
+}
+
+func NewLocal() *Local {
+	return &Local{
+		dir:     config.GetEnv("STORAGE_LOCAL_DIR", "uploads"),
+		baseURL: strings.TrimRight(config.GetEnv("STORAGE_PUBLIC_URL", ""), "/"),
+	}
+}
+
+func (l *Local) Name() string { return "local" }
+
+// path maps a key into the storage directory, refusing keys that would escape it
+func (l *Local) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if clean == "/" || clean != "/"+key {
+		return "", ErrNotFound
+	}
+	return filepath.Join(l.dir, filepath.FromSlash(clean)), nil
+}
+
+func (l *Local) Put(ctx context.Context, key, contentType string, data []byte) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	// Tulis ke file sementara dulu agar pembaca tidak pernah melihat file setengah jadi
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (l *Local) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+func (l *Local) Delete(ctx context.Context, key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (l *Local) SignedURL(key string, expiresAt time.Time) (string, error) {
+	signature, err := Sign(key, expiresAt)
+	if err != nil {
+		return "", err
+	}
+	query := url.Values{}
+	query.Set("key", key)
+	query.Set("expires", strconv.FormatInt(expiresAt.Unix(), 10))
+	query.Set("signature", signature)
+	return l.baseURL + "/attachment-file?" + query.Encode(), nil
+}
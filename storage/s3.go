@@ -0,0 +1,236 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"apkclaundry/config"
+)
+
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// S3 stores files in an S3-compatible bucket (AWS S3, MinIO, Cloudflare R2, ...) using path-style
+// URLs and AWS Signature Version 4. Viewing URLs are presigned and point directly at the bucket.
+type S3 struct {
+	endpoint  string
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+	now       func() time.Time // Jam untuk tanda tangan; diganti di test agar hasilnya tetap
+}
+
+func NewS3() *S3 {
+	return &S3{
+		endpoint:  strings.TrimRight(config.GetEnv("S3_ENDPOINT", "https://s3.amazonaws.com"), "/"),
+		region:    config.GetEnv("S3_REGION", "us-east-1"),
+		bucket:    config.GetEnv("S3_BUCKET", ""),
+		accessKey: config.GetEnv("S3_ACCESS_KEY", ""),
+		secretKey: config.GetEnv("S3_SECRET_KEY", ""),
+		client:    &http.Client{Timeout: 30 * time.Second},
+		now:       time.Now,
+	}
+}
+
+func (s *S3) Name() string { return "s3" }
+
+func (s *S3) Put(ctx context.Context, key, contentType string, data []byte) error {
+	request, err := s.request(ctx, http.MethodPut, key, data)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", contentType)
+	s.sign(request, sha256Hex(data))
+
+	response, err := s.do(request)
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
+
+func (s *S3) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	request, err := s.request(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.sign(request, sha256Hex(nil))
+
+	response, err := s.do(request)
+	if err != nil {
+		return nil, err
+	}
+	return response.Body, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	request, err := s.request(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	s.sign(request, sha256Hex(nil))
+
+	response, err := s.do(request)
+	if err == ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
+
+// SignedURL presigns a GET request; S3 allows at most 7 days
+func (s *S3) SignedURL(key string, expiresAt time.Time) (string, error) {
+	objectURL, err := url.Parse(s.objectURL(key))
+	if err != nil {
+		return "", err
+	}
+	return s.presign(objectURL, expiresAt), nil
+}
+
+// presign adds the Signature Version 4 query parameters that authorize a GET of objectURL
+func (s *S3) presign(objectURL *url.URL, expiresAt time.Time) string {
+	now := s.now().UTC()
+	expires := int64(expiresAt.Sub(now).Seconds())
+	if expires < 1 {
+		expires = 1
+	}
+	if expires > 7*24*60*60 {
+		expires = 7 * 24 * 60 * 60
+	}
+
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", s.accessKey+"/"+s.scope(now))
+	query.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	query.Set("X-Amz-Expires", strconv.FormatInt(expires, 10))
+	query.Set("X-Amz-SignedHeaders", "host")
+	objectURL.RawQuery = encodeQuery(query)
+
+	canonical := strings.Join([]string{
+		http.MethodGet,
+		objectURL.EscapedPath(),
+		objectURL.RawQuery,
+		"host:" + objectURL.Host + "\n",
+		"host",
+		unsignedPayload,
+	}, "\n")
+	objectURL.RawQuery += "&X-Amz-Signature=" + s.signature(now, canonical)
+	return objectURL.String()
+}
+
+func (s *S3) objectURL(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return s.endpoint + "/" + url.PathEscape(s.bucket) + "/" + strings.Join(segments, "/")
+}
+
+func (s *S3) request(ctx context.Context, method, key string, body []byte) (*http.Request, error) {
+	if s.bucket == "" || s.accessKey == "" || s.secretKey == "" {
+		return nil, fmt.Errorf("S3_BUCKET, S3_ACCESS_KEY and S3_SECRET_KEY must be set")
+	}
+	return http.NewRequestWithContext(ctx, method, s.objectURL(key), bytes.NewReader(body))
+}
+
+// do executes a request and turns 404 into ErrNotFound and other non-2xx responses into errors
+func (s *S3) do(request *http.Request) (*http.Response, error) {
+	response, err := s.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode == http.StatusNotFound {
+		response.Body.Close()
+		return nil, ErrNotFound
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		response.Body.Close()
+		return nil, fmt.Errorf("storage returned %s: %s", response.Status, strings.TrimSpace(string(body)))
+	}
+	return response, nil
+}
+
+// sign adds a Signature Version 4 Authorization header to a request
+func (s *S3) sign(request *http.Request, payloadHash string) {
+	now := s.now().UTC()
+	request.Header.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	names := []string{"host"}
+	headers := map[string]string{"host": request.URL.Host}
+	for name := range request.Header {
+		lower := strings.ToLower(name)
+		names = append(names, lower)
+		headers[lower] = strings.TrimSpace(request.Header.Get(name))
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{
+		request.Method,
+		request.URL.EscapedPath(),
+		encodeQuery(request.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	request.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, s.scope(now), signedHeaders, s.signature(now, canonical)))
+}
+
+func (s *S3) scope(now time.Time) string {
+	return now.Format("20060102") + "/" + s.region + "/s3/aws4_request"
+}
+
+// signature signs a canonical request with the key derived for the request date and region
+func (s *S3) signature(now time.Time, canonical string) string {
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		now.Format("20060102T150405Z"),
+		s.scope(now),
+		sha256Hex([]byte(canonical)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), now.Format("20060102"))
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// encodeQuery encodes query parameters sorted by name with %20 for spaces, as SigV4 requires
+func encodeQuery(query url.Values) string {
+	return strings.ReplaceAll(query.Encode(), "+", "%20")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,84 @@
+// Package storage keeps uploaded files (order and claim photos) in a configurable blob store
+// and hands out signed, expiring URLs for viewing them
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"apkclaundry/config"
+)
+
+var (
+	ErrNotFound         = errors.New("file not found")
+	ErrInvalidSignature = errors.New("invalid or expired file signature")
+	ErrNoSigningSecret  = errors.New("STORAGE_SECRET is not set")
+)
+
+// Store is a blob store. Keys are slash-separated paths such as "transactions/<id>/<file>.jpg".
+type Store interface {
+	Name() string
+	Put(ctx context.Context, key, contentType string, data []byte) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a URL that allows viewing the file until expiresAt, without logging in
+	SignedURL(key string, expiresAt time.Time) (string, error)
+}
+
+// FromSettings returns the store selected by STORAGE_PROVIDER (local or s3, default local)
+func FromSettings() (Store, error) {
+	switch provider := config.GetEnv("STORAGE_PROVIDER", "local"); provider {
+	case "local":
+		return NewLocal(), nil
+	case "s3":
+		return NewS3(), nil
+	default:
+		return nil, fmt.Errorf("unknown storage provider %q", provider)
+	}
+}
+
+// signingSecret returns STORAGE_SECRET, or JWT_SECRET when it is not set. Without either, file
+// links would be signed with a guessable key, so none are issued or accepted.
+func signingSecret() ([]byte, error) {
+	secret := config.GetEnv("STORAGE_SECRET", config.GetEnv("JWT_SECRET", ""))
+	if secret == "" {
+		return nil, ErrNoSigningSecret
+	}
+	return []byte(secret), nil
+}
+
+// Sign returns the signature that authorizes viewing key until expiresAt; it is used for files
+// served by this application rather than by the storage provider
+func Sign(key string, expiresAt time.Time) (string, error) {
+	secret, err := signingSecret()
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(key + "\n" + strconv.FormatInt(expiresAt.Unix(), 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// Verify checks a signature created by Sign; expires is the Unix time passed in the URL
+func Verify(key, expires, signature string) error {
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	expiresAt := time.Unix(unix, 0)
+	expected, err := Sign(key, expiresAt)
+	if err != nil {
+		return err
+	}
+	if time.Now().After(expiresAt) || !hmac.Equal([]byte(signature), []byte(expected)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	t.Setenv("STORAGE_SECRET", "test-secret")
+	key := "transactions/abc/photo.jpg"
+	expiresAt := time.Now().Add(time.Hour)
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	signature, err := Sign(key, expiresAt)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	tampered := signature[:len(signature)-1] + "A"
+	if tampered == signature {
+		tampered = signature[:len(signature)-1] + "B"
+	}
+	expired := time.Now().Add(-time.Minute)
+	expiredSignature, _ := Sign(key, expired)
+
+	tests := []struct {
+		name      string
+		key       string
+		expires   string
+		signature string
+		want      error
+	}{
+		{"valid", key, expires, signature, nil},
+		{"expired", key, strconv.FormatInt(expired.Unix(), 10), expiredSignature, ErrInvalidSignature},
+		{"other key", "transactions/abc/other.jpg", expires, signature, ErrInvalidSignature},
+		{"extended expiry", key, strconv.FormatInt(expiresAt.Add(24*time.Hour).Unix(), 10), signature, ErrInvalidSignature},
+		{"tampered signature", key, expires, tampered, ErrInvalidSignature},
+		{"no signature", key, expires, "", ErrInvalidSignature},
+		{"invalid expiry", key, "soon", signature, ErrInvalidSignature},
+	}
+	for _, test := range tests {
+		if err := Verify(test.key, test.expires, test.signature); err != test.want {
+			t.Errorf("%s: Verify = %v, want %v", test.name, err, test.want)
+		}
+	}
+
+	// Tanda tangan dengan secret lain tidak berlaku
+	t.Setenv("STORAGE_SECRET", "another-secret")
+	if err := Verify(key, expires, signature); err != ErrInvalidSignature {
+		t.Errorf("other secret: Verify = %v, want %v", err, ErrInvalidSignature)
+	}
+}
+
+func TestSignRequiresSecret(t *testing.T) {
+	t.Setenv("STORAGE_SECRET", "")
+	t.Setenv("JWT_SECRET", "")
+	expiresAt := time.Now().Add(time.Hour)
+
+	if _, err := Sign("transactions/abc/photo.jpg", expiresAt); err != ErrNoSigningSecret {
+		t.Errorf("Sign = %v, want %v", err, ErrNoSigningSecret)
+	}
+	if err := Verify("transactions/abc/photo.jpg", strconv.FormatInt(expiresAt.Unix(), 10), "x"); err != ErrNoSigningSecret {
+		t.Errorf("Verify = %v, want %v", err, ErrNoSigningSecret)
+	}
+
+	// JWT_SECRET dipakai bila STORAGE_SECRET kosong
+	t.Setenv("JWT_SECRET", "jwt-secret")
+	if _, err := Sign("transactions/abc/photo.jpg", expiresAt); err != nil {
+		t.Errorf("Sign with JWT_SECRET: %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	_ "image/png" // Mendaftarkan decoder PNG untuk image.Decode
+)
+
+// MaxImagePixels is the largest photo Thumbnail decodes; a small file can declare a huge image
+// that would take gigabytes of memory to decode
+const MaxImagePixels = 50_000_000
+
+var ErrImageTooLarge = errors.New("image has too many pixels")
+
+// Thumbnail decodes a JPEG or PNG photo and returns a JPEG copy that fits in maxSize×maxSize,
+// together with the size of the original. Each thumbnail pixel averages the source pixels it covers.
+func Thumbnail(data []byte, maxSize int) (thumbnail []byte, width, height int, err error) {
+	// Ukuran dibaca dari header dulu sebelum seluruh gambar di-decode
+	header, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if header.Width <= 0 || header.Height <= 0 || int64(header.Width)*int64(header.Height) > MaxImagePixels {
+		return nil, 0, 0, ErrImageTooLarge
+	}
+
+	source, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	bounds := source.Bounds()
+	width, height = bounds.Dx(), bounds.Dy()
+
+	thumbWidth, thumbHeight := width, height
+	if thumbWidth > maxSize || thumbHeight > maxSize {
+		if width >= height {
+			thumbWidth, thumbHeight = maxSize, max(1, height*maxSize/width)
+		} else {
+			thumbWidth, thumbHeight = max(1, width*maxSize/height), maxSize
+		}
+	}
+
+	result := image.NewRGBA(image.Rect(0, 0, thumbWidth, thumbHeight))
+	for ty := 0; ty < thumbHeight; ty++ {
+		y0 := bounds.Min.Y + ty*height/thumbHeight
+		y1 := max(y0+1, bounds.Min.Y+(ty+1)*height/thumbHeight)
+		for tx := 0; tx < thumbWidth; tx++ {
+			x0 := bounds.Min.X + tx*width/thumbWidth
+			x1 := max(x0+1, bounds.Min.X+(tx+1)*width/thumbWidth)
+
+			var r, g, b, a, n uint64
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					cr, cg, cb, ca := source.At(x, y).RGBA()
+					r, g, b, a, n = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca), n+1
+				}
+			}
+			i := result.PixOffset(tx, ty)
+			result.Pix[i+0] = uint8(r / n >> 8)
+			result.Pix[i+1] = uint8(g / n >> 8)
+			result.Pix[i+2] = uint8(b / n >> 8)
+			result.Pix[i+3] = uint8(a / n >> 8)
+		}
+	}
+
+	var buffer bytes.Buffer
+	if err := jpeg.Encode(&buffer, result, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, 0, 0, err
+	}
+	return buffer.Bytes(), width, height, nil
+}
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidTrackingToken = errors.New("invalid tracking token")
+	ErrExpiredTrackingToken = errors.New("tracking token has expired")
+	ErrNoTrackingSecret     = errors.New("TRACKING_SECRET is not set")
+)
+
+// trackingSecret returns TRACKING_SECRET, or JWT_SECRET when it is not set. Without either,
+// tokens would be signed with a guessable key, so none are issued or accepted.
+func trackingSecret() ([]byte, error) {
+	secret := os.Getenv("TRACKING_SECRET")
+	if secret == "" {
+		secret = os.Getenv("JWT_SECRET")
+	}
+	if secret == "" {
+		return nil, ErrNoTrackingSecret
+	}
+	return []byte(secret), nil
+}
+
+func signTracking(payload string) (string, error) {
+	secret, err := trackingSecret()
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// GenerateTrackingToken creates a URL-safe token that references one transaction until it expires
+func GenerateTrackingToken(transactionID string, expiresAt time.Time) (string, error) {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(transactionID + "." + strconv.FormatInt(expiresAt.Unix(), 10)))
+	signature, err := signTracking(payload)
+	if err != nil {
+		return "", err
+	}
+	return payload + "." + signature, nil
+}
+
+// ValidateTrackingToken checks the signature and expiry of a tracking token and returns the transaction ID
+func ValidateTrackingToken(token string) (string, error) {
+	payload, signature, found := strings.Cut(token, ".")
+	if !found {
+		return "", ErrInvalidTrackingToken
+	}
+	expected, err := signTracking(payload)
+	if err != nil {
+		return "", err
+	}
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return "", ErrInvalidTrackingToken
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", ErrInvalidTrackingToken
+	}
+	transactionID, expiry, found := strings.Cut(string(decoded), ".")
+	if !found {
+		return "", ErrInvalidTrackingToken
+	}
+	expiresAt, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil {
+		return "", ErrInvalidTrackingToken
+	}
+	if time.Now().Unix() > expiresAt {
+		return "", ErrExpiredTrackingToken
+	}
+
+	return transactionID, nil
+}